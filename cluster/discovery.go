@@ -0,0 +1,239 @@
+package cluster
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Discovery discovers the
+// peer nodes in the cluster
+type Discovery interface {
+	// Peers returns the peers discovered
+	Peers(ctx context.Context) ([]string, error)
+}
+
+// StaticDiscovery discovers a fixed list of peers
+type StaticDiscovery struct {
+	peers []string
+}
+
+// NewStaticDiscovery returns a new
+// StaticDiscovery of the peers passed
+func NewStaticDiscovery(peers []string) *StaticDiscovery {
+	return &StaticDiscovery{peers: normalize(peers)}
+}
+
+// Peers returns the peers passed
+func (discovery *StaticDiscovery) Peers(ctx context.Context) ([]string, error) {
+	return append([]string{}, discovery.peers...), nil
+}
+
+// FileDiscovery discovers the peers listed in a file, one
+// per line, ignoring blank lines & lines starting with #
+// The file is only read again once it has changed
+// It is safe for concurrent use
+type FileDiscovery struct {
+	mutex sync.Mutex
+	path  string
+	// modified & size identify the
+	// version of the file last read
+	modified time.Time
+	size     int64
+	peers    []string
+}
+
+// NewFileDiscovery returns a new FileDiscovery
+// of the peers listed in the file at the path
+func NewFileDiscovery(path string) *FileDiscovery {
+	return &FileDiscovery{path: path}
+}
+
+// Peers returns the peers listed in the file
+// reading it again if it has changed
+func (discovery *FileDiscovery) Peers(ctx context.Context) ([]string, error) {
+	discovery.mutex.Lock()
+	defer discovery.mutex.Unlock()
+
+	info, err := os.Stat(discovery.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if discovery.peers != nil && info.ModTime().Equal(discovery.modified) && info.Size() == discovery.size {
+		return append([]string{}, discovery.peers...), nil
+	}
+
+	content, err := ioutil.ReadFile(discovery.path)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		peers = append(peers, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	discovery.peers = normalize(peers)
+	discovery.modified = info.ModTime()
+	discovery.size = info.Size()
+
+	return append([]string{}, discovery.peers...), nil
+}
+
+// Resolver looks up DNS records, *net.Resolver
+// implements it & tests use a stub
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// DNSDiscovery discovers the peers from DNS, either as
+// the A records of a name with a fixed port, or as the
+// targets & ports of the SRV records of a name
+type DNSDiscovery struct {
+	resolver Resolver
+	name     string
+	// port is the port of the peers discovered from
+	// A records, SRV records are used if it is 0
+	port int
+}
+
+// NewDNSDiscovery returns a new DNSDiscovery of the peers at the
+// A records of the name with the port passed, or at the SRV
+// records of the name if the port is 0, looked up with the
+// resolver passed or net.DefaultResolver if it is nil
+func NewDNSDiscovery(resolver Resolver, name string, port int) *DNSDiscovery {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &DNSDiscovery{resolver: resolver, name: name, port: port}
+}
+
+// Peers returns the peers looked up from DNS
+// as host:port addresses
+func (discovery *DNSDiscovery) Peers(ctx context.Context) ([]string, error) {
+	if discovery.name == "" {
+		return nil, errors.New("empty dns name provided")
+	}
+
+	peers := []string{}
+
+	if discovery.port == 0 {
+		_, records, err := discovery.resolver.LookupSRV(ctx, "", "", discovery.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			host := strings.TrimSuffix(record.Target, ".")
+			peers = append(peers, net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+		}
+		return normalize(peers), nil
+	}
+
+	hosts, err := discovery.resolver.LookupHost(ctx, discovery.name)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		peers = append(peers, net.JoinHostPort(host, strconv.Itoa(discovery.port)))
+	}
+	return normalize(peers), nil
+}
+
+// SeedDiscovery bootstraps from seed nodes, discovering
+// the seeds along with the members of the cluster
+// fetched from each seed reachable
+type SeedDiscovery struct {
+	seeds []string
+	// fetch returns the members
+	// of the cluster known to a seed
+	fetch func(ctx context.Context, seed string) ([]string, error)
+}
+
+// NewSeedDiscovery returns a new SeedDiscovery of the seeds
+// passed fetching the members known to them with fetch
+func NewSeedDiscovery(seeds []string, fetch func(ctx context.Context, seed string) ([]string, error)) *SeedDiscovery {
+	return &SeedDiscovery{seeds: normalize(seeds), fetch: fetch}
+}
+
+// Peers returns the seeds along with the members fetched from
+// the seeds reachable. The seeds unreachable are still returned
+// as they may be starting up along with the local node
+func (discovery *SeedDiscovery) Peers(ctx context.Context) ([]string, error) {
+	peers := append([]string{}, discovery.seeds...)
+
+	for _, seed := range discovery.seeds {
+		members, err := discovery.fetch(ctx, seed)
+		if err != nil {
+			continue
+		}
+		peers = append(peers, members...)
+	}
+
+	return normalize(peers), nil
+}
+
+// MultiDiscovery discovers the peers
+// discovered by any of its providers
+type MultiDiscovery []Discovery
+
+// Peers returns the peers discovered by the providers, an
+// error is only returned if every provider fails
+func (discovery MultiDiscovery) Peers(ctx context.Context) ([]string, error) {
+	peers := []string{}
+
+	var lastErr error
+	succeeded := 0
+	for _, provider := range discovery {
+		discovered, err := provider.Peers(ctx)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		succeeded++
+		peers = append(peers, discovered...)
+	}
+
+	if succeeded == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return normalize(peers), nil
+}
+
+// normalize returns the peers trimmed
+// sorted & without duplicates or blanks
+func normalize(peers []string) []string {
+	seen := map[string]struct{}{}
+	normalized := []string{}
+	for _, peer := range peers {
+		peer = strings.TrimSpace(peer)
+		if peer == "" {
+			continue
+		}
+		if _, duplicate := seen[peer]; duplicate {
+			continue
+		}
+		seen[peer] = struct{}{}
+		normalized = append(normalized, peer)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
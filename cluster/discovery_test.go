@@ -0,0 +1,143 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubResolver is a Resolver
+// answering from fixed records
+type stubResolver struct {
+	hosts map[string][]string
+	srv   map[string][]*net.SRV
+}
+
+func (resolver stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addresses, present := resolver.hosts[host]
+	if !present {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addresses, nil
+}
+
+func (resolver stubResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	records, present := resolver.srv[name]
+	if !present {
+		return "", nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return name, records, nil
+}
+
+// TestStaticDiscovery checks the basic functionality of StaticDiscovery
+// the peers passed should be returned sorted without blanks or duplicates
+func TestStaticDiscovery(t *testing.T) {
+	discovery := NewStaticDiscovery([]string{"peer-1", " peer-0", "", "peer-1"})
+
+	actualValue, actualError := discovery.Peers(context.Background())
+	assert.Nil(t, actualError)
+	assert.Equal(t, []string{"peer-0", "peer-1"}, actualValue)
+}
+
+// TestFileDiscovery checks the basic functionality of FileDiscovery
+// the peers listed in the file should be returned and the file
+// should be read again once it has changed
+func TestFileDiscovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "twopset-discovery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "peers")
+	discovery := NewFileDiscovery(path)
+
+	_, actualError := discovery.Peers(context.Background())
+	assert.NotNil(t, actualError)
+
+	modified := time.Unix(100, 0)
+	write := func(content string) {
+		assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+		assert.Nil(t, os.Chtimes(path, modified, modified))
+		modified = modified.Add(time.Second)
+	}
+
+	write("# peers\n10.0.0.2:8080\n\n  10.0.0.1:8080  \n")
+	actualValue, actualError := discovery.Peers(context.Background())
+	assert.Nil(t, actualError)
+	assert.Equal(t, []string{"10.0.0.1:8080", "10.0.0.2:8080"}, actualValue)
+
+	write("10.0.0.3:8080\n")
+	actualValue, actualError = discovery.Peers(context.Background())
+	assert.Nil(t, actualError)
+	assert.Equal(t, []string{"10.0.0.3:8080"}, actualValue)
+}
+
+// TestDNSDiscovery checks the basic functionality of DNSDiscovery
+// the A records should be returned with the port passed
+// and the SRV records with their own ports
+func TestDNSDiscovery(t *testing.T) {
+	resolver := stubResolver{
+		hosts: map[string][]string{
+			"twopset.local": {"10.0.0.2", "10.0.0.1", "fd00::1"},
+		},
+		srv: map[string][]*net.SRV{
+			"_twopset._tcp.local": {
+				{Target: "peer-1.local.", Port: 8081},
+				{Target: "peer-0.local.", Port: 8080},
+			},
+		},
+	}
+
+	actualValue, actualError := NewDNSDiscovery(resolver, "twopset.local", 8080).Peers(context.Background())
+	assert.Nil(t, actualError)
+	assert.Equal(t, []string{"10.0.0.1:8080", "10.0.0.2:8080", "[fd00::1]:8080"}, actualValue)
+
+	actualValue, actualError = NewDNSDiscovery(resolver, "_twopset._tcp.local", 0).Peers(context.Background())
+	assert.Nil(t, actualError)
+	assert.Equal(t, []string{"peer-0.local:8080", "peer-1.local:8081"}, actualValue)
+
+	_, actualError = NewDNSDiscovery(resolver, "missing.local", 8080).Peers(context.Background())
+	assert.NotNil(t, actualError)
+
+	_, actualError = NewDNSDiscovery(resolver, "", 8080).Peers(context.Background())
+	assert.NotNil(t, actualError)
+}
+
+// TestSeedDiscovery checks the basic functionality of SeedDiscovery
+// the seeds should be returned along with the members
+// fetched from the seeds reachable
+func TestSeedDiscovery(t *testing.T) {
+	discovery := NewSeedDiscovery([]string{"seed-0", "seed-1"}, func(ctx context.Context, seed string) ([]string, error) {
+		if seed == "seed-1" {
+			return nil, errors.New("seed unreachable")
+		}
+		return []string{"seed-0", "peer-2", "peer-3"}, nil
+	})
+
+	actualValue, actualError := discovery.Peers(context.Background())
+	assert.Nil(t, actualError)
+	assert.Equal(t, []string{"peer-2", "peer-3", "seed-0", "seed-1"}, actualValue)
+}
+
+// TestMultiDiscovery checks the basic functionality of MultiDiscovery
+// the peers of every provider should be returned, an error
+// should only be returned if every provider fails
+func TestMultiDiscovery(t *testing.T) {
+	failing := NewDNSDiscovery(stubResolver{}, "missing.local", 8080)
+
+	discovery := MultiDiscovery{NewStaticDiscovery([]string{"peer-1"}), failing, NewStaticDiscovery([]string{"peer-0", "peer-1"})}
+	actualValue, actualError := discovery.Peers(context.Background())
+	assert.Nil(t, actualError)
+	assert.Equal(t, []string{"peer-0", "peer-1"}, actualValue)
+
+	_, actualError = MultiDiscovery{failing}.Peers(context.Background())
+	assert.NotNil(t, actualError)
+}
@@ -0,0 +1,249 @@
+package cluster
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// package cluster implements the subsystems tracking
+// the peer nodes in the cluster, such as their health
+
+// Circuit is the state of the
+// circuit breaker of a peer
+type Circuit string
+
+const (
+	// CircuitClosed lets requests through to the peer
+	CircuitClosed Circuit = "closed"
+	// CircuitOpen stops requests to the peer after
+	// repeated failures until its next probe is due
+	CircuitOpen Circuit = "open"
+	// CircuitHalfOpen lets a single probe through
+	// to the peer to check if it has recovered
+	CircuitHalfOpen Circuit = "half_open"
+)
+
+const (
+	// DefaultFailureThreshold is the default number of
+	// consecutive failures opening the circuit of a peer
+	DefaultFailureThreshold = 3
+	// DefaultProbeBackoff is the default delay
+	// before an open circuit is probed
+	DefaultProbeBackoff = time.Second
+	// DefaultMaxProbeBackoff is the default maximum
+	// delay before an open circuit is probed
+	DefaultMaxProbeBackoff = time.Minute
+
+	// latencyWeight is the weight of each new sample
+	// in the moving average of the latency of a peer
+	latencyWeight = 0.2
+)
+
+// HealthConfig configures when the
+// circuit of a peer is opened & probed
+type HealthConfig struct {
+	// Threshold is the number of consecutive
+	// failures opening the circuit of a peer
+	Threshold int
+	// Backoff is the delay before an open circuit is
+	// probed, doubled each time the probe fails
+	Backoff time.Duration
+	// MaxBackoff is the maximum delay
+	// before an open circuit is probed
+	MaxBackoff time.Duration
+}
+
+// DefaultHealthConfig returns the HealthConfig with DefaultFailureThreshold,
+// DefaultProbeBackoff & DefaultMaxProbeBackoff
+func DefaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		Threshold:  DefaultFailureThreshold,
+		Backoff:    DefaultProbeBackoff,
+		MaxBackoff: DefaultMaxProbeBackoff,
+	}
+}
+
+// PeerHealth is the JSON struct
+// encapsulating the health of a peer
+type PeerHealth struct {
+	Peer    string  `json:"peer"`
+	Circuit Circuit `json:"circuit"`
+	// Successes & Failures are the total number
+	// of requests to the peer recorded
+	Successes uint64 `json:"successes"`
+	Failures  uint64 `json:"failures"`
+	// ConsecutiveFailures is the number of
+	// failures since the last success
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// Latency is the moving average of the
+	// latency of the successful requests
+	Latency   time.Duration `json:"latency"`
+	LastError string        `json:"last_error,omitempty"`
+	// Backoff & NextProbe are the delay & the time
+	// the circuit is probed at when not closed
+	Backoff   time.Duration `json:"backoff,omitempty"`
+	NextProbe *time.Time    `json:"next_probe,omitempty"`
+}
+
+// Health tracks the health of the peers & the state
+// of their circuit breakers from the requests recorded
+// It is safe for concurrent use
+type Health struct {
+	mutex  sync.Mutex
+	config HealthConfig
+	peers  map[string]*PeerHealth
+	// now returns the current time
+	now func() time.Time
+}
+
+// NewHealth returns a new Health with the config passed
+// reading the current time from the function passed,
+// or time.Now if it is nil
+func NewHealth(config HealthConfig, now func() time.Time) *Health {
+	if now == nil {
+		now = time.Now
+	}
+	if config.Threshold <= 0 {
+		config.Threshold = DefaultFailureThreshold
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = DefaultProbeBackoff
+	}
+	if config.MaxBackoff < config.Backoff {
+		config.MaxBackoff = config.Backoff
+	}
+
+	return &Health{
+		config: config,
+		peers:  map[string]*PeerHealth{},
+		now:    now,
+	}
+}
+
+// peer returns the health of the peer
+// adding it with a closed circuit if not present
+func (health *Health) peer(peer string) *PeerHealth {
+	peerHealth, present := health.peers[peer]
+	if !present {
+		peerHealth = &PeerHealth{Peer: peer, Circuit: CircuitClosed}
+		health.peers[peer] = peerHealth
+	}
+	return peerHealth
+}
+
+// Allow returns true if a request can be sent to the peer
+// When the probe of an open circuit is due, the circuit is
+// half opened & only that request is let through. Another
+// probe is let through if it is not recorded within the backoff
+func (health *Health) Allow(peer string) bool {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	peerHealth := health.peer(peer)
+	if peerHealth.Circuit == CircuitClosed {
+		return true
+	}
+
+	now := health.now()
+	if now.Before(*peerHealth.NextProbe) {
+		return false
+	}
+
+	next := now.Add(peerHealth.Backoff)
+	peerHealth.Circuit = CircuitHalfOpen
+	peerHealth.NextProbe = &next
+	return true
+}
+
+// Record records the outcome & latency of a request
+// to the peer, closing its circuit on success
+// The circuit is opened once the consecutive failures
+// reach the threshold, or if the probe of a half open
+// circuit fails, doubling the backoff up to its maximum
+func (health *Health) Record(peer string, latency time.Duration, err error) {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	peerHealth := health.peer(peer)
+
+	if err == nil {
+		peerHealth.Successes++
+		peerHealth.ConsecutiveFailures = 0
+		peerHealth.Circuit = CircuitClosed
+		peerHealth.Backoff = 0
+		peerHealth.NextProbe = nil
+
+		if peerHealth.Latency == 0 {
+			peerHealth.Latency = latency
+		} else {
+			peerHealth.Latency += time.Duration(latencyWeight * float64(latency-peerHealth.Latency))
+		}
+		return
+	}
+
+	peerHealth.Failures++
+	peerHealth.ConsecutiveFailures++
+	peerHealth.LastError = err.Error()
+
+	switch peerHealth.Circuit {
+	case CircuitClosed:
+		if peerHealth.ConsecutiveFailures < health.config.Threshold {
+			return
+		}
+		peerHealth.Backoff = health.config.Backoff
+	default:
+		peerHealth.Backoff *= 2
+		if peerHealth.Backoff > health.config.MaxBackoff {
+			peerHealth.Backoff = health.config.MaxBackoff
+		}
+	}
+
+	next := health.now().Add(peerHealth.Backoff)
+	peerHealth.Circuit = CircuitOpen
+	peerHealth.NextProbe = &next
+}
+
+// Unhealthy returns the peers passed
+// whose circuit is not closed
+func (health *Health) Unhealthy(peers []string) []string {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	unhealthy := []string{}
+	for _, peer := range peers {
+		if peerHealth, present := health.peers[peer]; present && peerHealth.Circuit != CircuitClosed {
+			unhealthy = append(unhealthy, peer)
+		}
+	}
+	return unhealthy
+}
+
+// Status returns the health of the peers passed
+// sorted by peer, the peers without any
+// requests recorded have a closed circuit
+func (health *Health) Status(peers []string) []PeerHealth {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	status := make([]PeerHealth, 0, len(peers))
+	for _, peer := range peers {
+		peerHealth, present := health.peers[peer]
+		if !present {
+			status = append(status, PeerHealth{Peer: peer, Circuit: CircuitClosed})
+			continue
+		}
+
+		copied := *peerHealth
+		if peerHealth.NextProbe != nil {
+			next := *peerHealth.NextProbe
+			copied.NextProbe = &next
+		}
+		status = append(status, copied)
+	}
+
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Peer < status[j].Peer
+	})
+	return status
+}
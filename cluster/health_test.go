@@ -0,0 +1,123 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errPeer = errors.New("peer unreachable")
+
+// fixedTime returns a time source
+// that always returns the given time
+func fixedTime(now *time.Time) func() time.Time {
+	return func() time.Time {
+		return *now
+	}
+}
+
+// TestHealth checks the basic functionality of Health
+// the circuit of a peer should only open once the
+// consecutive failures reach the threshold
+func TestHealth(t *testing.T) {
+	now := time.Unix(100, 0)
+	health := NewHealth(HealthConfig{Threshold: 2, Backoff: time.Second, MaxBackoff: 4 * time.Second}, fixedTime(&now))
+
+	health.Record("peer-1", 10*time.Millisecond, nil)
+	health.Record("peer-1", 0, errPeer)
+	assert.True(t, health.Allow("peer-1"))
+
+	health.Record("peer-1", 20*time.Millisecond, nil)
+	health.Record("peer-1", 0, errPeer)
+	assert.True(t, health.Allow("peer-1"))
+
+	health.Record("peer-1", 0, errPeer)
+	assert.False(t, health.Allow("peer-1"))
+
+	status := health.Status([]string{"peer-1"})
+	assert.Equal(t, CircuitOpen, status[0].Circuit)
+	assert.Equal(t, uint64(2), status[0].Successes)
+	assert.Equal(t, uint64(3), status[0].Failures)
+	assert.Equal(t, 2, status[0].ConsecutiveFailures)
+	assert.Equal(t, 12*time.Millisecond, status[0].Latency)
+	assert.Equal(t, errPeer.Error(), status[0].LastError)
+	assert.Equal(t, time.Second, status[0].Backoff)
+	assert.Equal(t, now.Add(time.Second), *status[0].NextProbe)
+}
+
+// TestHealth_Probe checks the functionality of Health Allow()
+// once the probe of an open circuit is due, a single probe
+// should be let through & its success should close the circuit
+func TestHealth_Probe(t *testing.T) {
+	now := time.Unix(100, 0)
+	health := NewHealth(HealthConfig{Threshold: 1, Backoff: time.Second, MaxBackoff: 4 * time.Second}, fixedTime(&now))
+
+	health.Record("peer-1", 0, errPeer)
+	assert.False(t, health.Allow("peer-1"))
+
+	now = now.Add(time.Second)
+	assert.True(t, health.Allow("peer-1"))
+	assert.False(t, health.Allow("peer-1"))
+	assert.Equal(t, CircuitHalfOpen, health.Status([]string{"peer-1"})[0].Circuit)
+
+	health.Record("peer-1", time.Millisecond, nil)
+	assert.True(t, health.Allow("peer-1"))
+
+	status := health.Status([]string{"peer-1"})
+	assert.Equal(t, CircuitClosed, status[0].Circuit)
+	assert.Equal(t, 0, status[0].ConsecutiveFailures)
+	assert.Nil(t, status[0].NextProbe)
+}
+
+// TestHealth_Backoff checks the functionality of Health Record()
+// when the probes of an open circuit fail, the backoff
+// should double up to its maximum
+func TestHealth_Backoff(t *testing.T) {
+	now := time.Unix(100, 0)
+	health := NewHealth(HealthConfig{Threshold: 1, Backoff: time.Second, MaxBackoff: 3 * time.Second}, fixedTime(&now))
+
+	health.Record("peer-1", 0, errPeer)
+
+	for _, expectedBackoff := range []time.Duration{2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		now = *health.Status([]string{"peer-1"})[0].NextProbe
+		assert.True(t, health.Allow("peer-1"))
+
+		health.Record("peer-1", 0, errPeer)
+		assert.False(t, health.Allow("peer-1"))
+		assert.Equal(t, expectedBackoff, health.Status([]string{"peer-1"})[0].Backoff)
+	}
+}
+
+// TestHealth_LostProbe checks the functionality of Health Allow()
+// when a probe is not recorded, another probe should be
+// let through once the backoff has elapsed
+func TestHealth_LostProbe(t *testing.T) {
+	now := time.Unix(100, 0)
+	health := NewHealth(HealthConfig{Threshold: 1, Backoff: time.Second, MaxBackoff: time.Second}, fixedTime(&now))
+
+	health.Record("peer-1", 0, errPeer)
+
+	now = now.Add(time.Second)
+	assert.True(t, health.Allow("peer-1"))
+
+	now = now.Add(time.Second)
+	assert.True(t, health.Allow("peer-1"))
+}
+
+// TestHealth_Status checks the functionality of Health Status()
+// & Unhealthy(), the peers without requests recorded
+// should be reported with a closed circuit
+func TestHealth_Status(t *testing.T) {
+	health := NewHealth(HealthConfig{Threshold: 1}, nil)
+
+	health.Record("peer-2", 0, errPeer)
+
+	status := health.Status([]string{"peer-2", "peer-1"})
+	assert.Equal(t, []string{"peer-1", "peer-2"}, []string{status[0].Peer, status[1].Peer})
+	assert.Equal(t, PeerHealth{Peer: "peer-1", Circuit: CircuitClosed}, status[0])
+	assert.Equal(t, CircuitOpen, status[1].Circuit)
+
+	assert.Equal(t, []string{"peer-2"}, health.Unhealthy([]string{"peer-1", "peer-2", "peer-3"}))
+}
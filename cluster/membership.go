@@ -0,0 +1,182 @@
+package cluster
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/el10savio/twoPSet-crdt/hlc"
+	"github.com/el10savio/twoPSet-crdt/lwwset"
+)
+
+// Membership is the list of the nodes in the cluster. It is
+// replicated between the nodes as an LWWSet, so the latest of
+// the Join & Leave of a node decides if it is a member and
+// nodes that have left can join back again
+// It is safe for concurrent use
+type Membership struct {
+	mutex sync.Mutex
+	// self is the ID of the local node
+	self string
+	// set is the LWWSet of the members
+	set lwwset.LWWSet
+}
+
+// NewMembership returns a new Membership of the local node
+// along with the seed nodes passed. The seeds are added
+// as of the zero timestamp, so any Leave of a seed
+// replicated from the other nodes takes precedence
+func NewMembership(self string, seeds []string) *Membership {
+	set := lwwset.Initialize(lwwset.BiasAdd)
+	for _, node := range append([]string{self}, seeds...) {
+		if node != "" {
+			set.Add[node] = hlc.Timestamp{}
+		}
+	}
+	return &Membership{self: self, set: set}
+}
+
+// Self returns the ID of the local node
+func (membership *Membership) Self() string {
+	return membership.self
+}
+
+// Join adds the node to the cluster
+func (membership *Membership) Join(node string) error {
+	if err := ValidateNode(node); err != nil {
+		return err
+	}
+
+	membership.mutex.Lock()
+	defer membership.mutex.Unlock()
+
+	set, err := membership.set.Addition(node)
+	if err != nil {
+		return err
+	}
+	membership.set = set
+	return nil
+}
+
+// Discover adds the nodes discovered that have never been
+// members of the cluster as of the zero timestamp, like the
+// seeds, and returns them. The nodes that have left are
+// not added back until they join again
+func (membership *Membership) Discover(nodes []string) []string {
+	membership.mutex.Lock()
+	defer membership.mutex.Unlock()
+
+	discovered := []string{}
+	for _, node := range nodes {
+		if ValidateNode(node) != nil {
+			continue
+		}
+		if _, added := membership.set.Add[node]; added {
+			continue
+		}
+		if _, removed := membership.set.Remove[node]; removed {
+			continue
+		}
+		membership.set.Add[node] = hlc.Timestamp{}
+		discovered = append(discovered, node)
+	}
+	return discovered
+}
+
+// ValidateNode returns an error if the node ID can not be used
+// as a host name or a host:port address to reach the peer at
+func ValidateNode(node string) error {
+	if node == "" {
+		return errors.New("empty node provided")
+	}
+	if len(node) > 253 {
+		return errors.New("node too long")
+	}
+	for _, char := range node {
+		switch {
+		case char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z', char >= '0' && char <= '9':
+		case char == '-', char == '.', char == '_', char == ':', char == '[', char == ']':
+		default:
+			return errors.New("invalid node provided: " + node)
+		}
+	}
+	return nil
+}
+
+// Leave removes the node from the cluster
+// It can join back again later
+func (membership *Membership) Leave(node string) error {
+	membership.mutex.Lock()
+	defer membership.mutex.Unlock()
+
+	if present, _ := membership.set.Lookup(node); !present {
+		return errors.New("node is not a member: " + node)
+	}
+
+	set, err := membership.set.Removal(node)
+	if err != nil {
+		return err
+	}
+	membership.set = set
+	return nil
+}
+
+// IsMember returns true if the
+// node is a member of the cluster
+func (membership *Membership) IsMember(node string) bool {
+	membership.mutex.Lock()
+	defer membership.mutex.Unlock()
+
+	present, _ := membership.set.Lookup(node)
+	return present
+}
+
+// Members returns the members
+// of the cluster in sorted order
+func (membership *Membership) Members() []string {
+	membership.mutex.Lock()
+	defer membership.mutex.Unlock()
+
+	return membership.set.List()
+}
+
+// Peers returns the members of the cluster
+// other than the local node in sorted order
+func (membership *Membership) Peers() []string {
+	peers := []string{}
+	for _, node := range membership.Members() {
+		if node != membership.self {
+			peers = append(peers, node)
+		}
+	}
+	return peers
+}
+
+// State returns a copy of the LWWSet
+// of the members replicated to peers
+func (membership *Membership) State() lwwset.LWWSet {
+	membership.mutex.Lock()
+	defer membership.mutex.Unlock()
+
+	return membership.set.Copy()
+}
+
+// Merge merges the LWWSet of the members replicated
+// from a peer and returns true if the members changed
+func (membership *Membership) Merge(state lwwset.LWWSet) bool {
+	membership.mutex.Lock()
+	defer membership.mutex.Unlock()
+
+	before := membership.set.List()
+	membership.set = lwwset.Merge(membership.set, state)
+	after := membership.set.List()
+
+	if len(before) != len(after) {
+		return true
+	}
+	for index := range before {
+		if before[index] != after[index] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMembership checks the basic functionality of Membership
+// the local node & the seeds should be members and nodes
+// should be added & removed by Join() & Leave()
+func TestMembership(t *testing.T) {
+	membership := NewMembership("peer-0", []string{"peer-0", "peer-1", ""})
+
+	assert.Equal(t, []string{"peer-0", "peer-1"}, membership.Members())
+	assert.Equal(t, []string{"peer-1"}, membership.Peers())
+
+	assert.Nil(t, membership.Join("peer-2"))
+	assert.True(t, membership.IsMember("peer-2"))
+	assert.Equal(t, []string{"peer-1", "peer-2"}, membership.Peers())
+
+	assert.Nil(t, membership.Leave("peer-1"))
+	assert.False(t, membership.IsMember("peer-1"))
+	assert.Equal(t, []string{"peer-2"}, membership.Peers())
+
+	// Nodes that have left can join back again
+	assert.Nil(t, membership.Join("peer-1"))
+	assert.Equal(t, []string{"peer-1", "peer-2"}, membership.Peers())
+}
+
+// TestMembership_Invalid checks the functionality of Membership
+// Join() & Leave() when an empty node or a node that
+// is not a member is passed, an error should be returned
+func TestMembership_Invalid(t *testing.T) {
+	membership := NewMembership("peer-0", nil)
+
+	assert.NotNil(t, membership.Join(""))
+	assert.NotNil(t, membership.Join("peer-1/twopset"))
+	assert.NotNil(t, membership.Join("peer 1"))
+	assert.NotNil(t, membership.Leave(""))
+	assert.NotNil(t, membership.Leave("peer-1"))
+	assert.Equal(t, []string{"peer-0"}, membership.Members())
+}
+
+// TestMembership_Merge checks the functionality of Membership Merge()
+// the Joins & Leaves replicated from a peer should be merged
+// and the Leave of a seed should take precedence over it
+func TestMembership_Merge(t *testing.T) {
+	local := NewMembership("peer-0", []string{"peer-1"})
+	remote := NewMembership("peer-1", []string{"peer-0"})
+
+	assert.Nil(t, remote.Join("peer-2"))
+	assert.Nil(t, remote.Leave("peer-0"))
+
+	assert.True(t, local.Merge(remote.State()))
+	assert.Equal(t, []string{"peer-1", "peer-2"}, local.Members())
+	assert.Equal(t, []string{"peer-1", "peer-2"}, local.Peers())
+
+	assert.False(t, local.Merge(remote.State()))
+
+	// A restarted node seeded with a node that has
+	// left should not add it back once merged
+	restarted := NewMembership("peer-3", []string{"peer-0", "peer-1"})
+	restarted.Merge(local.State())
+	assert.Equal(t, []string{"peer-1", "peer-2", "peer-3"}, restarted.Members())
+}
+
+// TestMembership_State checks the functionality of Membership State()
+// the state returned should be independent of the Membership
+func TestMembership_State(t *testing.T) {
+	membership := NewMembership("peer-0", nil)
+
+	state := membership.State()
+	assert.Nil(t, membership.Join("peer-1"))
+
+	_, present := state.Add["peer-1"]
+	assert.False(t, present)
+}
+
+// TestMembership_Discover checks the functionality of Membership Discover()
+// the nodes never members should be added & returned while
+// the nodes that have left should not be added back
+func TestMembership_Discover(t *testing.T) {
+	membership := NewMembership("peer-0", []string{"peer-1"})
+	assert.Nil(t, membership.Leave("peer-1"))
+
+	discovered := membership.Discover([]string{"peer-0", "peer-1", "10.0.0.2:8080", "[fd00::1]:8080", "bad/x"})
+	assert.Equal(t, []string{"10.0.0.2:8080", "[fd00::1]:8080"}, discovered)
+	assert.Equal(t, []string{"10.0.0.2:8080", "[fd00::1]:8080", "peer-0"}, membership.Members())
+
+	// A Leave of a node discovered takes precedence
+	assert.Nil(t, membership.Leave("10.0.0.2:8080"))
+	assert.Empty(t, membership.Discover([]string{"10.0.0.2:8080"}))
+	assert.False(t, membership.IsMember("10.0.0.2:8080"))
+}
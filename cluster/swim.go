@@ -0,0 +1,612 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemberState is the state of a member
+// in the SWIM failure detector
+type MemberState string
+
+const (
+	// StateAlive members acknowledged their latest probe
+	StateAlive MemberState = "alive"
+	// StateSuspect members failed their latest probe, directly
+	// & indirectly, and are declared dead unless they refute
+	// the suspicion within the suspicion timeout
+	StateSuspect MemberState = "suspect"
+	// StateDead members stayed suspected for the suspicion
+	// timeout and are no longer probed, they are alive again
+	// once they refute it with a higher incarnation
+	StateDead MemberState = "dead"
+)
+
+// MessageType is the type of a SWIM message
+type MessageType string
+
+const (
+	// MessagePing probes the node it is sent to
+	MessagePing MessageType = "ping"
+	// MessagePingReq asks the node it is sent
+	// to to probe the target on the sender's behalf
+	MessagePingReq MessageType = "ping_req"
+	// MessageAck acknowledges a ping, or a ping_req
+	// once the target has acknowledged the ping
+	MessageAck MessageType = "ack"
+	// MessageNack replies to a ping_req whose
+	// target did not acknowledge the ping
+	MessageNack MessageType = "nack"
+)
+
+const (
+	// DefaultProbeInterval is the default
+	// interval between SWIM protocol periods
+	DefaultProbeInterval = time.Second
+	// DefaultProbeTimeout is the default deadline
+	// for the direct & the indirect probes
+	DefaultProbeTimeout = 500 * time.Millisecond
+	// DefaultIndirectProbes is the default number of
+	// members asked to probe a member indirectly
+	DefaultIndirectProbes = 3
+	// DefaultSuspicionTimeout is the default time a member
+	// is suspected for before it is declared dead
+	DefaultSuspicionTimeout = 5 * time.Second
+	// DefaultRetransmits is the default number of
+	// messages each membership update is piggybacked on
+	DefaultRetransmits = 4
+
+	// maxPiggyback is the maximum number of membership
+	// updates piggybacked on each message
+	maxPiggyback = 8
+)
+
+// Update is a membership update
+// piggybacked on the SWIM messages
+type Update struct {
+	Node        string      `json:"node"`
+	State       MemberState `json:"state"`
+	Incarnation uint64      `json:"incarnation"`
+}
+
+// Message is a SWIM message
+type Message struct {
+	Type MessageType `json:"type"`
+	From string      `json:"from"`
+	// Target is the member to
+	// probe for a ping_req
+	Target  string   `json:"target,omitempty"`
+	Updates []Update `json:"updates,omitempty"`
+}
+
+// Transport sends the SWIM messages between the nodes
+type Transport interface {
+	// Send sends the message to the node and
+	// returns its reply, within the context deadline
+	Send(ctx context.Context, node string, message Message) (Message, error)
+}
+
+// SWIMConfig configures the SWIM failure detector
+type SWIMConfig struct {
+	// ProbeTimeout is the deadline for the
+	// direct & the indirect probes
+	ProbeTimeout time.Duration
+	// IndirectProbes is the number of members asked
+	// to probe a member that failed the direct probe
+	IndirectProbes int
+	// SuspicionTimeout is the time a member is
+	// suspected for before it is declared dead
+	SuspicionTimeout time.Duration
+	// Retransmits is the number of messages each
+	// membership update is piggybacked on
+	Retransmits int
+}
+
+// DefaultSWIMConfig returns the SWIMConfig with DefaultProbeTimeout,
+// DefaultIndirectProbes, DefaultSuspicionTimeout & DefaultRetransmits
+func DefaultSWIMConfig() SWIMConfig {
+	return SWIMConfig{
+		ProbeTimeout:     DefaultProbeTimeout,
+		IndirectProbes:   DefaultIndirectProbes,
+		SuspicionTimeout: DefaultSuspicionTimeout,
+		Retransmits:      DefaultRetransmits,
+	}
+}
+
+// Member is the JSON struct encapsulating the
+// state of a member in the SWIM failure detector
+type Member struct {
+	Node        string      `json:"node"`
+	State       MemberState `json:"state"`
+	Incarnation uint64      `json:"incarnation"`
+}
+
+// broadcast is a membership update along with the
+// number of messages it is still piggybacked on
+type broadcast struct {
+	update    Update
+	transmits int
+}
+
+// SWIM is the SWIM failure detector of the local node. Each protocol
+// period it probes a member with a ping, asking other members to
+// probe it with a ping_req if it does not acknowledge the ping,
+// and suspects it if neither succeed. The membership updates are
+// piggybacked on the messages & ordered by the incarnation numbers
+// of the members, a member refutes a suspicion of itself by
+// incrementing its incarnation
+// It is safe for concurrent use
+type SWIM struct {
+	mutex sync.Mutex
+	// self is the ID of the local node
+	self string
+	// incarnation is the incarnation of the local node
+	incarnation uint64
+	// members are the members other than the local node
+	members map[string]*Member
+	// suspected maps the suspected
+	// members to when they were suspected
+	suspected map[string]time.Time
+	// broadcasts are the membership updates
+	// to piggyback on the messages sent
+	broadcasts []*broadcast
+	// probes are the members left to probe in
+	// the current round, in a random order
+	probes []string
+
+	transport Transport
+	config    SWIMConfig
+	random    *rand.Rand
+	// now returns the current time
+	now func() time.Time
+}
+
+// NewSWIM returns a new SWIM failure detector of the local node
+// sending messages over the transport passed, reading the current
+// time from the function passed, or time.Now if it is nil
+func NewSWIM(self string, transport Transport, config SWIMConfig, now func() time.Time) *SWIM {
+	if now == nil {
+		now = time.Now
+	}
+	defaults := DefaultSWIMConfig()
+	if config.ProbeTimeout <= 0 {
+		config.ProbeTimeout = defaults.ProbeTimeout
+	}
+	if config.IndirectProbes < 0 {
+		config.IndirectProbes = defaults.IndirectProbes
+	}
+	if config.SuspicionTimeout <= 0 {
+		config.SuspicionTimeout = defaults.SuspicionTimeout
+	}
+	if config.Retransmits <= 0 {
+		config.Retransmits = defaults.Retransmits
+	}
+
+	return &SWIM{
+		self:      self,
+		members:   map[string]*Member{},
+		suspected: map[string]time.Time{},
+		transport: transport,
+		config:    config,
+		random:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:       now,
+	}
+}
+
+// SetMembers replaces the members probed with the nodes
+// passed, the new members are assumed alive
+func (swim *SWIM) SetMembers(nodes []string) {
+	swim.mutex.Lock()
+	defer swim.mutex.Unlock()
+
+	current := make(map[string]struct{}, len(nodes))
+	for _, node := range nodes {
+		if node == swim.self || node == "" {
+			continue
+		}
+		current[node] = struct{}{}
+		if _, known := swim.members[node]; !known {
+			swim.members[node] = &Member{Node: node, State: StateAlive}
+		}
+	}
+
+	for node := range swim.members {
+		if _, present := current[node]; !present {
+			delete(swim.members, node)
+			delete(swim.suspected, node)
+		}
+	}
+}
+
+// Alive returns the members
+// alive in sorted order
+func (swim *SWIM) Alive() []string {
+	swim.mutex.Lock()
+	defer swim.mutex.Unlock()
+
+	alive := []string{}
+	for node, member := range swim.members {
+		if member.State == StateAlive {
+			alive = append(alive, node)
+		}
+	}
+	sort.Strings(alive)
+	return alive
+}
+
+// Members returns the state of the
+// members sorted by node
+func (swim *SWIM) Members() []Member {
+	swim.mutex.Lock()
+	defer swim.mutex.Unlock()
+
+	members := make([]Member, 0, len(swim.members))
+	for _, member := range swim.members {
+		members = append(members, *member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Node < members[j].Node
+	})
+	return members
+}
+
+// Incarnation returns the
+// incarnation of the local node
+func (swim *SWIM) Incarnation() uint64 {
+	swim.mutex.Lock()
+	defer swim.mutex.Unlock()
+	return swim.incarnation
+}
+
+// Handle handles a message received from
+// a node and returns the reply to it
+func (swim *SWIM) Handle(ctx context.Context, message Message) (Message, error) {
+	swim.merge(message.Updates)
+
+	switch message.Type {
+	case MessagePing:
+		return swim.message(MessageAck, message.From, ""), nil
+	case MessagePingReq:
+		if message.Target == "" {
+			return Message{}, errors.New("empty target provided")
+		}
+		if swim.ping(ctx, message.Target) {
+			return swim.message(MessageAck, message.From, message.Target), nil
+		}
+		return swim.message(MessageNack, message.From, message.Target), nil
+	default:
+		return Message{}, errors.New("invalid message type provided: " + string(message.Type))
+	}
+}
+
+// Probe runs a SWIM protocol period, declaring the members
+// suspected for longer than the suspicion timeout dead and
+// probing the next member, which is suspected if neither the
+// direct nor the indirect probes are acknowledged
+// It returns the member probed, if any
+func (swim *SWIM) Probe(ctx context.Context) string {
+	swim.expireSuspects()
+
+	target, ok := swim.nextProbe()
+	if !ok {
+		return ""
+	}
+
+	if swim.ping(ctx, target) || swim.pingIndirect(ctx, target) {
+		return target
+	}
+
+	swim.suspect(target)
+	return target
+}
+
+// ping sends a ping to the node within the probe
+// timeout and returns true if it was acknowledged
+func (swim *SWIM) ping(ctx context.Context, node string) bool {
+	ctx, cancel := context.WithTimeout(ctx, swim.config.ProbeTimeout)
+	defer cancel()
+
+	reply, err := swim.transport.Send(ctx, node, swim.message(MessagePing, node, ""))
+	if err != nil {
+		return false
+	}
+	swim.merge(reply.Updates)
+	return reply.Type == MessageAck
+}
+
+// pingIndirect asks random members to probe the target concurrently
+// within the probe timeout and returns true if any of them did
+func (swim *SWIM) pingIndirect(ctx context.Context, target string) bool {
+	helpers := swim.randomMembers(swim.config.IndirectProbes, target)
+	if len(helpers) == 0 {
+		return false
+	}
+
+	// The helpers probe the target within the probe
+	// timeout, which they are given twice to reply in
+	ctx, cancel := context.WithTimeout(ctx, 2*swim.config.ProbeTimeout)
+	defer cancel()
+
+	acks := make(chan bool, len(helpers))
+	for _, helper := range helpers {
+		go func(helper string) {
+			reply, err := swim.transport.Send(ctx, helper, swim.message(MessagePingReq, helper, target))
+			if err != nil {
+				acks <- false
+				return
+			}
+			swim.merge(reply.Updates)
+			acks <- reply.Type == MessageAck
+		}(helper)
+	}
+
+	for range helpers {
+		if <-acks {
+			return true
+		}
+	}
+	return false
+}
+
+// nextProbe returns the next member to probe, going
+// through the members not dead in a random order
+func (swim *SWIM) nextProbe() (string, bool) {
+	swim.mutex.Lock()
+	defer swim.mutex.Unlock()
+
+	for attempt := 0; attempt < 2; attempt++ {
+		for len(swim.probes) > 0 {
+			node := swim.probes[0]
+			swim.probes = swim.probes[1:]
+			if member, known := swim.members[node]; known && member.State != StateDead {
+				return node, true
+			}
+		}
+
+		// Start a new round
+		for node, member := range swim.members {
+			if member.State != StateDead {
+				swim.probes = append(swim.probes, node)
+			}
+		}
+		sort.Strings(swim.probes)
+		swim.random.Shuffle(len(swim.probes), func(i, j int) {
+			swim.probes[i], swim.probes[j] = swim.probes[j], swim.probes[i]
+		})
+	}
+
+	return "", false
+}
+
+// randomMembers returns at most count random
+// members alive other than the one excluded
+func (swim *SWIM) randomMembers(count int, excluded string) []string {
+	swim.mutex.Lock()
+	defer swim.mutex.Unlock()
+
+	candidates := []string{}
+	for node, member := range swim.members {
+		if node != excluded && member.State == StateAlive {
+			candidates = append(candidates, node)
+		}
+	}
+	sort.Strings(candidates)
+	swim.random.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	if count < len(candidates) {
+		candidates = candidates[:count]
+	}
+	return candidates
+}
+
+// suspect suspects the member if it is alive
+func (swim *SWIM) suspect(node string) {
+	swim.mutex.Lock()
+	defer swim.mutex.Unlock()
+
+	member, known := swim.members[node]
+	if !known || member.State != StateAlive {
+		return
+	}
+	swim.apply(Update{Node: node, State: StateSuspect, Incarnation: member.Incarnation})
+}
+
+// expireSuspects declares the members suspected
+// for longer than the suspicion timeout dead
+func (swim *SWIM) expireSuspects() {
+	swim.mutex.Lock()
+	defer swim.mutex.Unlock()
+
+	now := swim.now()
+	for node, since := range swim.suspected {
+		if now.Sub(since) < swim.config.SuspicionTimeout {
+			continue
+		}
+		member := swim.members[node]
+		swim.apply(Update{Node: node, State: StateDead, Incarnation: member.Incarnation})
+	}
+}
+
+// merge applies the membership updates received
+func (swim *SWIM) merge(updates []Update) {
+	swim.mutex.Lock()
+	defer swim.mutex.Unlock()
+
+	for _, update := range updates {
+		swim.apply(update)
+	}
+}
+
+// apply applies a membership update if it is newer than the
+// state of the member, broadcasting it to the other members
+// It must be called with the mutex held
+func (swim *SWIM) apply(update Update) {
+	// Refute the suspicions & deaths of the local node
+	if update.Node == swim.self {
+		if update.State != StateAlive && update.Incarnation >= swim.incarnation {
+			swim.incarnation = update.Incarnation + 1
+		}
+		return
+	}
+
+	// Only the members passed to SetMembers()
+	// are tracked, so only they are probed
+	member, known := swim.members[update.Node]
+	if !known {
+		return
+	}
+
+	switch update.State {
+	case StateAlive:
+		if update.Incarnation <= member.Incarnation {
+			return
+		}
+	case StateSuspect:
+		if update.Incarnation < member.Incarnation {
+			return
+		}
+		if update.Incarnation == member.Incarnation && member.State != StateAlive {
+			return
+		}
+	case StateDead:
+		if update.Incarnation < member.Incarnation {
+			return
+		}
+		if update.Incarnation == member.Incarnation && member.State == StateDead {
+			return
+		}
+	default:
+		return
+	}
+
+	member.State = update.State
+	member.Incarnation = update.Incarnation
+
+	if update.State == StateSuspect {
+		swim.suspected[update.Node] = swim.now()
+	} else {
+		delete(swim.suspected, update.Node)
+	}
+
+	swim.queue(update)
+}
+
+// queue piggybacks the membership update on the next
+// messages, replacing any update of the same member
+// It must be called with the mutex held
+func (swim *SWIM) queue(update Update) {
+	for _, queued := range swim.broadcasts {
+		if queued.update.Node == update.Node {
+			queued.update = update
+			queued.transmits = swim.config.Retransmits
+			return
+		}
+	}
+	swim.broadcasts = append(swim.broadcasts, &broadcast{update: update, transmits: swim.config.Retransmits})
+}
+
+// message returns a message to the node piggybacking the
+// local node as alive & the membership updates queued
+// The node is also told if it is suspected or dead so
+// it can refute it, even if the update was already sent
+func (swim *SWIM) message(messageType MessageType, to string, target string) Message {
+	swim.mutex.Lock()
+	defer swim.mutex.Unlock()
+
+	message := Message{
+		Type:    messageType,
+		From:    swim.self,
+		Target:  target,
+		Updates: []Update{{Node: swim.self, State: StateAlive, Incarnation: swim.incarnation}},
+	}
+
+	notified := false
+	if member, known := swim.members[to]; known && member.State != StateAlive {
+		message.Updates = append(message.Updates, Update{Node: to, State: member.State, Incarnation: member.Incarnation})
+		notified = true
+	}
+
+	remaining := swim.broadcasts[:0]
+	for _, queued := range swim.broadcasts {
+		if len(message.Updates) < maxPiggyback {
+			if !notified || queued.update.Node != to {
+				message.Updates = append(message.Updates, queued.update)
+			}
+			queued.transmits--
+		}
+		if queued.transmits > 0 {
+			remaining = append(remaining, queued)
+		}
+	}
+	swim.broadcasts = remaining
+
+	return message
+}
+
+// MemoryNetwork is an in-process Transport between
+// SWIM failure detectors used for testing
+// Messages to nodes that are down or across a
+// cut link fail immediately
+// It is safe for concurrent use
+type MemoryNetwork struct {
+	mutex sync.Mutex
+	nodes map[string]*SWIM
+	down  map[string]bool
+	cut   map[[2]string]bool
+}
+
+// NewMemoryNetwork returns a new empty MemoryNetwork
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{
+		nodes: map[string]*SWIM{},
+		down:  map[string]bool{},
+		cut:   map[[2]string]bool{},
+	}
+}
+
+// Add adds a SWIM failure detector to
+// the network, receiving messages to its node
+func (network *MemoryNetwork) Add(swim *SWIM) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	network.nodes[swim.self] = swim
+}
+
+// SetDown stops or resumes the delivery
+// of the messages to & from the node
+func (network *MemoryNetwork) SetDown(node string, down bool) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	network.down[node] = down
+}
+
+// SetCut cuts or restores the link between two nodes
+func (network *MemoryNetwork) SetCut(from string, to string, cut bool) {
+	network.mutex.Lock()
+	defer network.mutex.Unlock()
+	network.cut[[2]string{from, to}] = cut
+	network.cut[[2]string{to, from}] = cut
+}
+
+// Send delivers the message to the node
+// and returns its reply
+func (network *MemoryNetwork) Send(ctx context.Context, node string, message Message) (Message, error) {
+	network.mutex.Lock()
+	swim, known := network.nodes[node]
+	unreachable := network.down[node] || network.down[message.From] || network.cut[[2]string{message.From, node}]
+	network.mutex.Unlock()
+
+	if !known || unreachable {
+		return Message{}, errors.New("node unreachable: " + node)
+	}
+	if err := ctx.Err(); err != nil {
+		return Message{}, err
+	}
+	return swim.Handle(ctx, message)
+}
@@ -0,0 +1,176 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSWIMCluster returns SWIM failure detectors for the
+// given nodes connected by a MemoryNetwork, each with
+// every other node as a member
+func newSWIMCluster(now *time.Time, nodes ...string) (*MemoryNetwork, map[string]*SWIM) {
+	network := NewMemoryNetwork()
+	swims := map[string]*SWIM{}
+
+	config := SWIMConfig{ProbeTimeout: time.Second, IndirectProbes: 2, SuspicionTimeout: 5 * time.Second, Retransmits: 4}
+	for _, node := range nodes {
+		swim := NewSWIM(node, network, config, fixedTime(now))
+		swim.SetMembers(nodes)
+		network.Add(swim)
+		swims[node] = swim
+	}
+
+	return network, swims
+}
+
+// probe runs protocol periods on the SWIM
+// failure detector until the target is probed
+func probe(t *testing.T, swim *SWIM, target string) {
+	for attempt := 0; attempt < 10; attempt++ {
+		if swim.Probe(context.Background()) == target {
+			return
+		}
+	}
+	t.Fatalf("%s was not probed", target)
+}
+
+// state returns the state of the
+// node in the SWIM failure detector
+func state(swim *SWIM, node string) MemberState {
+	for _, member := range swim.Members() {
+		if member.Node == node {
+			return member.State
+		}
+	}
+	return ""
+}
+
+// TestSWIM checks the basic functionality of SWIM
+// members acknowledging their probes should stay alive
+func TestSWIM(t *testing.T) {
+	now := time.Unix(100, 0)
+	_, swims := newSWIMCluster(&now, "peer-0", "peer-1", "peer-2")
+
+	probe(t, swims["peer-0"], "peer-1")
+	probe(t, swims["peer-0"], "peer-2")
+
+	assert.Equal(t, []string{"peer-1", "peer-2"}, swims["peer-0"].Alive())
+	assert.Equal(t, []Member{
+		{Node: "peer-1", State: StateAlive},
+		{Node: "peer-2", State: StateAlive},
+	}, swims["peer-0"].Members())
+}
+
+// TestSWIM_IndirectProbe checks the functionality of SWIM Probe()
+// when the link to a member is cut, it should be probed
+// indirectly through the other members & stay alive
+func TestSWIM_IndirectProbe(t *testing.T) {
+	now := time.Unix(100, 0)
+	network, swims := newSWIMCluster(&now, "peer-0", "peer-1", "peer-2")
+
+	network.SetCut("peer-0", "peer-1", true)
+	probe(t, swims["peer-0"], "peer-1")
+
+	assert.Equal(t, StateAlive, state(swims["peer-0"], "peer-1"))
+}
+
+// TestSWIM_Suspect checks the functionality of SWIM Probe()
+// when a member is down, it should be suspected, the suspicion
+// gossiped to the other members & the member declared dead
+// once the suspicion timeout elapses
+func TestSWIM_Suspect(t *testing.T) {
+	now := time.Unix(100, 0)
+	network, swims := newSWIMCluster(&now, "peer-0", "peer-1", "peer-2")
+
+	network.SetDown("peer-1", true)
+	probe(t, swims["peer-0"], "peer-1")
+
+	assert.Equal(t, StateSuspect, state(swims["peer-0"], "peer-1"))
+	assert.Equal(t, []string{"peer-2"}, swims["peer-0"].Alive())
+
+	// The suspicion is piggybacked on the next probe
+	probe(t, swims["peer-0"], "peer-2")
+	assert.Equal(t, StateSuspect, state(swims["peer-2"], "peer-1"))
+
+	now = now.Add(5 * time.Second)
+	swims["peer-0"].Probe(context.Background())
+	assert.Equal(t, StateDead, state(swims["peer-0"], "peer-1"))
+}
+
+// TestSWIM_Refute checks the functionality of SWIM Handle()
+// when a suspected member is reachable again, it should
+// refute the suspicion with a higher incarnation
+func TestSWIM_Refute(t *testing.T) {
+	now := time.Unix(100, 0)
+	network, swims := newSWIMCluster(&now, "peer-0", "peer-1", "peer-2")
+
+	network.SetDown("peer-1", true)
+	probe(t, swims["peer-0"], "peer-1")
+	assert.Equal(t, StateSuspect, state(swims["peer-0"], "peer-1"))
+
+	network.SetDown("peer-1", false)
+	probe(t, swims["peer-0"], "peer-1")
+
+	assert.Equal(t, uint64(1), swims["peer-1"].Incarnation())
+	assert.Equal(t, StateAlive, state(swims["peer-0"], "peer-1"))
+	assert.Equal(t, []Member{
+		{Node: "peer-1", State: StateAlive, Incarnation: 1},
+		{Node: "peer-2", State: StateAlive},
+	}, swims["peer-0"].Members())
+}
+
+// TestSWIM_Rejoin checks the functionality of SWIM Handle()
+// when a member declared dead probes a member, it should
+// learn it is dead & be alive again once it refutes it
+func TestSWIM_Rejoin(t *testing.T) {
+	now := time.Unix(100, 0)
+	network, swims := newSWIMCluster(&now, "peer-0", "peer-1")
+
+	network.SetDown("peer-1", true)
+	probe(t, swims["peer-0"], "peer-1")
+	now = now.Add(5 * time.Second)
+	swims["peer-0"].Probe(context.Background())
+	assert.Equal(t, StateDead, state(swims["peer-0"], "peer-1"))
+
+	// Dead members are no longer probed
+	assert.Equal(t, "", swims["peer-0"].Probe(context.Background()))
+
+	network.SetDown("peer-1", false)
+	probe(t, swims["peer-1"], "peer-0")
+	probe(t, swims["peer-1"], "peer-0")
+
+	assert.Equal(t, StateAlive, state(swims["peer-0"], "peer-1"))
+	assert.Equal(t, []string{"peer-1"}, swims["peer-0"].Alive())
+}
+
+// TestSWIM_SetMembers checks the functionality of SWIM SetMembers()
+// new members should be assumed alive and the members
+// not passed should no longer be tracked
+func TestSWIM_SetMembers(t *testing.T) {
+	swim := NewSWIM("peer-0", NewMemoryNetwork(), DefaultSWIMConfig(), nil)
+
+	swim.SetMembers([]string{"peer-0", "peer-1", "peer-2"})
+	assert.Equal(t, []string{"peer-1", "peer-2"}, swim.Alive())
+
+	swim.SetMembers([]string{"peer-2", "peer-3"})
+	assert.Equal(t, []string{"peer-2", "peer-3"}, swim.Alive())
+}
+
+// TestSWIM_Handle checks the functionality of SWIM Handle()
+// when an invalid message is received, an error should be returned
+func TestSWIM_Handle(t *testing.T) {
+	swim := NewSWIM("peer-0", NewMemoryNetwork(), DefaultSWIMConfig(), nil)
+
+	_, err := swim.Handle(context.Background(), Message{Type: "invalid", From: "peer-1"})
+	assert.NotNil(t, err)
+
+	_, err = swim.Handle(context.Background(), Message{Type: MessagePingReq, From: "peer-1"})
+	assert.NotNil(t, err)
+
+	reply, err := swim.Handle(context.Background(), Message{Type: MessagePingReq, From: "peer-1", Target: "peer-2"})
+	assert.Nil(t, err)
+	assert.Equal(t, MessageNack, reply.Type)
+}
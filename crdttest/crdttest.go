@@ -0,0 +1,259 @@
+package crdttest
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// package crdttest implements randomized property checks of the laws a state-based
+// CRDT must satisfy: its Merge must be commutative, associative & idempotent and its
+// mutators must be monotonic i.e. never move the state backwards in the lattice.
+// Any type satisfying the CRDT interface can be checked by calling Run in a test
+
+// CRDT is the interface a state-based
+// CRDT must satisfy to be checked
+type CRDT interface {
+	// Merge returns the join of the CRDT with another
+	// CRDT of the same type without modifying either
+	Merge(other CRDT) CRDT
+	// LessOrEqual returns true if the other CRDT
+	// has observed every update of the CRDT
+	LessOrEqual(other CRDT) bool
+	// Equal returns true if both CRDTs
+	// have the same state
+	Equal(other CRDT) bool
+	// Copy returns an independent copy of the CRDT
+	Copy() CRDT
+}
+
+// Mutator applies an update for a value to a CRDT
+// and returns the updated CRDT, e.g. an Addition
+type Mutator func(state CRDT, value string) (CRDT, error)
+
+// Config describes the CRDT to check
+// and how its random states are built
+type Config struct {
+	// Empty returns a new empty CRDT
+	Empty func() CRDT
+	// Mutators are the named
+	// updates of the CRDT
+	Mutators map[string]Mutator
+	// Iterations is the number of random states
+	// checked per law, defaults to 100
+	Iterations int
+	// Operations is the maximum number of updates
+	// applied to build a random state, defaults to 20
+	Operations int
+	// Values is the number of distinct values the
+	// updates are applied with, defaults to 8
+	Values int
+	// Seed seeds the random states, a failure
+	// can be reproduced by using the same Seed
+	Seed int64
+}
+
+// withDefaults returns the Config with
+// its unset fields set to their defaults
+func (config Config) withDefaults() Config {
+	if config.Iterations <= 0 {
+		config.Iterations = 100
+	}
+	if config.Operations <= 0 {
+		config.Operations = 20
+	}
+	if config.Values <= 0 {
+		config.Values = 8
+	}
+	return config
+}
+
+// Run checks every law against the CRDT
+// described by the Config as subtests
+func Run(t *testing.T, config Config) {
+	laws := []struct {
+		name  string
+		check func(Config) error
+	}{
+		{"Commutativity", CheckCommutativity},
+		{"Associativity", CheckAssociativity},
+		{"Idempotence", CheckIdempotence},
+		{"Monotonicity", CheckMonotonicity},
+	}
+
+	for _, law := range laws {
+		law := law
+		t.Run(law.name, func(t *testing.T) {
+			if err := law.check(config); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// CheckCommutativity checks that a ⊔ b = b ⊔ a
+func CheckCommutativity(config Config) error {
+	return check(config, func(generator *generator) error {
+		a, err := generator.state()
+		if err != nil {
+			return err
+		}
+		b, err := generator.state()
+		if err != nil {
+			return err
+		}
+
+		if !a.Merge(b).Equal(b.Merge(a)) {
+			return generator.failure("merge is not commutative")
+		}
+		return nil
+	})
+}
+
+// CheckAssociativity checks that (a ⊔ b) ⊔ c = a ⊔ (b ⊔ c)
+func CheckAssociativity(config Config) error {
+	return check(config, func(generator *generator) error {
+		a, err := generator.state()
+		if err != nil {
+			return err
+		}
+		b, err := generator.state()
+		if err != nil {
+			return err
+		}
+		c, err := generator.state()
+		if err != nil {
+			return err
+		}
+
+		if !a.Merge(b).Merge(c).Equal(a.Merge(b.Merge(c))) {
+			return generator.failure("merge is not associative")
+		}
+		return nil
+	})
+}
+
+// CheckIdempotence checks that a ⊔ a = a
+func CheckIdempotence(config Config) error {
+	return check(config, func(generator *generator) error {
+		a, err := generator.state()
+		if err != nil {
+			return err
+		}
+
+		if !a.Merge(a.Copy()).Equal(a) {
+			return generator.failure("merge is not idempotent")
+		}
+		return nil
+	})
+}
+
+// CheckMonotonicity checks that every mutator inflates the
+// state i.e. a ≤ mutate(a), and that a ≤ a ⊔ b
+func CheckMonotonicity(config Config) error {
+	return check(config, func(generator *generator) error {
+		a, err := generator.state()
+		if err != nil {
+			return err
+		}
+		b, err := generator.state()
+		if err != nil {
+			return err
+		}
+
+		if !a.LessOrEqual(a.Merge(b)) || !b.LessOrEqual(a.Merge(b)) {
+			return generator.failure("merge is not an upper bound")
+		}
+
+		for _, name := range generator.names {
+			value := generator.value()
+			mutated, err := generator.mutators[name](a.Copy(), value)
+			if err != nil {
+				return generator.failure(fmt.Sprintf("%s(%q) failed: %v", name, value, err))
+			}
+			if !a.LessOrEqual(mutated) {
+				return generator.failure(fmt.Sprintf("%s(%q) is not monotonic", name, value))
+			}
+		}
+		return nil
+	})
+}
+
+// check runs the law for each iteration of the Config
+func check(config Config, law func(*generator) error) error {
+	config = config.withDefaults()
+
+	if config.Empty == nil {
+		return fmt.Errorf("crdttest: no Empty function provided")
+	}
+	if len(config.Mutators) == 0 {
+		return fmt.Errorf("crdttest: no Mutators provided")
+	}
+
+	generator := newGenerator(config)
+	for generator.iteration = 0; generator.iteration < config.Iterations; generator.iteration++ {
+		if err := law(generator); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generator builds random CRDT states
+type generator struct {
+	config    Config
+	random    *rand.Rand
+	mutators  map[string]Mutator
+	names     []string
+	iteration int
+}
+
+// newGenerator returns a new generator for the Config
+// The mutators are sorted by name so a Seed
+// always generates the same states
+func newGenerator(config Config) *generator {
+	names := make([]string, 0, len(config.Mutators))
+	for name := range config.Mutators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return &generator{
+		config:   config,
+		random:   rand.New(rand.NewSource(config.Seed)),
+		mutators: config.Mutators,
+		names:    names,
+	}
+}
+
+// value returns a random value
+func (generator *generator) value() string {
+	return fmt.Sprintf("v%d", generator.random.Intn(generator.config.Values))
+}
+
+// state returns a random CRDT state built by
+// applying random updates to an empty CRDT
+func (generator *generator) state() (CRDT, error) {
+	state := generator.config.Empty()
+
+	operations := generator.random.Intn(generator.config.Operations + 1)
+	for operation := 0; operation < operations; operation++ {
+		name := generator.names[generator.random.Intn(len(generator.names))]
+		value := generator.value()
+
+		var err error
+		state, err = generator.mutators[name](state, value)
+		if err != nil {
+			return nil, generator.failure(fmt.Sprintf("%s(%q) failed: %v", name, value, err))
+		}
+	}
+
+	return state, nil
+}
+
+// failure returns an error describing how to
+// reproduce the current iteration
+func (generator *generator) failure(message string) error {
+	return fmt.Errorf("crdttest: %s (seed %d, iteration %d)", message, generator.config.Seed, generator.iteration)
+}
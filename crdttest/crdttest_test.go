@@ -0,0 +1,116 @@
+package crdttest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// maxRegister is a CRDT holding the longest
+// value written to it, used to test the checks
+type maxRegister struct {
+	value string
+}
+
+func (register maxRegister) Merge(other CRDT) CRDT {
+	if len(other.(maxRegister).value) > len(register.value) {
+		return other
+	}
+	return register
+}
+
+func (register maxRegister) LessOrEqual(other CRDT) bool {
+	return len(register.value) <= len(other.(maxRegister).value)
+}
+
+func (register maxRegister) Equal(other CRDT) bool {
+	return len(register.value) == len(other.(maxRegister).value)
+}
+
+func (register maxRegister) Copy() CRDT {
+	return register
+}
+
+// lastRegister is a broken CRDT whose Merge keeps
+// the last value merged, used to test the checks
+type lastRegister struct {
+	maxRegister
+}
+
+func (register lastRegister) Merge(other CRDT) CRDT {
+	return other
+}
+
+func (register lastRegister) LessOrEqual(other CRDT) bool {
+	return true
+}
+
+func (register lastRegister) Equal(other CRDT) bool {
+	return register.value == other.(lastRegister).value
+}
+
+func (register lastRegister) Copy() CRDT {
+	return register
+}
+
+// maxConfig returns the Config of the maxRegister
+func maxConfig() Config {
+	return Config{
+		Empty: func() CRDT { return maxRegister{} },
+		Mutators: map[string]Mutator{
+			"write": func(state CRDT, value string) (CRDT, error) {
+				return state.Merge(maxRegister{value: state.(maxRegister).value + value}), nil
+			},
+		},
+		Seed: 1,
+	}
+}
+
+// TestRun checks the basic functionality of Run()
+// every law should hold for a valid CRDT
+func TestRun(t *testing.T) {
+	Run(t, maxConfig())
+}
+
+// TestCheckCommutativity_Broken checks the functionality of the checks
+// when Merge is not commutative, it should return an error
+func TestCheckCommutativity_Broken(t *testing.T) {
+	config := Config{
+		Empty: func() CRDT { return lastRegister{} },
+		Mutators: map[string]Mutator{
+			"write": func(state CRDT, value string) (CRDT, error) {
+				return lastRegister{maxRegister{value: value}}, nil
+			},
+		},
+		Operations: 1,
+		Seed:       1,
+	}
+
+	assert.Error(t, CheckCommutativity(config))
+	assert.NoError(t, CheckIdempotence(config))
+}
+
+// TestCheckMonotonicity_Broken checks the functionality of the checks
+// when a mutator moves the state backwards, it should return an error
+func TestCheckMonotonicity_Broken(t *testing.T) {
+	config := maxConfig()
+	config.Mutators["reset"] = func(state CRDT, value string) (CRDT, error) {
+		return maxRegister{}, nil
+	}
+
+	actualError := CheckMonotonicity(config)
+
+	assert.Error(t, actualError)
+	assert.Contains(t, actualError.Error(), "seed 1")
+}
+
+// TestCheck_NoConfig checks the functionality of the checks
+// when the Config is incomplete, it should return an error
+func TestCheck_NoConfig(t *testing.T) {
+	assert.EqualError(t, CheckIdempotence(Config{}), "crdttest: no Empty function provided")
+
+	config := maxConfig()
+	config.Mutators = nil
+
+	assert.EqualError(t, CheckIdempotence(config), "crdttest: no Mutators provided")
+}
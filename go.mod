@@ -3,7 +3,6 @@ module github.com/el10savio/twoPSet-crdt
 go 1.14
 
 require (
-	github.com/el10savio/gset-crdt v0.0.0-20200905084909-637da04284fc
 	github.com/gorilla/mux v1.8.0
 	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.6.1
@@ -5,31 +5,65 @@ import (
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
 )
 
 // Add is the HTTP handler used to append
 // values to the TwoPSet node in the server
+// With ?consistency=quorum or ?consistency=all it only
+// returns once a majority or all of the peers have
+// acknowledged the value added
 func Add(w http.ResponseWriter, r *http.Request) {
-	var err error
-
 	// Obtain the value from URL params
 	value := mux.Vars(r)["value"]
 
-	// Add the given value to our stored TwoPSet
-	TwoPSet, err = TwoPSet.Addition(value)
+	// Fail before adding the value if the
+	// consistency level can not be met
+	level, err := parseConsistency(r)
+	if err == nil {
+		err = checkWriteConsistency(level)
+	}
 	if err != nil {
+		log.WithFields(log.Fields{"error": err, "value": value}).Debug("rejected twopset addition")
+		writeConsistencyError(w, err)
+		return
+	}
+
+	// Add the given value to our stored set
+	// Returns HTTP 409 Conflict if the value
+	// was removed & its tombstone purged
+	err = Node.Addition(value)
+	switch err {
+	case nil:
+	case twopset.ErrPurged:
+		log.WithFields(log.Fields{"error": err, "value": value}).Debug("rejected twopset addition")
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
 		log.WithFields(log.Fields{"error": err}).Error("failed to add value")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	// DEBUG log in the case of success indicating
-	// the new TwoPSet and the value added
+	// DEBUG log in the case of success
+	// indicating the value added
 	log.WithFields(log.Fields{
-		"set":   TwoPSet,
 		"value": value,
 	}).Debug("successful twopset addition")
 
+	// Wait for the peers required by the
+	// consistency level to acknowledge it
+	delta, err := twopset.Initialize().Addition(value)
+	if err == nil {
+		err = writeConsistency(r.Context(), level, delta)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"error": err, "value": value}).Warn("failed to meet twopset addition consistency")
+		writeConsistencyError(w, err)
+		return
+	}
+
 	// Return HTTP 200 OK in the case of success
 	w.WriteHeader(http.StatusOK)
 }
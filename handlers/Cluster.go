@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
+	"github.com/el10savio/twoPSet-crdt/lwwset"
+)
+
+// ClusterMembers is the JSON struct
+// encapsulating the members of the cluster
+type ClusterMembers struct {
+	Self    string   `json:"self"`
+	Members []string `json:"members"`
+	// States are the states of the peers in the
+	// SWIM failure detector when it is enabled
+	States []cluster.Member `json:"states,omitempty"`
+}
+
+// clusterMembers returns the
+// members of the cluster
+func clusterMembers() ClusterMembers {
+	members := ClusterMembers{Self: Members.Self(), Members: Members.Members()}
+	if Detector != nil {
+		members.States = Detector.Members()
+	}
+	return members
+}
+
+// Join is the HTTP handler used to add a node to the cluster
+// The node is obtained from the ?node= URL query param and
+// defaults to the local node. The new members are pushed to
+// every peer, including the node joining
+func Join(w http.ResponseWriter, r *http.Request) {
+	node := r.URL.Query().Get("node")
+	if node == "" {
+		node = Members.Self()
+	}
+
+	if err := Members.Join(node); err != nil {
+		log.WithFields(log.Fields{"error": err, "node": node}).Error("failed to join cluster")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report := pushMembership(r.Context(), GetPeerList())
+
+	// INFO log in the case of success
+	// indicating the node joining
+	log.WithFields(log.Fields{
+		"node":    node,
+		"members": Members.Members(),
+		"pushed":  report.OK(),
+	}).Info("successful cluster join")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clusterMembers())
+}
+
+// Leave is the HTTP handler used to remove a node from the cluster
+// The node is obtained from the ?node= URL query param and
+// defaults to the local node. The new members are pushed to
+// every peer, including the node leaving
+func Leave(w http.ResponseWriter, r *http.Request) {
+	node := r.URL.Query().Get("node")
+	if node == "" {
+		node = Members.Self()
+	}
+
+	if err := Members.Leave(node); err != nil {
+		log.WithFields(log.Fields{"error": err, "node": node}).Error("failed to leave cluster")
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	nodes := GetPeerList()
+	if node != Members.Self() {
+		nodes = append(nodes, node)
+	}
+	report := pushMembership(r.Context(), nodes)
+
+	// INFO log in the case of success
+	// indicating the node leaving
+	log.WithFields(log.Fields{
+		"node":    node,
+		"members": Members.Members(),
+		"pushed":  report.OK(),
+	}).Info("successful cluster leave")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clusterMembers())
+}
+
+// ListMembers is the HTTP handler used to
+// return the members of the cluster
+func ListMembers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clusterMembers())
+}
+
+// Membership is the HTTP handler used to return
+// the LWWSet of the members replicated to peers
+func Membership(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Members.State())
+}
+
+// MergeMembership is the HTTP handler used to merge
+// the LWWSet of the members pushed by a peer
+func MergeMembership(w http.ResponseWriter, r *http.Request) {
+	var state lwwset.LWWSet
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, mergeMaxBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&state); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := validateMembership(state); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mergeMembership(state)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clusterMembers())
+}
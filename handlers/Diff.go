@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// PeerDiff is the JSON struct
+// encapsulating the Diff Response
+type PeerDiff struct {
+	// Peer is the peer compared with
+	Peer string `json:"peer"`
+	// Order is how the local TwoPSet
+	// compares to the peer's TwoPSet
+	Order twopset.Ordering `json:"order"`
+	// Missing are the Additions & Removals
+	// the peer has that the local node lacks
+	Missing twopset.TwoPSet `json:"missing"`
+	// Extra are the Additions & Removals the
+	// local node has that the peer lacks
+	Extra twopset.TwoPSet `json:"extra"`
+}
+
+// Diff is the HTTP handler used to return the
+// difference between the local TwoPSet and the
+// TwoPSet of the peer passed as ?peer=<id>
+func Diff(w http.ResponseWriter, r *http.Request) {
+	// Diffs are only supported
+	// when serving a TwoPSet
+	if _, ok := Node.(twoPSetNode); !ok {
+		http.Error(w, "diff is only supported for the twopset", http.StatusNotImplemented)
+		return
+	}
+
+	// Obtain the peer from URL query params
+	peer := r.URL.Query().Get("peer")
+	if peer == "" {
+		http.Error(w, "empty peer provided", http.StatusBadRequest)
+		return
+	}
+
+	// Only peers in the cluster can be diffed
+	if !isPeer(peer) {
+		http.Error(w, "unknown peer provided", http.StatusNotFound)
+		return
+	}
+
+	peerTwoPSet, err := SendListRequest(r.Context(), peer)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err, "peer": peer}).Error("failed sending twopset values request")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	// Compare a copy of the TwoPSet as it
+	// is written to while comparing
+	local := copyState().TwoPSet
+
+	diff := PeerDiff{
+		Peer:    peer,
+		Order:   twopset.Compare(local, peerTwoPSet),
+		Missing: twopset.Diff(local, peerTwoPSet),
+		Extra:   twopset.Diff(peerTwoPSet, local),
+	}
+
+	// DEBUG log in the case of success
+	// indicating the peer and the diff
+	log.WithFields(log.Fields{
+		"peer": peer,
+		"diff": diff,
+	}).Debug("successful twopset diff")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// isPeer returns true if the given
+// peer is present in the cluster
+func isPeer(peer string) bool {
+	for _, _peer := range GetPeerList() {
+		if _peer == peer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GCStatus is the HTTP handler used to report on the TwoPSet
+// tombstones that are causally stable across the cluster
+func GCStatus(w http.ResponseWriter, r *http.Request) {
+	// Tombstones are only tracked
+	// when serving a TwoPSet
+	if _, ok := Node.(twoPSetNode); !ok {
+		http.Error(w, "garbage collection is only supported for the twopset", http.StatusNotImplemented)
+		return
+	}
+
+	// Tombstones are only stable once observed by every
+	// member, including the ones suspected or dead
+	storeMutex.Lock()
+	report := Collector.Status(TwoPSet, Members.Peers())
+	storeMutex.Unlock()
+
+	// DEBUG log in the case of success
+	// indicating the garbage collection report
+	log.WithFields(log.Fields{
+		"report": report,
+	}).Debug("successful twopset gc status")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GC is the HTTP handler used to purge the TwoPSet
+// tombstones that are causally stable across the cluster
+// along with their add entries
+func GC(w http.ResponseWriter, r *http.Request) {
+	// Tombstones are only tracked
+	// when serving a TwoPSet
+	if _, ok := Node.(twoPSetNode); !ok {
+		http.Error(w, "garbage collection is only supported for the twopset", http.StatusNotImplemented)
+		return
+	}
+
+	// Sync first so the latest VersionVectors
+	// of the peers are known
+	if len(GetPeerList()) != 0 {
+		if _, err := Node.Sync(r.Context(), GetPeerList()); err != nil {
+			log.WithFields(log.Fields{"error": err}).Error("failed to sync twopset")
+		}
+	}
+
+	// Tombstones are only stable once observed by every
+	// member, including the ones suspected or dead
+	report, err := collectTwoPSet(Members.Peers())
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("failed to store collected twopset")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// INFO log the garbage collection
+	// report in the case of success
+	log.WithFields(log.Fields{
+		"report": report,
+	}).Info("successful twopset gc")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+const (
+	// maxIBLTKeysSize is the maximum size of
+	// the body of an IBLT elements request
+	maxIBLTKeysSize = 1 << 20
+)
+
+var (
+	// errIBLTUnsupported is returned when
+	// a peer does not serve IBLTs
+	errIBLTUnsupported = errors.New("iblt not supported by peer")
+)
+
+// IBLTResponse is the JSON struct
+// encapsulating the IBLT Response
+type IBLTResponse struct {
+	// IBLT is the IBLT of the TwoPSet
+	IBLT *twopset.IBLT `json:"iblt"`
+	// GC is the garbage collection VersionVector
+	// of the node without the Dots & values purged
+	GC *twopset.Metadata `json:"gc,omitempty"`
+}
+
+// IBLTKeys is the JSON struct
+// encapsulating the IBLT Elements Request
+type IBLTKeys struct {
+	Keys []uint64 `json:"keys"`
+}
+
+// IBLT is the HTTP handler used to return the IBLT of
+// the TwoPSet with the number of cells passed as ?cells=<cells>
+func IBLT(w http.ResponseWriter, r *http.Request) {
+	// IBLTs are only supported
+	// when serving a TwoPSet
+	if _, ok := Node.(twoPSetNode); !ok {
+		http.Error(w, "iblt is only supported for the twopset", http.StatusNotImplemented)
+		return
+	}
+
+	cells, err := strconv.Atoi(r.URL.Query().Get("cells"))
+	if err != nil {
+		http.Error(w, "invalid cells provided", http.StatusBadRequest)
+		return
+	}
+
+	// Build the IBLT from a copy of the TwoPSet
+	// as it is written to while building it
+	state := copyState()
+
+	iblt, err := twopset.NewTwoPSetIBLT(state.TwoPSet, cells)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metadata := *state.GC
+	metadata.Dots, metadata.Purged = nil, nil
+
+	// DEBUG log in the case of success
+	// indicating the number of cells
+	log.WithFields(log.Fields{
+		"cells": len(iblt.Cells),
+	}).Debug("successful twopset iblt")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(IBLTResponse{IBLT: iblt, GC: &metadata})
+}
+
+// IBLTElements is the HTTP handler used to return the additions
+// & tombstones of the TwoPSet with the IBLT keys in the request
+// along with their garbage collection metadata
+func IBLTElements(w http.ResponseWriter, r *http.Request) {
+	// IBLTs are only supported
+	// when serving a TwoPSet
+	if _, ok := Node.(twoPSetNode); !ok {
+		http.Error(w, "iblt is only supported for the twopset", http.StatusNotImplemented)
+		return
+	}
+
+	var request IBLTKeys
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIBLTKeysSize)).Decode(&request)
+	if err != nil {
+		http.Error(w, "invalid keys provided", http.StatusBadRequest)
+		return
+	}
+
+	// Look the keys up in a copy of the TwoPSet
+	// as it is written to while looking them up
+	state := copyState()
+	set := twopset.Elements(state.TwoPSet, request.Keys)
+
+	// Only send the Dots of the
+	// tombstones requested
+	metadata := *state.GC
+	for value := range metadata.Dots {
+		if !set.Remove.Contains(value) {
+			delete(metadata.Dots, value)
+		}
+	}
+
+	// DEBUG log in the case of success
+	// indicating the elements returned
+	log.WithFields(log.Fields{
+		"keys": len(request.Keys),
+		"set":  set,
+	}).Debug("successful twopset iblt elements")
+
+	writeState(w, r, State{TwoPSet: set, GC: &metadata})
+}
+
+// ibltPeer reconciles the local TwoPSet
+// with a peer node over HTTP
+type ibltPeer struct {
+	// ctx is the context the
+	// requests to the peer are sent with
+	ctx  context.Context
+	peer string
+	// version is the garbage collection VersionVector
+	// obtained with the first IBLT
+	version *twopset.Metadata
+	// dots are the garbage collection Dots
+	// obtained with the elements
+	dots map[string][]twopset.Dot
+	// purged are the values purged
+	// obtained with the elements
+	purged []string
+}
+
+// IBLT sends a GET /twopset/iblt to the peer
+func (peer *ibltPeer) IBLT(cells int) (*twopset.IBLT, error) {
+	url := fmt.Sprintf("http://%s/twopset/iblt?cells=%d", GetPeerAddress(peer.peer), cells)
+	response, err := SendRequest(peer.ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	// Peers running an older build or
+	// another set type have no IBLT
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return nil, errIBLTUnsupported
+	default:
+		return nil, StatusError{StatusCode: response.StatusCode}
+	}
+
+	var ibltResponse IBLTResponse
+	err = json.NewDecoder(response.Body).Decode(&ibltResponse)
+	if err != nil {
+		return nil, DecodeError{Err: err}
+	}
+	if ibltResponse.IBLT == nil {
+		return nil, DecodeError{Err: errors.New("received invalid iblt response")}
+	}
+
+	if peer.version == nil {
+		peer.version = ibltResponse.GC
+	}
+
+	return ibltResponse.IBLT, nil
+}
+
+// Elements sends a POST /twopset/iblt/elements to the peer
+func (peer *ibltPeer) Elements(keys []uint64) (twopset.TwoPSet, error) {
+	body, err := json.Marshal(IBLTKeys{Keys: keys})
+	if err != nil {
+		return twopset.TwoPSet{}, err
+	}
+
+	url := fmt.Sprintf("http://%s/twopset/iblt/elements", GetPeerAddress(peer.peer))
+	state, err := sendStatePostRequest(peer.ctx, url, body)
+	if err != nil {
+		return twopset.TwoPSet{}, err
+	}
+
+	if state.GC != nil {
+		peer.dots = state.GC.Dots
+		peer.purged = state.GC.Purged
+	}
+
+	return state.TwoPSet, nil
+}
+
+// SendIBLTSyncRequest reconciles the local TwoPSet with the peer
+// using IBLTs and returns the peer's State for the additions &
+// tombstones the local TwoPSet lacks
+// The peer's full State is returned if it does not support IBLTs
+// or the difference is too large to be reconciled
+func SendIBLTSyncRequest(ctx context.Context, peer string, local twopset.TwoPSet) (State, error) {
+	// Return an empty State followed by an error if the peer is nil
+	if peer == "" {
+		return State{}, errors.New("empty peer provided")
+	}
+
+	reconciler := &ibltPeer{ctx: ctx, peer: peer}
+	delta, err := twopset.Reconcile(local, reconciler)
+	if err == errIBLTUnsupported || err == twopset.ErrIBLTTooLarge {
+		return SendStateRequest(ctx, peer)
+	}
+	if err != nil {
+		return State{}, err
+	}
+
+	// The VersionVector obtained with the first IBLT is used as
+	// the peer had observed at most what was reconciled against
+	state := State{TwoPSet: delta, GC: reconciler.version}
+	if reconciler.version != nil {
+		state.GC = &twopset.Metadata{
+			Node:    reconciler.version.Node,
+			Dots:    reconciler.dots,
+			Version: reconciler.version.Version,
+			Purged:  reconciler.purged,
+		}
+	}
+
+	return state, nil
+}
+
+// sendStatePostRequest is used to send a POST request
+// with a JSON body for a State to the URL of a peer node
+func sendStatePostRequest(ctx context.Context, url string, body []byte) (State, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return State{}, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	return doStateRequest(request)
+}
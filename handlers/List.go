@@ -10,14 +10,26 @@ import (
 // List is the HTTP handler used to return
 // all the values present in the TwoPSet node in the server
 func List(w http.ResponseWriter, r *http.Request) {
-	// Sync the TwoPSets if multiple nodes
-	// are present in a cluster
-	if len(GetPeerList()) != 0 {
-		TwoPSet, _ = Sync(TwoPSet)
+	// Sync the sets with a majority or all of the peers for
+	// ?consistency=quorum or ?consistency=all, failing if they
+	// can not be reached, or with every node in the cluster
+	// when requested. The local set is served otherwise
+	// as it is kept in sync in the background by Gossip
+	level, err := parseConsistency(r)
+	if err == nil {
+		err = readConsistency(r.Context(), level)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Warn("failed to meet twopset list consistency")
+		writeConsistencyError(w, err)
+		return
+	}
+	if level == ConsistencyLocal && syncRequested(r) && len(GetPeerList()) != 0 {
+		Node.Sync(r.Context(), GetPeerList())
 	}
 
-	// Get the values from the TwoPSet
-	set := TwoPSet.List()
+	// Get the values from the set
+	set := Node.List()
 
 	// DEBUG log in the case of success
 	// indicating the new TwoPSet
@@ -23,14 +23,26 @@ func Lookup(w http.ResponseWriter, r *http.Request) {
 	// Obtain the value from URL params
 	value := mux.Vars(r)["value"]
 
-	// Sync the TwoPSets if multiple nodes
-	// are present in a cluster
-	if len(GetPeerList()) != 0 {
-		TwoPSet, _ = Sync(TwoPSet)
+	// Sync the sets with a majority or all of the peers for
+	// ?consistency=quorum or ?consistency=all, failing if they
+	// can not be reached, or with every node in the cluster
+	// when requested. The local set is served otherwise
+	// as it is kept in sync in the background by Gossip
+	level, err := parseConsistency(r)
+	if err == nil {
+		err = readConsistency(r.Context(), level)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Warn("failed to meet twopset lookup consistency")
+		writeConsistencyError(w, err)
+		return
+	}
+	if level == ConsistencyLocal && syncRequested(r) && len(GetPeerList()) != 0 {
+		Node.Sync(r.Context(), GetPeerList())
 	}
 
-	// Lookup given value in the TwoPSet
-	present, err = TwoPSet.Lookup(value)
+	// Lookup given value in the set
+	present, err = Node.Lookup(value)
 	if err != nil {
 		log.WithFields(log.Fields{"error": err}).Error("failed to lookup twopset value")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -38,9 +50,8 @@ func Lookup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// DEBUG log in the case of success indicating
-	// the new TwoPSet, the lookup value and if its present
+	// the lookup value and if its present
 	log.WithFields(log.Fields{
-		"set":     TwoPSet,
 		"value":   value,
 		"present": present,
 	}).Debug("successful twopset lookup")
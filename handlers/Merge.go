@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+const (
+	// DefaultMergeMaxBytes is the default maximum
+	// size of the State in a merge request
+	DefaultMergeMaxBytes = 16 << 20
+	// MaxValueLength is the maximum length
+	// of a value in a merged TwoPSet
+	MaxValueLength = 4096
+)
+
+var (
+	// mergeMaxBytes is the maximum size of
+	// the State in a merge request
+	mergeMaxBytes int64 = DefaultMergeMaxBytes
+
+	// errMergeTooLarge is returned when the State in a
+	// merge request is larger than mergeMaxBytes
+	errMergeTooLarge = errors.New("merged state too large")
+	// errMergeContentType is returned when the State
+	// in a merge request is neither JSON nor binary
+	errMergeContentType = errors.New("unsupported merged state content type")
+)
+
+// MergeResponse is the JSON struct
+// encapsulating the Merge Response
+type MergeResponse struct {
+	// Digest is the hex encoded Merkle root hash
+	// of the TwoPSet resulting from the merge
+	Digest string `json:"digest"`
+	// Added is the number of additions
+	// merged missing in the TwoPSet
+	Added int `json:"added"`
+	// Removed is the number of tombstones
+	// merged missing in the TwoPSet
+	Removed int `json:"removed"`
+}
+
+// UseMergeMaxBytes sets the maximum size of the State
+// in a merge request, such as from GetMergeMaxBytes()
+func UseMergeMaxBytes(maxBytes int64) {
+	mergeMaxBytes = maxBytes
+}
+
+// Merge is the HTTP handler used to merge a State pushed
+// by a peer node or tooling into the TwoPSet, returning
+// the digest of the resulting TwoPSet. Its garbage collection
+// metadata is merged along with it, if any, so the tombstones
+// purged locally are not merged back
+// The State is decoded from JSON or the binary
+// encoding depending on its Content-Type
+func Merge(w http.ResponseWriter, r *http.Request) {
+	// Merges are only supported
+	// when serving a TwoPSet
+	if _, ok := Node.(twoPSetNode); !ok {
+		http.Error(w, "merge is only supported for the twopset", http.StatusNotImplemented)
+		return
+	}
+
+	state, err := readState(r, mergeMaxBytes)
+	switch err {
+	case nil:
+	case errMergeTooLarge:
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case errMergeContentType:
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	default:
+		log.WithFields(log.Fields{"error": err}).Error("failed to decode merged twopset")
+		http.Error(w, "invalid state provided", http.StatusBadRequest)
+		return
+	}
+
+	err = validateTwoPSet(state.TwoPSet)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Joining the missing additions & tombstones into
+	// the TwoPSet is equivalent to Collector.Merge()
+	delta, root, err := mergeState(state)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("failed to merge twopset")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := MergeResponse{
+		Digest:  hex.EncodeToString(root[:]),
+		Added:   delta.Add.Len(),
+		Removed: delta.Remove.Len(),
+	}
+
+	// DEBUG log in the case of success
+	// indicating the delta merged
+	log.WithFields(log.Fields{
+		"delta":  delta,
+		"digest": response.Digest,
+	}).Debug("successful twopset merge")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// readState decodes the State in the body of the request using
+// the binary encoding of a TwoPSet along with its Metadata if its
+// Content-Type is twopset.StateMediaType, the binary encoding of
+// the TwoPSet alone if it is twopset.MediaType & JSON if it
+// is JSON or not set
+// Bodies larger than the given size are rejected
+func readState(r *http.Request, maxBytes int64) (State, error) {
+	var state State
+
+	contentType := r.Header.Get("Content-Type")
+	isState := hasContentType(contentType, twopset.StateMediaType)
+	isTwoPSet := hasContentType(contentType, twopset.MediaType)
+	if !isState && !isTwoPSet && contentType != "" && !hasContentType(contentType, "application/json") {
+		return state, errMergeContentType
+	}
+
+	// Read one byte past the limit
+	// to find bodies over it
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+	if err != nil {
+		return state, err
+	}
+	if int64(len(body)) > maxBytes {
+		return state, errMergeTooLarge
+	}
+
+	if isState {
+		err = state.UnmarshalBinary(body)
+		return state, err
+	}
+	if isTwoPSet {
+		err = state.TwoPSet.UnmarshalBinary(body)
+		return state, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&state); err != nil {
+		return state, err
+	}
+	if decoder.More() {
+		return state, errors.New("trailing data after state")
+	}
+
+	return state, nil
+}
+
+// validateTwoPSet checks the values in
+// a TwoPSet received from a client
+func validateTwoPSet(set twopset.TwoPSet) error {
+	for _, gset := range []twopset.GSet{set.Add, set.Remove} {
+		for value := range gset {
+			if len(value) > MaxValueLength {
+				return fmt.Errorf("value longer than %d bytes provided", MaxValueLength)
+			}
+			if !utf8.ValidString(value) {
+				return errors.New("invalid utf-8 value provided")
+			}
+		}
+	}
+	return nil
+}
+
+// SendMergeRequest is used to send a POST /twopset/merge
+// pushing a State to a peer node in the cluster
+// using the binary encoding of twopset.StateMediaType
+func SendMergeRequest(ctx context.Context, peer string, state State) error {
+	// Return an error if the peer is nil
+	if peer == "" {
+		return errors.New("empty peer provided")
+	}
+
+	body, err := state.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("http://%s/twopset/merge", GetPeerAddress(peer))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", twopset.StateMediaType)
+
+	response, err := DoRequest(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return StatusError{StatusCode: response.StatusCode}
+	}
+
+	return nil
+}
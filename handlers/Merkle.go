@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+var (
+	// errInvalidIndex is returned when a
+	// node index requested is out of range
+	errInvalidIndex = errors.New("invalid index provided")
+	// errMerkleUnsupported is returned when a
+	// peer does not serve a Merkle tree
+	errMerkleUnsupported = errors.New("merkle tree not supported by peer")
+)
+
+// MerkleLevel is the JSON struct
+// encapsulating the Merkle Response
+type MerkleLevel struct {
+	// Depth is the depth of the tree
+	Depth int `json:"depth"`
+	// Level is the level of the nodes
+	Level int `json:"level"`
+	// Index are the indexes of the nodes
+	Index []int `json:"index"`
+	// Hashes are the hex encoded
+	// hashes of the nodes
+	Hashes []string `json:"hashes"`
+	// GC is the garbage collection VersionVector
+	// of the node without the Dots & values purged
+	GC *twopset.Metadata `json:"gc,omitempty"`
+}
+
+// Merkle is the HTTP handler used to return the hashes of the
+// nodes of the TwoPSet's Merkle tree at ?level=<level>, the root
+// by default, optionally only those at ?index=<index>,<index>
+func Merkle(w http.ResponseWriter, r *http.Request) {
+	// Merkle trees are only
+	// maintained for the TwoPSet
+	if _, ok := Node.(twoPSetNode); !ok {
+		http.Error(w, "merkle tree is only supported for the twopset", http.StatusNotImplemented)
+		return
+	}
+
+	level := 0
+	if r.URL.Query().Get("level") != "" {
+		var err error
+		level, err = strconv.Atoi(r.URL.Query().Get("level"))
+		if err != nil || level < 0 || level > twopset.MerkleDepth {
+			http.Error(w, "invalid level provided", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Copy the hashes of the level as the
+	// Tree is written to along with the TwoPSet
+	storeMutex.Lock()
+	depth := Tree.Depth()
+	hashes := Tree.Level(level)
+	storeMutex.Unlock()
+
+	index, err := parseIndex(r.URL.Query().Get("index"), len(hashes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metadata := Collector.Metadata()
+	metadata.Dots, metadata.Purged = nil, nil
+
+	response := MerkleLevel{
+		Depth:  depth,
+		Level:  level,
+		Index:  index,
+		Hashes: make([]string, 0, len(index)),
+		GC:     &metadata,
+	}
+	for _, node := range index {
+		response.Hashes = append(response.Hashes, hex.EncodeToString(hashes[node][:]))
+	}
+
+	// DEBUG log in the case of success
+	// indicating the level and its hashes
+	log.WithFields(log.Fields{
+		"level":  level,
+		"hashes": len(response.Hashes),
+	}).Debug("successful twopset merkle")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Buckets is the HTTP handler used to return the additions &
+// tombstones of the TwoPSet in the Merkle tree leaf buckets at
+// ?index=<index>,<index> along with their garbage collection metadata
+func Buckets(w http.ResponseWriter, r *http.Request) {
+	// Merkle trees are only
+	// maintained for the TwoPSet
+	if _, ok := Node.(twoPSetNode); !ok {
+		http.Error(w, "merkle tree is only supported for the twopset", http.StatusNotImplemented)
+		return
+	}
+
+	if r.URL.Query().Get("index") == "" {
+		http.Error(w, "empty index provided", http.StatusBadRequest)
+		return
+	}
+
+	index, err := parseIndex(r.URL.Query().Get("index"), 1<<uint(twopset.MerkleDepth))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Filter the buckets into a new TwoPSet as the
+	// TwoPSet is written to once the lock is released
+	storeMutex.Lock()
+	set := Tree.Filter(TwoPSet, index)
+	metadata := Collector.Metadata()
+	storeMutex.Unlock()
+
+	// Only send the Dots of the
+	// tombstones in the buckets
+	for value := range metadata.Dots {
+		if !set.Remove.Contains(value) {
+			delete(metadata.Dots, value)
+		}
+	}
+
+	// DEBUG log in the case of success
+	// indicating the buckets
+	log.WithFields(log.Fields{
+		"index": index,
+		"set":   set,
+	}).Debug("successful twopset buckets")
+
+	writeState(w, r, State{TwoPSet: set, GC: &metadata})
+}
+
+// parseIndex parses a comma separated list of node indexes
+// below the limit, returning all of them if it is empty
+func parseIndex(query string, limit int) ([]int, error) {
+	index := []int{}
+
+	if query == "" {
+		for node := 0; node < limit; node++ {
+			index = append(index, node)
+		}
+		return index, nil
+	}
+
+	for _, part := range strings.Split(query, ",") {
+		node, err := strconv.Atoi(part)
+		if err != nil || node < 0 || node >= limit {
+			return nil, errInvalidIndex
+		}
+		index = append(index, node)
+	}
+
+	return index, nil
+}
+
+// SendMerkleSyncRequest walks the Merkle tree of the local TwoPSet with
+// the peer's tree from the root down and returns the peer's State for the
+// leaf buckets that differ, which is empty if the trees are equal
+// The peer's full State is returned if it does not support Merkle trees
+func SendMerkleSyncRequest(ctx context.Context, peer string, tree *twopset.MerkleTree) (State, error) {
+	root, err := SendMerkleRequest(ctx, peer, 0, []int{0})
+	if err == errMerkleUnsupported || (err == nil && root.Depth != tree.Depth()) {
+		return SendStateRequest(ctx, peer)
+	}
+	if err != nil {
+		return State{}, err
+	}
+
+	differing, err := diffMerkleLevel(tree, root)
+	if err != nil {
+		return State{}, err
+	}
+
+	// Walk down the levels only
+	// following the nodes that differ
+	for level := 1; level <= tree.Depth() && len(differing) != 0; level++ {
+		children := make([]int, 0, 2*len(differing))
+		for _, index := range differing {
+			children = append(children, 2*index, 2*index+1)
+		}
+
+		peerLevel, err := SendMerkleRequest(ctx, peer, level, children)
+		if err != nil {
+			return State{}, err
+		}
+
+		differing, err = diffMerkleLevel(tree, peerLevel)
+		if err != nil {
+			return State{}, err
+		}
+	}
+
+	// The VersionVector obtained with the root is used as the
+	// peer had observed at most what the walk compared against
+	state := State{TwoPSet: twopset.Initialize(), GC: root.GC}
+	if len(differing) == 0 {
+		return state, nil
+	}
+
+	buckets, err := SendBucketsRequest(ctx, peer, differing)
+	if err != nil {
+		return State{}, err
+	}
+
+	state.TwoPSet = buckets.TwoPSet
+	if buckets.GC != nil && root.GC != nil {
+		state.GC = &twopset.Metadata{
+			Node:    root.GC.Node,
+			Dots:    buckets.GC.Dots,
+			Version: root.GC.Version,
+			Purged:  buckets.GC.Purged,
+		}
+	}
+
+	return state, nil
+}
+
+// diffMerkleLevel returns the indexes of the nodes
+// in the peer's level that differ from the local tree
+func diffMerkleLevel(tree *twopset.MerkleTree, peerLevel MerkleLevel) ([]int, error) {
+	if len(peerLevel.Index) != len(peerLevel.Hashes) {
+		return nil, DecodeError{Err: errors.New("received invalid merkle response")}
+	}
+
+	local := tree.Level(peerLevel.Level)
+	differing := []int{}
+
+	for position, index := range peerLevel.Index {
+		if index < 0 || index >= len(local) {
+			return nil, DecodeError{Err: errors.New("received invalid merkle response")}
+		}
+		if hex.EncodeToString(local[index][:]) != peerLevel.Hashes[position] {
+			differing = append(differing, index)
+		}
+	}
+
+	return differing, nil
+}
+
+// SendMerkleRequest is used to send a GET /twopset/merkle to peer nodes
+// in the cluster to obtain the hashes of the nodes at a level
+func SendMerkleRequest(ctx context.Context, peer string, level int, index []int) (MerkleLevel, error) {
+	var merkleLevel MerkleLevel
+
+	// Return an empty MerkleLevel followed by an error if the peer is nil
+	if peer == "" {
+		return merkleLevel, errors.New("empty peer provided")
+	}
+
+	// Resolve the Peer ID and network to generate the request URL
+	url := fmt.Sprintf("http://%s/twopset/merkle?level=%d&index=%s", GetPeerAddress(peer), level, formatIndex(index))
+	response, err := SendRequest(ctx, url)
+	if err != nil {
+		return merkleLevel, err
+	}
+	defer response.Body.Close()
+
+	// Peers running an older build or
+	// another set type have no Merkle tree
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return merkleLevel, errMerkleUnsupported
+	default:
+		return merkleLevel, StatusError{StatusCode: response.StatusCode}
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&merkleLevel)
+	if err != nil {
+		return MerkleLevel{}, DecodeError{Err: err}
+	}
+
+	return merkleLevel, nil
+}
+
+// SendBucketsRequest is used to send a GET /twopset/buckets to peer
+// nodes in the cluster to obtain the State in the given leaf buckets
+func SendBucketsRequest(ctx context.Context, peer string, index []int) (State, error) {
+	// Return an empty State followed by an error if the peer is nil
+	if peer == "" {
+		return State{}, errors.New("empty peer provided")
+	}
+
+	// Resolve the Peer ID and network to generate the request URL
+	url := fmt.Sprintf("http://%s/twopset/buckets?index=%s", GetPeerAddress(peer), formatIndex(index))
+	return sendStateRequest(ctx, url)
+}
+
+// formatIndex formats node indexes
+// as a comma separated list
+func formatIndex(index []int) string {
+	parts := make([]string, 0, len(index))
+	for _, node := range index {
+		parts = append(parts, strconv.Itoa(node))
+	}
+	return strings.Join(parts, ",")
+}
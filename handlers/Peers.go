@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Peers is the HTTP handler used to return the health
+// of the peers in the cluster & the state of their circuits
+// The members suspected or dead are included, as their
+// circuits are most likely the ones open
+func Peers(w http.ResponseWriter, r *http.Request) {
+	status := Health.Status(Members.Peers())
+
+	// DEBUG log in the case of success
+	// indicating the health of the peers
+	log.WithFields(log.Fields{
+		"peers": status,
+	}).Debug("successful cluster peers")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
+)
+
+// TestPeers checks the basic functionality of the Peers handler
+// the peers suspected by SWIM should be reported along with
+// the state of their circuits
+func TestPeers(t *testing.T) {
+	peer := "127.0.0.1:1"
+	assert.Nil(t, Members.Join(peer))
+	defer Members.Leave(peer)
+
+	// The peer is not in the network
+	// so probing it suspects it
+	network := cluster.NewMemoryNetwork()
+	Detector = cluster.NewSWIM(Members.Self(), network, cluster.DefaultSWIMConfig(), nil)
+	network.Add(Detector)
+	defer func() { Detector = nil }()
+
+	Detector.SetMembers(Members.Members())
+	assert.Equal(t, peer, Detector.Probe(context.Background()))
+	assert.NotContains(t, GetPeerList(), peer)
+
+	recorder := httptest.NewRecorder()
+	Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/cluster/peers", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var status []cluster.PeerHealth
+	json.NewDecoder(recorder.Body).Decode(&status)
+
+	peers := []string{}
+	for _, peerHealth := range status {
+		peers = append(peers, peerHealth.Peer)
+	}
+	assert.Contains(t, peers, peer)
+}
@@ -5,31 +5,69 @@ import (
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
 )
 
 // Remove is the HTTP handler used to remove
 // values to the TwoPSet node in the server
+// With ?consistency=quorum or ?consistency=all it only
+// returns once a majority or all of the peers have
+// acknowledged the value removed
 func Remove(w http.ResponseWriter, r *http.Request) {
-	var err error
-
 	// Obtain the value from URL params
 	value := mux.Vars(r)["value"]
 
-	// Remove the given value to our stored TwoPSet
-	TwoPSet, err = TwoPSet.Removal(value)
+	// Fail before removing the value if the
+	// consistency level can not be met
+	level, err := parseConsistency(r)
+	if err == nil {
+		err = checkWriteConsistency(level)
+	}
 	if err != nil {
+		log.WithFields(log.Fields{"error": err, "value": value}).Debug("rejected twopset removal")
+		writeConsistencyError(w, err)
+		return
+	}
+
+	// Remove the given value to our stored set
+	// A strict TwoPSet returns HTTP 404 Not Found if the value was
+	// never added or HTTP 409 Conflict if it is already removed
+	err = Node.Removal(value)
+	switch err {
+	case nil:
+	case twopset.ErrNotAdded:
+		log.WithFields(log.Fields{"error": err, "value": value}).Debug("rejected twopset removal")
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case twopset.ErrAlreadyRemoved:
+		log.WithFields(log.Fields{"error": err, "value": value}).Debug("rejected twopset removal")
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
 		log.WithFields(log.Fields{"error": err}).Error("failed to remove value")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	// DEBUG log in the case of success indicating
-	// the new TwoPSet and the value removed
+	// DEBUG log in the case of success
+	// indicating the value removed
 	log.WithFields(log.Fields{
-		"set":   TwoPSet,
 		"value": value,
 	}).Debug("successful twopset removal")
 
+	// Wait for the peers required by the
+	// consistency level to acknowledge it
+	delta, err := twopset.Initialize().Removal(value)
+	if err == nil {
+		err = writeConsistency(r.Context(), level, delta)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"error": err, "value": value}).Warn("failed to meet twopset removal consistency")
+		writeConsistencyError(w, err)
+		return
+	}
+
 	// Return HTTP 200 OK in the case of success
 	w.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SyncAll is the HTTP handler used to sync the set with
+// every node in the cluster, reporting the outcome for each
+// The sync is canceled if the request is canceled
+func SyncAll(w http.ResponseWriter, r *http.Request) {
+	report, err := Node.Sync(r.Context(), GetPeerList())
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("failed to sync set")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// DEBUG log in the case of success
+	// indicating the sync report
+	log.WithFields(log.Fields{
+		"report": report,
+	}).Debug("successful set sync")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
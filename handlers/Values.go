@@ -1,17 +1,23 @@
 package handlers
 
 import (
+	"encoding"
 	"encoding/json"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
 )
 
-// Values is the HTTP handler to return the local TwoPSet's values
+// Values is the HTTP handler to return the local set's values
 // without syncing it with other nodes in a cluster
+// The TwoPSet is returned using its binary encoding when the
+// request accepts it, along with its garbage collection Metadata
+// if twopset.StateMediaType is accepted, and JSON encoded otherwise
 func Values(w http.ResponseWriter, r *http.Request) {
-	// Get the local TwoPSet values
-	set := TwoPSet
+	// Get the local set values
+	set := Node.Values()
 
 	// DEBUG log in the case of successful
 	// list indicating the set
@@ -19,6 +25,44 @@ func Values(w http.ResponseWriter, r *http.Request) {
 		"set": set,
 	}).Debug("successful twopset values")
 
+	writeState(w, r, set)
+}
+
+// writeState writes the set's state using a binary encoding
+// if both the set & the request support it, and JSON otherwise
+// A State is written along with its Metadata if the request
+// accepts twopset.StateMediaType, and as the TwoPSet alone
+// if it only accepts twopset.MediaType
+func writeState(w http.ResponseWriter, r *http.Request, set interface{}) {
+	accept := r.Header.Get("Accept")
+
+	// Binary encode the response value if
+	// both the set & the peer support it
+	state, ok := set.(State)
+	switch {
+	case ok && accepts(accept, twopset.StateMediaType):
+		writeBinary(w, state, twopset.StateMediaType)
+		return
+	case ok && accepts(accept, twopset.MediaType):
+		writeBinary(w, state.TwoPSet, twopset.MediaType)
+		return
+	}
+
 	// json encode response value
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(set)
 }
+
+// writeBinary writes the value's binary
+// encoding under the given media type
+func writeBinary(w http.ResponseWriter, value encoding.BinaryMarshaler, mediaType string) {
+	encoded, err := value.MarshalBinary()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("failed to binary marshal twopset values")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Write(encoded)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+var (
+	// Client is the HTTP client shared by every request
+	// sent to the peers, pooling their connections
+	// Requests are bounded by the deadline of their
+	// context, the client timeout is a backstop
+	Client = &http.Client{
+		Timeout: 5 * time.Minute,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   5 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 16,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+)
+
+// StatusError is returned when a peer replies
+// with an unexpected HTTP response status
+type StatusError struct {
+	StatusCode int
+}
+
+func (err StatusError) Error() string {
+	return "received invalid http response status:" + fmt.Sprint(err.StatusCode)
+}
+
+// DecodeError is returned when a
+// peer's response can not be decoded
+type DecodeError struct {
+	Err error
+}
+
+func (err DecodeError) Error() string {
+	return "failed to decode peer response: " + err.Err.Error()
+}
+
+// Unwrap returns the decoding error
+func (err DecodeError) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
+	"github.com/el10savio/twoPSet-crdt/lwwset"
+)
+
+// membershipPeer serves a peer recording the
+// members pushed to it by the local node
+type membershipPeer struct {
+	mutex  sync.Mutex
+	pushed []lwwset.LWWSet
+}
+
+func (peer *membershipPeer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/cluster/membership" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var state lwwset.LWWSet
+	json.NewDecoder(r.Body).Decode(&state)
+
+	peer.mutex.Lock()
+	peer.pushed = append(peer.pushed, state)
+	peer.mutex.Unlock()
+}
+
+// members returns the members in the
+// last membership pushed to the peer
+func (peer *membershipPeer) members() []string {
+	peer.mutex.Lock()
+	defer peer.mutex.Unlock()
+
+	if len(peer.pushed) == 0 {
+		return nil
+	}
+	return peer.pushed[len(peer.pushed)-1].List()
+}
+
+// sendCluster sends a request to the cluster endpoint
+// URL and returns the response recorded along with
+// the members of the cluster it returned
+func sendCluster(method string, url string, body []byte) (*httptest.ResponseRecorder, ClusterMembers) {
+	request := httptest.NewRequest(method, url, bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	Router().ServeHTTP(recorder, request)
+
+	var members ClusterMembers
+	json.NewDecoder(bytes.NewReader(recorder.Body.Bytes())).Decode(&members)
+	return recorder, members
+}
+
+// TestJoin checks the basic functionality of the Join handler
+// the node joining should be added to the members and the
+// new members should be pushed to it
+func TestJoin(t *testing.T) {
+	defer useMembers()()
+
+	peer := &membershipPeer{}
+	node, closePeer := newPeer(peer)
+	defer closePeer()
+
+	recorder, actualValue := sendCluster(http.MethodPost, "/cluster/join?node="+node, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	expectedValue := []string{Members.Self(), node}
+	assert.ElementsMatch(t, expectedValue, actualValue.Members)
+	assert.ElementsMatch(t, expectedValue, peer.members())
+
+	recorder, _ = sendCluster(http.MethodPost, "/cluster/join?node=xx/yy", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// TestLeave checks the basic functionality of the Leave handler
+// the node leaving should be removed from the members and the new
+// members should be pushed to it, while nodes that are not members
+// should return HTTP 404 Not Found
+func TestLeave(t *testing.T) {
+	peer := &membershipPeer{}
+	node, closePeer := newPeer(peer)
+	defer closePeer()
+
+	defer useMembers(node)()
+
+	recorder, actualValue := sendCluster(http.MethodPost, "/cluster/leave?node="+node, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	expectedValue := []string{Members.Self()}
+	assert.Equal(t, expectedValue, actualValue.Members)
+	assert.Equal(t, expectedValue, peer.members())
+
+	recorder, _ = sendCluster(http.MethodPost, "/cluster/leave?node="+node, nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+// TestListMembers checks the basic functionality of the ListMembers handler
+// the local node should be returned along with the members of the cluster
+func TestListMembers(t *testing.T) {
+	defer useMembers("peer-1", "peer-2")()
+
+	recorder, actualValue := sendCluster(http.MethodGet, "/cluster/members", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	assert.Equal(t, Members.Self(), actualValue.Self)
+	assert.ElementsMatch(t, []string{Members.Self(), "peer-1", "peer-2"}, actualValue.Members)
+}
+
+// TestMembership checks the basic functionality of the Membership
+// & MergeMembership handlers, the members pushed by a peer should
+// be merged with the local ones and invalid members rejected
+func TestMembership(t *testing.T) {
+	defer useMembers("peer-1")()
+
+	request := httptest.NewRequest(http.MethodGet, "/cluster/membership", nil)
+	recorder := httptest.NewRecorder()
+	Router().ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var state lwwset.LWWSet
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&state))
+	assert.ElementsMatch(t, []string{Members.Self(), "peer-1"}, state.List())
+
+	peer := cluster.NewMembership("peer-2", []string{Members.Self()})
+	body, _ := json.Marshal(peer.State())
+
+	recorder, actualValue := sendCluster(http.MethodPost, "/cluster/membership", body)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.ElementsMatch(t, []string{Members.Self(), "peer-1", "peer-2"}, actualValue.Members)
+
+	recorder, _ = sendCluster(http.MethodPost, "/cluster/membership", []byte(`{"add":{"xx/yy":{}},"remove":{}}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.ElementsMatch(t, []string{Members.Self(), "peer-1", "peer-2"}, Members.Members())
+}
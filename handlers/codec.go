@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// MarshalBinary encodes the State using the binary encoding
+// of a TwoPSet along with its Metadata, served under
+// twopset.StateMediaType
+func (state State) MarshalBinary() ([]byte, error) {
+	return twopset.MarshalState(state.TwoPSet, state.GC)
+}
+
+// UnmarshalBinary decodes a State from the binary encoding
+// of a TwoPSet along with its Metadata
+func (state *State) UnmarshalBinary(data []byte) error {
+	set, metadata, err := twopset.UnmarshalState(data)
+	if err != nil {
+		return err
+	}
+
+	*state = State{TwoPSet: set, GC: metadata}
+	return nil
+}
+
+// accepts returns true if the Accept
+// header includes the given media type
+func accepts(accept string, mediaType string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		parsed, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || parsed != mediaType {
+			continue
+		}
+		if params["q"] == "0" {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// hasContentType returns true if the Content-Type
+// header is the given media type
+func hasContentType(contentType string, mediaType string) bool {
+	parsed, _, err := mime.ParseMediaType(contentType)
+	return err == nil && parsed == mediaType
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// Consistency is the consistency
+// level of a read or a write
+type Consistency string
+
+const (
+	// ConsistencyLocal reads & writes
+	// only the local set
+	ConsistencyLocal Consistency = "local"
+	// ConsistencyQuorum reads from or writes to a
+	// majority of the members, including the local node
+	ConsistencyQuorum Consistency = "quorum"
+	// ConsistencyAll reads from or writes
+	// to every member of the cluster
+	ConsistencyAll Consistency = "all"
+)
+
+var (
+	errConsistency = errors.New("invalid consistency level provided")
+	// errConsistencySet is returned for writes above ConsistencyLocal
+	// as only TwoPSet deltas can be pushed to the peers
+	errConsistencySet = errors.New("write consistency levels are only supported for the twopset")
+)
+
+// ConsistencyError is the JSON struct encapsulating
+// a consistency level that could not be met
+type ConsistencyError struct {
+	Level Consistency `json:"level"`
+	// Required is the number of peers required
+	// to reply for the level to be met
+	Required int `json:"required"`
+	// Reachable is the number of peers alive
+	// when the request was received
+	Reachable int `json:"reachable"`
+	// Replied is the number of
+	// peers that replied in time
+	Replied int `json:"replied"`
+	// Applied is true if a write was applied locally
+	// & will still be replicated in the background
+	Applied bool `json:"applied"`
+	// Peers is the outcome for each peer contacted
+	Peers []PeerResult `json:"peers,omitempty"`
+}
+
+// Error returns the reason the
+// consistency level was not met
+func (err ConsistencyError) Error() string {
+	if err.Reachable < err.Required {
+		return fmt.Sprintf("consistency level %s not met: %d of %d required peers reachable", err.Level, err.Reachable, err.Required)
+	}
+	return fmt.Sprintf("consistency level %s not met: %d of %d required peers replied", err.Level, err.Replied, err.Required)
+}
+
+// parseConsistency returns the consistency level from the
+// ?consistency= URL query param, ConsistencyLocal by default
+func parseConsistency(r *http.Request) (Consistency, error) {
+	switch level := Consistency(r.URL.Query().Get("consistency")); level {
+	case "":
+		return ConsistencyLocal, nil
+	case ConsistencyLocal, ConsistencyQuorum, ConsistencyAll:
+		return level, nil
+	default:
+		return "", errConsistency
+	}
+}
+
+// requiredPeers returns the number of peers that must
+// reply for the level to be met in a cluster of the
+// given number of members, including the local node
+func requiredPeers(level Consistency, members int) int {
+	switch level {
+	case ConsistencyQuorum:
+		return members / 2
+	case ConsistencyAll:
+		return members - 1
+	default:
+		return 0
+	}
+}
+
+// consistencyPeers returns the peers to contact for the level
+// along with the number of them required to reply. An error
+// is returned without contacting them if too few are alive
+func consistencyPeers(level Consistency) ([]string, int, error) {
+	required := requiredPeers(level, len(Members.Members()))
+	peers := GetPeerList()
+
+	if len(peers) < required {
+		return nil, required, ConsistencyError{Level: level, Required: required, Reachable: len(peers)}
+	}
+	return peers, required, nil
+}
+
+// checkWriteConsistency returns an error without writing
+// if the level can not be met, as too few peers are alive
+// or the set served does not support it
+func checkWriteConsistency(level Consistency) error {
+	if level == ConsistencyLocal {
+		return nil
+	}
+	if _, ok := Node.(twoPSetNode); !ok {
+		return errConsistencySet
+	}
+	_, _, err := consistencyPeers(level)
+	return err
+}
+
+// quorumKey is the context key of the
+// number of peers set by withQuorum()
+type quorumKey struct{}
+
+// withQuorum returns a context under which syncPeers()
+// returns once the given number of peers were synced
+// with, canceling the syncs still running
+func withQuorum(ctx context.Context, required int) context.Context {
+	return context.WithValue(ctx, quorumKey{}, required)
+}
+
+// readConsistency syncs the set with the alive peers
+// and returns an error if fewer than the level
+// requires were synced with. It returns as soon
+// as enough were, canceling the other syncs
+func readConsistency(ctx context.Context, level Consistency) error {
+	if level == ConsistencyLocal {
+		return nil
+	}
+
+	peers, required, err := consistencyPeers(level)
+	if err != nil || len(peers) == 0 {
+		return err
+	}
+
+	report, err := Node.Sync(withQuorum(ctx, required), peers)
+	if err != nil {
+		return err
+	}
+
+	if report.OK() < required {
+		return ConsistencyError{Level: level, Required: required, Reachable: len(peers), Replied: report.OK(), Peers: report.Peers}
+	}
+	return nil
+}
+
+// writeConsistency pushes the delta of a write applied locally
+// to the alive peers and returns an error if fewer than
+// the level requires acknowledged it
+func writeConsistency(ctx context.Context, level Consistency, delta twopset.TwoPSet) error {
+	if level == ConsistencyLocal {
+		return nil
+	}
+
+	peers, required, err := consistencyPeers(level)
+	if err != nil {
+		if consistencyErr, ok := err.(ConsistencyError); ok {
+			consistencyErr.Applied = true
+			return consistencyErr
+		}
+		return err
+	}
+	if len(peers) == 0 {
+		return nil
+	}
+
+	// The delta is pushed along with
+	// the Dots of its tombstones
+	state := deltaState(delta)
+
+	_, report := syncPeers(ctx, peers, func(ctx context.Context, peer string) (interface{}, error) {
+		return nil, SendMergeRequest(ctx, peer, state)
+	})
+
+	if report.OK() < required {
+		return ConsistencyError{Level: level, Required: required, Reachable: len(peers), Replied: report.OK(), Applied: true, Peers: report.Peers}
+	}
+	return nil
+}
+
+// writeConsistencyError writes the HTTP response for
+// a consistency level that could not be met, or for
+// an invalid or unsupported consistency level
+func writeConsistencyError(w http.ResponseWriter, err error) {
+	switch consistencyErr := err.(type) {
+	case ConsistencyError:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(struct {
+			Error string `json:"error"`
+			ConsistencyError
+		}{consistencyErr.Error(), consistencyErr})
+	default:
+		switch err {
+		case errConsistency:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errConsistencySet:
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// useMembers replaces the Members with the local node
+// & the peers passed and returns a function restoring them
+func useMembers(peers ...string) func() {
+	members := Members
+	Members = cluster.NewMembership(GetNodeID(), peers)
+	return func() { Members = members }
+}
+
+// slowPeer serves a peer that never replies
+// before the request is canceled
+func slowPeer() (string, func()) {
+	return newPeer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+}
+
+// statePeer serves a peer whose
+// TwoPSet is the one passed
+func statePeer(set twopset.TwoPSet) (string, func()) {
+	return newPeer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/twopset/values" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(State{TwoPSet: set})
+	}))
+}
+
+// TestReadConsistency checks the basic functionality of readConsistency()
+// a quorum read should return as soon as enough peers replied
+// canceling the others, while a read from all the peers should
+// return an error if any of them does not reply in time
+func TestReadConsistency(t *testing.T) {
+	remote, local, expectedValue := syncFixture()
+	useTwoPSet(local)
+
+	fast, closeFast := statePeer(remote)
+	defer closeFast()
+	slow, closeSlow := slowPeer()
+	defer closeSlow()
+
+	defer useMembers(fast, slow)()
+	defer setEnv("SYNC_STRATEGY", SyncFull)()
+
+	start := time.Now()
+	assert.Nil(t, readConsistency(context.Background(), ConsistencyQuorum))
+	assert.True(t, time.Since(start) < syncConfig.PeerTimeout/2)
+	assert.Equal(t, expectedValue, copyState().TwoPSet)
+
+	defer UseSyncConfig(syncConfig)
+	UseSyncConfig(SyncConfig{Workers: DefaultSyncWorkers, PeerTimeout: 200 * time.Millisecond})
+
+	actualError := readConsistency(context.Background(), ConsistencyAll)
+	consistencyErr, ok := actualError.(ConsistencyError)
+	assert.True(t, ok)
+	assert.Equal(t, 2, consistencyErr.Required)
+	assert.Equal(t, 1, consistencyErr.Replied)
+}
+
+// TestReadConsistency_List checks the functionality of reads with the
+// ?consistency= URL query param, invalid levels should be rejected
+func TestReadConsistency_List(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+
+	fast, closeFast := statePeer(twopset.Initialize())
+	defer closeFast()
+	slow, closeSlow := slowPeer()
+	defer closeSlow()
+
+	defer useMembers(fast, slow)()
+	defer setEnv("SYNC_STRATEGY", SyncFull)()
+
+	for path, expectedValue := range map[string]int{
+		"/twopset/list?consistency=quorum": http.StatusOK,
+		"/twopset/list?consistency=xx":     http.StatusBadRequest,
+	} {
+		recorder := httptest.NewRecorder()
+		Router().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, expectedValue, recorder.Code, path)
+	}
+}
+
+// TestWriteConsistency checks the basic functionality of writeConsistency()
+// the delta should be pushed to the peers along with the Dots
+// of its tombstones
+func TestWriteConsistency(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+
+	var mutex sync.Mutex
+	states := []State{}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		var state State
+		assert.Nil(t, state.UnmarshalBinary(body))
+
+		mutex.Lock()
+		states = append(states, state)
+		mutex.Unlock()
+	})
+
+	first, closeFirst := newPeer(handler)
+	defer closeFirst()
+	second, closeSecond := newPeer(handler)
+	defer closeSecond()
+
+	defer useMembers(first, second)()
+
+	Node.Addition("xx")
+	Node.Removal("xx")
+	delta := twopset.TwoPSet{Add: twopset.NewGSet(), Remove: twopset.NewGSet("xx")}
+
+	assert.Nil(t, writeConsistency(context.Background(), ConsistencyAll, delta))
+
+	assert.Equal(t, 2, len(states))
+	for _, state := range states {
+		assert.Equal(t, delta, state.TwoPSet)
+		assert.Equal(t, Collector.Metadata().Dots["xx"], state.GC.Dots["xx"])
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
+)
+
+const (
+	// DiscoveryFile discovers the peers
+	// listed in a static peers file
+	DiscoveryFile = "file"
+	// DiscoveryDNS discovers the peers
+	// from DNS A or SRV records
+	DiscoveryDNS = "dns"
+	// DiscoverySeeds discovers the peers
+	// known to the seed nodes
+	DiscoverySeeds = "seeds"
+
+	// DefaultDiscoveryInterval is the default
+	// interval between peer discoveries
+	DefaultDiscoveryInterval = 10 * time.Second
+	// DefaultDiscoveryPort is the default port of
+	// the peers discovered from DNS A records
+	DefaultDiscoveryPort = 8080
+)
+
+// DiscoveryConfig configures how the peers
+// joining the cluster are discovered
+type DiscoveryConfig struct {
+	// Providers are the discovery providers used
+	// No providers only uses the PEERS seeds
+	Providers []string
+	// Interval is the interval between discoveries
+	Interval time.Duration
+	// PeersFile is the file the peers are listed in
+	PeersFile string
+	// DNSName is the name the DNS records are looked up for
+	DNSName string
+	// DNSPort is the port of the peers discovered from
+	// DNS A records, SRV records are used if it is 0
+	DNSPort int
+	// Seeds are the seed nodes
+	Seeds []string
+}
+
+// newDiscovery returns the discovery of
+// the providers in the config passed
+func newDiscovery(config DiscoveryConfig) (cluster.Discovery, error) {
+	providers := cluster.MultiDiscovery{}
+
+	for _, provider := range config.Providers {
+		switch provider {
+		case DiscoveryFile:
+			if config.PeersFile == "" {
+				return nil, errors.New("no peers file provided for the file discovery")
+			}
+			providers = append(providers, cluster.NewFileDiscovery(config.PeersFile))
+		case DiscoveryDNS:
+			if config.DNSName == "" {
+				return nil, errors.New("no dns name provided for the dns discovery")
+			}
+			providers = append(providers, cluster.NewDNSDiscovery(nil, config.DNSName, config.DNSPort))
+		case DiscoverySeeds:
+			if len(config.Seeds) == 0 {
+				return nil, errors.New("no seeds provided for the seeds discovery")
+			}
+			providers = append(providers, cluster.NewSeedDiscovery(config.Seeds, SendMembersRequest))
+		default:
+			return nil, errors.New("invalid discovery provider provided: " + provider)
+		}
+	}
+
+	return providers, nil
+}
+
+// StartDiscovery discovers the peers joining & leaving the cluster
+// using the providers in the config, once before returning & then
+// every interval in the background. The peers discovered are
+// added to the Members unless they have left the cluster, and
+// the peers no longer listed are marked left
+func StartDiscovery(config DiscoveryConfig) error {
+	if len(config.Providers) == 0 {
+		return nil
+	}
+
+	discovery, err := newDiscovery(config)
+	if err != nil {
+		return err
+	}
+
+	discoverer := newPeerDiscovery(discovery)
+	discoverer.discover()
+
+	if config.Interval <= 0 {
+		return nil
+	}
+
+	go func() {
+		for range time.Tick(config.Interval) {
+			discoverer.discover()
+		}
+	}()
+
+	return nil
+}
+
+// peerDiscovery tracks the peers listed by a discovery
+// so the ones no longer listed can be marked left
+type peerDiscovery struct {
+	discovery cluster.Discovery
+	// listed are the peers listed
+	// by the previous discovery
+	listed map[string]struct{}
+	// dropped are the peers marked left
+	// as they were no longer listed
+	dropped map[string]struct{}
+}
+
+// newPeerDiscovery returns a new peerDiscovery
+// of the peers listed by the discovery passed
+func newPeerDiscovery(discovery cluster.Discovery) *peerDiscovery {
+	return &peerDiscovery{
+		discovery: discovery,
+		listed:    map[string]struct{}{},
+		dropped:   map[string]struct{}{},
+	}
+}
+
+// discover adds the peers discovered to the Members within
+// the per peer sync deadline, and marks the peers listed by
+// the previous discovery but no longer listed as left, so
+// the peers removed from a peers file or DNS do not block
+// garbage collection. The peers it marked left join back
+// once listed again. The addresses of the local node are
+// dropped, as when NODE is the hostname it is discovered
+// by its IP address
+func (discoverer *peerDiscovery) discover() {
+	ctx, cancel := context.WithTimeout(context.Background(), syncConfig.PeerTimeout)
+	defer cancel()
+
+	peers, err := discoverer.discovery.Peers(ctx)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Warn("failed to discover peers")
+		return
+	}
+	peers = dropSelf(peers)
+
+	listed := make(map[string]struct{}, len(peers))
+	rejoined := []string{}
+	for _, peer := range peers {
+		listed[peer] = struct{}{}
+		if _, dropped := discoverer.dropped[peer]; !dropped {
+			continue
+		}
+		delete(discoverer.dropped, peer)
+		if Members.Join(peer) == nil {
+			rejoined = append(rejoined, peer)
+		}
+	}
+
+	discovered := append(Members.Discover(peers), rejoined...)
+
+	left := []string{}
+	for peer := range discoverer.listed {
+		if _, present := listed[peer]; present {
+			continue
+		}
+		if Members.Leave(peer) == nil {
+			discoverer.dropped[peer] = struct{}{}
+			left = append(left, peer)
+		}
+	}
+	sort.Strings(left)
+
+	discoverer.listed = listed
+
+	if len(discovered) == 0 && len(left) == 0 {
+		return
+	}
+
+	// INFO log the peers discovered & left
+	// along with the new members
+	log.WithFields(log.Fields{
+		"discovered": discovered,
+		"left":       left,
+		"members":    Members.Members(),
+	}).Info("discovered peers")
+}
+
+// dropSelf returns the peers that are not
+// addresses of the local node on GetPort()
+func dropSelf(peers []string) []string {
+	self := map[string]struct{}{
+		Members.Self():                           {},
+		net.JoinHostPort("localhost", GetPort()): {},
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		self[hostname] = struct{}{}
+		self[net.JoinHostPort(hostname, GetPort())] = struct{}{}
+	}
+
+	addresses, err := net.InterfaceAddrs()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Warn("failed to obtain local addresses")
+	}
+	for _, address := range addresses {
+		if ip, ok := address.(*net.IPNet); ok {
+			self[net.JoinHostPort(ip.IP.String(), GetPort())] = struct{}{}
+		}
+	}
+
+	remote := []string{}
+	for _, peer := range peers {
+		if _, present := self[peer]; !present {
+			remote = append(remote, peer)
+		}
+	}
+	return remote
+}
+
+// SendMembersRequest is used to send a GET /cluster/members
+// to a node to obtain the members of the cluster known to it
+func SendMembersRequest(ctx context.Context, node string) ([]string, error) {
+	// Return an error if the node is nil
+	if node == "" {
+		return nil, errors.New("empty node provided")
+	}
+
+	url := fmt.Sprintf("http://%s/cluster/members", GetPeerAddress(node))
+	response, err := SendRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, StatusError{StatusCode: response.StatusCode}
+	}
+
+	var members ClusterMembers
+	if err := json.NewDecoder(response.Body).Decode(&members); err != nil {
+		return nil, DecodeError{Err: err}
+	}
+	return members.Members, nil
+}
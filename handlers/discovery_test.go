@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
+)
+
+// staticDiscovery discovers the peers listed
+type staticDiscovery []string
+
+func (discovery staticDiscovery) Peers(ctx context.Context) ([]string, error) {
+	return discovery, nil
+}
+
+// TestDiscover checks the functionality of peerDiscovery discover()
+// the addresses of the local node should not be added
+// to the Members while the other peers should
+func TestDiscover(t *testing.T) {
+	defer setEnv("PORT", "8080")()
+
+	defer func(members *cluster.Membership) { Members = members }(Members)
+	Members = cluster.NewMembership(GetNodeID(), nil)
+
+	peer := "10.255.255.1:8080"
+
+	newPeerDiscovery(staticDiscovery{
+		net.JoinHostPort("127.0.0.1", GetPort()),
+		net.JoinHostPort("::1", GetPort()),
+		net.JoinHostPort("localhost", GetPort()),
+		Members.Self(),
+		peer,
+	}).discover()
+
+	assert.Equal(t, []string{peer}, Members.Peers())
+}
+
+// TestDiscover_Removed checks the functionality of peerDiscovery discover()
+// the peers removed from the peers file should be marked left
+// and join back once they are listed in it again
+func TestDiscover_Removed(t *testing.T) {
+	defer setEnv("PORT", "8080")()
+	defer useMembers()()
+
+	directory, err := ioutil.TempDir("", "discovery")
+	assert.Nil(t, err)
+	defer os.RemoveAll(directory)
+
+	path := filepath.Join(directory, "peers")
+	discoverer := newPeerDiscovery(cluster.NewFileDiscovery(path))
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("10.255.255.1:8080\n10.255.255.2:8080\n"), 0644))
+	discoverer.discover()
+	assert.ElementsMatch(t, []string{"10.255.255.1:8080", "10.255.255.2:8080"}, Members.Peers())
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("10.255.255.1:8080\n"), 0644))
+	discoverer.discover()
+	assert.Equal(t, []string{"10.255.255.1:8080"}, Members.Peers())
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("# restored\n10.255.255.1:8080\n10.255.255.2:8080\n"), 0644))
+	discoverer.discover()
+	assert.ElementsMatch(t, []string{"10.255.255.1:8080", "10.255.255.2:8080"}, Members.Peers())
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultGossipInterval is the default
+	// interval between gossip rounds
+	DefaultGossipInterval = 5 * time.Second
+	// DefaultGossipFanout is the default number
+	// of peers synced with in each gossip round
+	DefaultGossipFanout = 2
+	// DefaultGossipJitter is the default maximum
+	// random delay added to the gossip interval
+	DefaultGossipJitter = time.Second
+)
+
+// GossipConfig configures the background
+// anti-entropy between the nodes in the cluster
+type GossipConfig struct {
+	// Interval is the interval between gossip rounds
+	// A non positive interval disables gossip
+	Interval time.Duration
+	// Fanout is the number of random peers
+	// synced with in each gossip round
+	Fanout int
+	// Jitter is the maximum random delay added to
+	// the interval so nodes do not gossip in lockstep
+	Jitter time.Duration
+}
+
+// StartGossip syncs the set with a random subset of
+// the peers in the cluster every interval in the background
+// so reads can be served from the local set
+func StartGossip(config GossipConfig) {
+	if config.Interval <= 0 {
+		return
+	}
+
+	go func() {
+		random := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+		for {
+			delay := config.Interval
+			if config.Jitter > 0 {
+				delay += time.Duration(random.Int63n(int64(config.Jitter)))
+			}
+			time.Sleep(delay)
+
+			peers := selectPeers(random, GetPeerList(), config.Fanout)
+			if len(peers) == 0 {
+				continue
+			}
+
+			// The members are gossiped along with the set
+			// so the nodes joining & leaving are learnt
+			syncMembership(context.Background(), peers)
+
+			if _, err := Node.Sync(context.Background(), peers); err != nil {
+				log.WithFields(log.Fields{"error": err, "peers": peers}).Error("failed to gossip with peers")
+				continue
+			}
+
+			// DEBUG log in the case of success
+			// indicating the peers gossiped with
+			log.WithFields(log.Fields{
+				"peers": peers,
+			}).Debug("successful gossip round")
+		}
+	}()
+}
+
+// selectPeers returns a random subset of
+// the peers of at most the fanout passed
+func selectPeers(random *rand.Rand, peers []string, fanout int) []string {
+	if fanout <= 0 || len(peers) == 0 {
+		return []string{}
+	}
+	if fanout > len(peers) {
+		fanout = len(peers)
+	}
+
+	selected := make([]string, 0, fanout)
+	for _, index := range random.Perm(len(peers))[:fanout] {
+		selected = append(selected, peers[index])
+	}
+	return selected
+}
+
+// syncRequested returns true if the read
+// request asked to sync with every peer
+// first using ?sync=true
+func syncRequested(r *http.Request) bool {
+	return r.URL.Query().Get("sync") == "true"
+}
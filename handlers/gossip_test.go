@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// TestGossip_ConcurrentReads checks the functionality of the TwoPSet
+// handlers while it is gossiped & written to, every reader should
+// hold the storeMutex or read a copy of the TwoPSet
+// It is meant to be run with the race detector
+func TestGossip_ConcurrentReads(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+
+	router := Router()
+	peer, closePeer := newPeer(router)
+	defer closePeer()
+
+	var wait sync.WaitGroup
+	done := make(chan struct{})
+
+	wait.Add(2)
+	go func() {
+		defer wait.Done()
+		defer close(done)
+		for index := 0; index < 500; index++ {
+			Node.Addition(fmt.Sprintf("value-%d", index))
+			if index%3 == 0 {
+				Node.Removal(fmt.Sprintf("value-%d", index))
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	// Gossip rounds as run by StartGossip()
+	go func() {
+		defer wait.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			_, err := Node.Sync(context.Background(), []string{peer})
+			assert.Nil(t, err)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	paths := []string{
+		"/twopset/values",
+		"/twopset/list",
+		"/twopset/merkle?level=0",
+		"/twopset/buckets?index=0",
+		"/twopset/iblt?cells=64",
+		"/twopset/gc",
+	}
+
+	for served := false; !served; {
+		select {
+		case <-done:
+			served = true
+		default:
+		}
+
+		for _, path := range paths {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+			assert.Equal(t, http.StatusOK, recorder.Code, path)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	wait.Wait()
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
+)
+
+// probeCheckInterval is the interval the peers
+// are checked at for probes that are due
+const probeCheckInterval = 250 * time.Millisecond
+
+var (
+	// Health tracks the health of the peers from the
+	// requests sent to them, so peers failing repeatedly
+	// are skipped until they recover
+	Health = cluster.NewHealth(cluster.DefaultHealthConfig(), nil)
+)
+
+// StartHealth tracks the health of the peers with the config
+// passed and probes the peers whose circuit is open in the
+// background, so they are synced with again once they recover
+// even when no requests are sent to them
+func StartHealth(config cluster.HealthConfig) {
+	Health = cluster.NewHealth(config, nil)
+
+	go func() {
+		for {
+			time.Sleep(probeCheckInterval)
+
+			// The members suspected or dead are probed
+			// too so their circuits close on recovery
+			for _, peer := range Health.Unhealthy(Members.Peers()) {
+				if Health.Allow(peer) {
+					go probePeer(peer)
+				}
+			}
+		}
+	}()
+}
+
+// probePeer sends a GET / to the peer within the
+// per peer sync deadline and records its outcome
+func probePeer(peer string) {
+	ctx, cancel := context.WithTimeout(context.Background(), syncConfig.PeerTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := sendProbeRequest(ctx, peer)
+	Health.Record(peer, time.Since(start), err)
+
+	// DEBUG log the outcome
+	// of the probe of the peer
+	log.WithFields(log.Fields{
+		"peer":  peer,
+		"error": err,
+	}).Debug("probed peer")
+}
+
+// sendProbeRequest is used to send a GET /
+// to peer nodes in the cluster
+func sendProbeRequest(ctx context.Context, peer string) error {
+	url := fmt.Sprintf("http://%s/", GetPeerAddress(peer))
+	response, err := SendRequest(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return StatusError{StatusCode: response.StatusCode}
+	}
+	return nil
+}
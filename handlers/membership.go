@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
+	"github.com/el10savio/twoPSet-crdt/hlc"
+	"github.com/el10savio/twoPSet-crdt/lwwset"
+)
+
+var (
+	// Members is the live list of the nodes in the cluster
+	// seeded from GetSeedPeers() & replicated between the
+	// nodes, the peers synced with are obtained from it
+	Members = cluster.NewMembership(GetNodeID(), GetSeedPeers())
+)
+
+// validateMembership returns an error if the LWWSet of the
+// members replicated from a peer has an invalid node
+func validateMembership(state lwwset.LWWSet) error {
+	for _, nodes := range []map[string]hlc.Timestamp{state.Add, state.Remove} {
+		for node := range nodes {
+			if err := cluster.ValidateNode(node); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeMembership merges the LWWSet of the members
+// replicated from a peer, logging the members if
+// they changed
+func mergeMembership(state lwwset.LWWSet) {
+	if !Members.Merge(state) {
+		return
+	}
+
+	// INFO log the new members
+	// of the cluster
+	log.WithFields(log.Fields{
+		"members": Members.Members(),
+	}).Info("cluster membership changed")
+}
+
+// syncMembership obtains the LWWSet of the members
+// from each of the given peers & merges it
+func syncMembership(ctx context.Context, peers []string) SyncReport {
+	states, report := syncPeers(ctx, peers, func(ctx context.Context, peer string) (interface{}, error) {
+		return SendMembershipRequest(ctx, peer)
+	})
+
+	for index, result := range report.Peers {
+		if result.Status != SyncOK {
+			logSyncFailure(result, "failed sending cluster membership request")
+			continue
+		}
+		mergeMembership(states[index].(lwwset.LWWSet))
+	}
+
+	return report
+}
+
+// pushMembership sends the LWWSet of the
+// members to each of the given nodes
+func pushMembership(ctx context.Context, nodes []string) SyncReport {
+	state := Members.State()
+
+	_, report := syncPeers(ctx, nodes, func(ctx context.Context, node string) (interface{}, error) {
+		return nil, SendMembershipMergeRequest(ctx, node, state)
+	})
+
+	for _, result := range report.Peers {
+		if result.Status != SyncOK {
+			logSyncFailure(result, "failed pushing cluster membership")
+		}
+	}
+
+	return report
+}
+
+// SendMembershipRequest is used to send a GET /cluster/membership
+// to peer nodes in the cluster to obtain the LWWSet of the members
+func SendMembershipRequest(ctx context.Context, peer string) (lwwset.LWWSet, error) {
+	var state lwwset.LWWSet
+
+	// Return an empty LWWSet followed by an error if the peer is nil
+	if peer == "" {
+		return state, errors.New("empty peer provided")
+	}
+
+	url := fmt.Sprintf("http://%s/cluster/membership", GetPeerAddress(peer))
+	response, err := SendRequest(ctx, url)
+	if err != nil {
+		return state, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return state, StatusError{StatusCode: response.StatusCode}
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&state); err != nil {
+		return lwwset.LWWSet{}, DecodeError{Err: err}
+	}
+	if err := validateMembership(state); err != nil {
+		return lwwset.LWWSet{}, DecodeError{Err: err}
+	}
+
+	return state, nil
+}
+
+// SendMembershipMergeRequest is used to send a POST /cluster/membership
+// pushing the LWWSet of the members to a node in the cluster
+func SendMembershipMergeRequest(ctx context.Context, node string, state lwwset.LWWSet) error {
+	// Return an error if the node is nil
+	if node == "" {
+		return errors.New("empty node provided")
+	}
+
+	body, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("http://%s/cluster/membership", GetPeerAddress(node))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := DoRequest(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return StatusError{StatusCode: response.StatusCode}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/store"
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// postMerge sends the JSON encoded State to the merge endpoint
+// with the given Content-Type and returns the response recorded
+func postMerge(state State, contentType string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(state)
+	return postMergeBody(body, contentType)
+}
+
+// postMergeBody sends the body to the merge endpoint with the
+// given Content-Type and returns the response recorded
+func postMergeBody(body []byte, contentType string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/twopset/merge", bytes.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+
+	recorder := httptest.NewRecorder()
+	Router().ServeHTTP(recorder, request)
+	return recorder
+}
+
+// TestMerge checks the basic functionality of the Merge handler
+// the additions & tombstones missing should be merged along with
+// the Dots of the tombstones in the garbage collection metadata
+func TestMerge(t *testing.T) {
+	useTwoPSet(twopset.TwoPSet{Add: twopset.NewGSet("xx"), Remove: twopset.NewGSet()})
+
+	dots := []twopset.Dot{{Node: "peer-1", Sequence: 1}}
+	state := State{
+		TwoPSet: twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy"), Remove: twopset.NewGSet("yy")},
+		GC:      &twopset.Metadata{Dots: map[string][]twopset.Dot{"yy": dots}},
+	}
+
+	recorder := postMerge(state, "application/json")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response MergeResponse
+	json.NewDecoder(recorder.Body).Decode(&response)
+	assert.Equal(t, 1, response.Added)
+	assert.Equal(t, 1, response.Removed)
+
+	values := copyState()
+	assert.Equal(t, state.TwoPSet, values.TwoPSet)
+	assert.Equal(t, dots, values.GC.Dots["yy"])
+}
+
+// TestMerge_Binary checks the functionality of the Merge handler
+// a TwoPSet in its binary encoding should be merged, and a State
+// in the binary encoding of twopset.StateMediaType should be
+// merged along with its garbage collection metadata
+func TestMerge_Binary(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+
+	set := twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy"), Remove: twopset.NewGSet("xx")}
+	body, _ := set.MarshalBinary()
+
+	recorder := postMergeBody(body, twopset.MediaType)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, set, copyState().TwoPSet)
+
+	dots := []twopset.Dot{{Node: "peer-1", Sequence: 1}}
+	state := State{
+		TwoPSet: twopset.TwoPSet{Add: twopset.NewGSet("zz"), Remove: twopset.NewGSet("zz")},
+		GC:      &twopset.Metadata{Dots: map[string][]twopset.Dot{"zz": dots}},
+	}
+	body, _ = state.MarshalBinary()
+
+	recorder = postMergeBody(body, twopset.StateMediaType)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	values := copyState()
+	assert.Equal(t, twopset.Merge(set, state.TwoPSet), values.TwoPSet)
+	assert.Equal(t, dots, values.GC.Dots["zz"])
+
+	// Each binary encoding is only decoded under its media type
+	recorder = postMergeBody(body, twopset.MediaType)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// TestMerge_Stored checks the functionality of the Merge handler
+// with the FileStore, the State merged should be stored once it
+// is acknowledged, so it is loaded back without a snapshot
+func TestMerge_Stored(t *testing.T) {
+	dir, err := ioutil.TempDir("", "twopset-merge")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	backend, err := store.OpenFileStore(dir)
+	assert.Nil(t, err)
+
+	useTwoPSet(twopset.Initialize())
+	assert.Nil(t, UseStore(backend))
+	defer func() { Storage = store.NewMemoryStore() }()
+
+	state := State{TwoPSet: twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy"), Remove: twopset.NewGSet("xx")}}
+	recorder := postMerge(state, "application/json")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Nil(t, backend.Close())
+
+	backend, _ = store.OpenFileStore(dir)
+	actualValue, actualError := backend.Load()
+	assert.Nil(t, actualError)
+	assert.Equal(t, state.TwoPSet, actualValue.TwoPSet)
+	assert.Empty(t, actualValue.Removals)
+	backend.Close()
+}
+
+// TestMerge_Purged checks the functionality of the Merge handler
+// for a value purged locally, it should not be merged back
+// whether the State has garbage collection metadata or not
+// and adding it again should return HTTP 409 Conflict
+func TestMerge_Purged(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+
+	Node.Addition("xx")
+	Node.Removal("xx")
+	metadata := Collector.Metadata()
+
+	// Purge the tombstone as stable
+	// with no other members
+	report, err := collectTwoPSet(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.Purged)
+
+	set := twopset.TwoPSet{Add: twopset.NewGSet("xx"), Remove: twopset.NewGSet("xx")}
+	for _, state := range []State{
+		{TwoPSet: set, GC: &twopset.Metadata{Node: "peer-1", Dots: metadata.Dots, Version: metadata.Version}},
+		{TwoPSet: set},
+	} {
+		recorder := postMerge(state, "application/json")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, twopset.Initialize(), copyState().TwoPSet)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/twopset/add/xx", nil)
+	recorder := httptest.NewRecorder()
+	Router().ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	assert.Equal(t, twopset.ErrPurged, Node.Addition("xx"))
+	assert.Equal(t, twopset.Initialize(), copyState().TwoPSet)
+}
+
+// TestMerge_Invalid checks the functionality of the Merge handler
+// States too large or of other content types should be rejected
+func TestMerge_Invalid(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+
+	state := State{TwoPSet: twopset.TwoPSet{Add: twopset.NewGSet("xx"), Remove: twopset.NewGSet()}}
+
+	UseMergeMaxBytes(8)
+	recorder := postMerge(state, "application/json")
+	UseMergeMaxBytes(DefaultMergeMaxBytes)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+
+	recorder = postMerge(state, "text/plain")
+	assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+
+	assert.Equal(t, twopset.Initialize(), copyState().TwoPSet)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// getMerkle sends a GET request for the given Merkle
+// endpoint URL and returns the response recorded
+func getMerkle(url string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	recorder := httptest.NewRecorder()
+	Router().ServeHTTP(recorder, request)
+	return recorder
+}
+
+// TestMerkle checks the basic functionality of the Merkle handler
+// the root should match the tree of the TwoPSet, the leaves should
+// be returned for the level requested & only the VersionVector
+// should be sent along with them
+func TestMerkle(t *testing.T) {
+	set := twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy"), Remove: twopset.NewGSet()}
+	useTwoPSet(set)
+	Node.Removal("xx")
+	set.Remove = twopset.NewGSet("xx")
+
+	recorder := getMerkle("/twopset/merkle")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var actualValue MerkleLevel
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&actualValue))
+
+	root := twopset.NewMerkleTree(set).Root()
+	assert.Equal(t, twopset.MerkleDepth, actualValue.Depth)
+	assert.Equal(t, []int{0}, actualValue.Index)
+	assert.Equal(t, []string{hex.EncodeToString(root[:])}, actualValue.Hashes)
+	assert.NotNil(t, actualValue.GC)
+	assert.Nil(t, actualValue.GC.Dots)
+	assert.Equal(t, 1, len(actualValue.GC.Version))
+
+	recorder = getMerkle(fmt.Sprintf("/twopset/merkle?level=%d&index=1,3", twopset.MerkleDepth))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	actualValue = MerkleLevel{}
+	json.NewDecoder(recorder.Body).Decode(&actualValue)
+	assert.Equal(t, twopset.MerkleDepth, actualValue.Level)
+	assert.Equal(t, []int{1, 3}, actualValue.Index)
+	assert.Equal(t, 2, len(actualValue.Hashes))
+}
+
+// TestMerkle_Invalid checks the functionality of the Merkle handler
+// levels & indexes outside the tree should return HTTP 400 Bad Request
+func TestMerkle_Invalid(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+
+	for _, url := range []string{
+		"/twopset/merkle?level=-1",
+		fmt.Sprintf("/twopset/merkle?level=%d", twopset.MerkleDepth+1),
+		"/twopset/merkle?level=xx",
+		"/twopset/merkle?index=1",
+	} {
+		recorder := getMerkle(url)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, url)
+	}
+}
+
+// TestBuckets checks the basic functionality of the Buckets handler
+// only the additions & tombstones in the buckets requested should be
+// returned along with the Dots of those tombstones
+func TestBuckets(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+	Node.Addition("xx")
+	Node.Addition("yy")
+	Node.Removal("xx")
+	Node.Removal("yy")
+
+	bucket := Tree.Bucket("xx")
+	assert.NotEqual(t, bucket, Tree.Bucket("yy"))
+
+	recorder := getMerkle(fmt.Sprintf("/twopset/buckets?index=%d", bucket))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var actualValue State
+	assert.Nil(t, json.NewDecoder(recorder.Body).Decode(&actualValue))
+
+	expectedValue := twopset.TwoPSet{Add: twopset.NewGSet("xx"), Remove: twopset.NewGSet("xx")}
+	assert.Equal(t, expectedValue, actualValue.TwoPSet)
+	assert.NotNil(t, actualValue.GC)
+	assert.Equal(t, 1, len(actualValue.GC.Dots))
+	assert.Equal(t, 1, len(actualValue.GC.Dots["xx"]))
+
+	for _, url := range []string{
+		"/twopset/buckets",
+		fmt.Sprintf("/twopset/buckets?index=%d", 1<<uint(twopset.MerkleDepth)),
+	} {
+		recorder := getMerkle(url)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, url)
+	}
+}
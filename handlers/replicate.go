@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+const (
+	// DefaultPushInterval is the default
+	// interval between push rounds
+	DefaultPushInterval = 500 * time.Millisecond
+	// DefaultPushRetries is the default number of times
+	// a push to a peer is retried in each round
+	DefaultPushRetries = 3
+	// DefaultPushBackoff is the default delay before
+	// the first retry, doubled for each retry after it
+	DefaultPushBackoff = 100 * time.Millisecond
+	// DefaultPushQueue is the default maximum
+	// number of deltas buffered for the peers
+	DefaultPushQueue = 1024
+)
+
+// PushConfig configures the push
+// replication of writes to the peers
+type PushConfig struct {
+	// Interval is the interval between push rounds
+	// A non positive interval disables push replication
+	Interval time.Duration
+	// Retries is the number of times a push
+	// to a peer is retried in each round
+	Retries int
+	// Backoff is the delay before the first
+	// retry, doubled for each retry after it
+	Backoff time.Duration
+	// Queue is the maximum number of deltas
+	// buffered for the peers. The peers lagging
+	// behind are pushed the full TwoPSet instead
+	Queue int
+}
+
+var (
+	// Deltas buffers the deltas of the writes until
+	// they are pushed to every peer. It is nil
+	// when push replication is disabled
+	Deltas *twopset.DeltaBuffer
+
+	// pushConfig is the push replication config
+	pushConfig PushConfig
+
+	// resyncMutex guards resync
+	resyncMutex sync.Mutex
+	// resync maps the peers whose deltas were dropped
+	// and need the full TwoPSet pushed to the sequence
+	// number of the latest delta dropped
+	resync = make(map[string]uint64)
+
+	// pushPeers are the peers the deltas are buffered
+	// for. It is only accessed by Push() so the peers
+	// joining & leaving the cluster are tracked
+	pushPeers = make(map[string]struct{})
+)
+
+// StartPush pushes the writes to the TwoPSet to every peer
+// in the cluster in the background, batching the deltas
+// written during each interval
+func StartPush(config PushConfig) {
+	if config.Interval <= 0 {
+		return
+	}
+
+	pushConfig = config
+	Deltas = twopset.NewDeltaBuffer(GetPeerList()...)
+	for _, peer := range GetPeerList() {
+		pushPeers[peer] = struct{}{}
+	}
+
+	go func() {
+		for range time.Tick(config.Interval) {
+			Push()
+		}
+	}()
+}
+
+// Push sends the deltas not yet acknowledged
+// by each peer to it concurrently
+func Push() {
+	if Deltas == nil {
+		return
+	}
+
+	peers := GetPeerList()
+	trackPeers(peers)
+
+	var wait sync.WaitGroup
+	for _, peer := range peers {
+		wait.Add(1)
+		go func(peer string) {
+			defer wait.Done()
+			if err := pushPeer(peer); err != nil {
+				log.WithFields(log.Fields{"error": err, "peer": peer}).Error("failed to push twopset to peer")
+			}
+		}(peer)
+	}
+	wait.Wait()
+}
+
+// trackPeers buffers the deltas for the peers that joined
+// the cluster, which are pushed the full TwoPSet first, and
+// stops buffering them for the peers that left
+func trackPeers(peers []string) {
+	current := make(map[string]struct{}, len(peers))
+	for _, peer := range peers {
+		current[peer] = struct{}{}
+		if _, tracked := pushPeers[peer]; tracked {
+			continue
+		}
+
+		pushPeers[peer] = struct{}{}
+		Deltas.AddPeer(peer)
+		resyncMutex.Lock()
+		resync[peer] = Deltas.Sequence()
+		resyncMutex.Unlock()
+	}
+
+	for peer := range pushPeers {
+		if _, present := current[peer]; present {
+			continue
+		}
+
+		delete(pushPeers, peer)
+		Deltas.RemovePeer(peer)
+		resyncMutex.Lock()
+		delete(resync, peer)
+		resyncMutex.Unlock()
+	}
+}
+
+// pushPeer sends the deltas not yet acknowledged by the
+// peer to it, or the full TwoPSet if its deltas were dropped
+// The push is retried with an exponential backoff
+func pushPeer(peer string) error {
+	// The deltas stay buffered for the peers failing
+	// repeatedly until their circuit is probed
+	if !Health.Allow(peer) {
+		return nil
+	}
+
+	resyncMutex.Lock()
+	_, full := resync[peer]
+	resyncMutex.Unlock()
+
+	var state State
+	var sequence uint64
+
+	if full {
+		// The full TwoPSet holds every delta
+		// pushed up to the current sequence
+		storeMutex.Lock()
+		sequence = Deltas.Sequence()
+		metadata := Collector.Metadata()
+		state = State{TwoPSet: TwoPSet.Copy(), GC: &metadata}
+		storeMutex.Unlock()
+	} else {
+		delta, pending, err := Deltas.Pending(peer)
+		if err != nil {
+			return err
+		}
+		if delta.IsEmpty() {
+			return nil
+		}
+		state, sequence = deltaState(delta), pending
+	}
+
+	var err error
+
+	backoff := pushConfig.Backoff
+	for attempt := 0; ; attempt++ {
+		// Each attempt is given the
+		// per peer sync deadline
+		ctx, cancel := context.WithTimeout(context.Background(), syncConfig.PeerTimeout)
+		start := time.Now()
+		err = SendMergeRequest(ctx, peer, state)
+		cancel()
+		Health.Record(peer, time.Since(start), err)
+
+		// Stop retrying once the
+		// circuit of the peer opens
+		if err == nil || attempt >= pushConfig.Retries || !Health.Allow(peer) {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	if err != nil {
+		return err
+	}
+
+	// Deltas dropped while pushing the full
+	// TwoPSet are only covered if they were
+	// dropped before it was copied
+	if full {
+		resyncMutex.Lock()
+		if resync[peer] <= sequence {
+			delete(resync, peer)
+		}
+		resyncMutex.Unlock()
+	}
+
+	// DEBUG log in the case of success
+	// indicating the delta pushed
+	log.WithFields(log.Fields{
+		"peer":     peer,
+		"delta":    state.TwoPSet,
+		"sequence": sequence,
+		"full":     full,
+	}).Debug("successful twopset push")
+
+	return Deltas.Acknowledge(peer, sequence)
+}
+
+// deltaState returns the State pushing the delta to a peer
+// along with the Dots of its tombstones, so the peer tracks
+// them until they are stable. The VersionVector is left out
+// as the peer does not observe the Removals outside the delta
+func deltaState(delta twopset.TwoPSet) State {
+	metadata := Collector.Metadata()
+
+	dots := make(map[string][]twopset.Dot)
+	for value := range delta.Remove {
+		if removals, tracked := metadata.Dots[value]; tracked {
+			dots[value] = removals
+		}
+	}
+
+	return State{TwoPSet: delta, GC: &twopset.Metadata{Dots: dots}}
+}
+
+// pushDelta buffers the delta of a write to be pushed
+// to the peers, dropping the oldest deltas if the
+// buffer is full. It must be called with the storeMutex
+// held so the deltas are buffered in the order applied
+func pushDelta(delta twopset.TwoPSet) {
+	if Deltas == nil {
+		return
+	}
+
+	sequence := Deltas.Push(delta)
+
+	dropped := Deltas.Drop(pushConfig.Queue)
+	if len(dropped) == 0 {
+		return
+	}
+
+	resyncMutex.Lock()
+	for _, peer := range dropped {
+		resync[peer] = sequence
+	}
+	resyncMutex.Unlock()
+
+	log.WithFields(log.Fields{
+		"peers": dropped,
+	}).Warn("push queue full, dropped deltas of lagging peers")
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// TestDeltaState checks the basic functionality of deltaState()
+// the Dots of the delta's tombstones should be pushed along with it
+// while the ones of other tombstones & the VersionVector are left out
+func TestDeltaState(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+
+	Node.Addition("xx")
+	Node.Removal("xx")
+	Node.Addition("yy")
+	Node.Removal("yy")
+
+	delta := twopset.TwoPSet{Add: twopset.NewGSet("xx"), Remove: twopset.NewGSet("xx")}
+
+	expectedValue := State{
+		TwoPSet: delta,
+		GC: &twopset.Metadata{
+			Dots: map[string][]twopset.Dot{"xx": Collector.Metadata().Dots["xx"]},
+		},
+	}
+
+	actualValue := deltaState(delta)
+	assert.Equal(t, expectedValue, actualValue)
+	assert.Equal(t, 1, len(actualValue.GC.Dots["xx"]))
+
+	// It should be sent using the binary encoding
+	encoded, actualError := actualValue.MarshalBinary()
+	assert.Nil(t, actualError)
+
+	var decoded State
+	assert.Nil(t, decoded.UnmarshalBinary(encoded))
+	assert.Equal(t, actualValue.TwoPSet, decoded.TwoPSet)
+	assert.Equal(t, actualValue.GC.Dots, decoded.GC.Dots)
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
@@ -14,10 +16,27 @@ var (
 	// TwoPSet is the 2PSet
 	// data structure initialized
 	TwoPSet twopset.TwoPSet
+
+	// Collector tracks & purges the
+	// causally stable TwoPSet tombstones
+	Collector *twopset.Collector
+
+	// Tree is the Merkle tree digest
+	// of the TwoPSet used for anti-entropy
+	Tree *twopset.MerkleTree
 )
 
 func init() {
 	TwoPSet = twopset.Initialize()
+	Collector, _ = twopset.NewReplicaCollector(GetNodeID(), replicaID())
+	Tree = twopset.NewMerkleTree(TwoPSet)
+}
+
+// replicaID returns the ID the Removals of this run of
+// the node are issued with, so a node restarted without
+// its snapshot never reuses the Dots its peers observed
+func replicaID() string {
+	return GetNodeID() + "@" + strconv.FormatInt(time.Now().UnixNano(), 36)
 }
 
 // Route defines the Mux
@@ -37,6 +56,22 @@ var Routes = []Route{
 	{"/twopset/lookup/{value}", "GET", Lookup},
 	{"/twopset/add/{value}", "POST", Add},
 	{"/twopset/remove/{value}", "POST", Remove},
+	{"/twopset/diff", "GET", Diff},
+	{"/twopset/merkle", "GET", Merkle},
+	{"/twopset/buckets", "GET", Buckets},
+	{"/twopset/merge", "POST", Merge},
+	{"/twopset/iblt", "GET", IBLT},
+	{"/twopset/iblt/elements", "POST", IBLTElements},
+	{"/twopset/gc", "GET", GCStatus},
+	{"/twopset/gc", "POST", GC},
+	{"/twopset/sync", "POST", SyncAll},
+	{"/cluster/peers", "GET", Peers},
+	{"/cluster/members", "GET", ListMembers},
+	{"/cluster/membership", "GET", Membership},
+	{"/cluster/membership", "POST", MergeMembership},
+	{"/cluster/join", "POST", Join},
+	{"/cluster/leave", "POST", Leave},
+	{"/cluster/swim", "POST", SWIM},
 }
 
 // Index is the handler for the path "/"
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/lwwset"
+	"github.com/el10savio/twoPSet-crdt/orset"
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+const (
+	// SetTwoPSet serves a TwoPSet
+	SetTwoPSet = "twopset"
+	// SetORSet serves an ORSet
+	SetORSet = "orset"
+	// SetLWWSet serves an LWWSet
+	SetLWWSet = "lwwset"
+)
+
+// Set is the CRDT set served by
+// the node over the /twopset/* routes
+type Set interface {
+	// Addition adds a value to the set
+	Addition(value string) error
+	// Removal removes a value from the set
+	Removal(value string) error
+	// Lookup checks if a value is present in the set
+	Lookup(value string) (bool, error)
+	// List returns the values present in the set
+	List() []string
+	// Values returns the set's state sent to peers
+	Values() interface{}
+	// Sync merges the set with the given peers
+	// reporting the outcome for each peer
+	Sync(ctx context.Context, peers []string) (SyncReport, error)
+}
+
+var (
+	// Node is the CRDT set
+	// served by the node
+	Node Set = twoPSetNode{}
+)
+
+// UseSet selects the CRDT set type served by the node
+// An empty set type defaults to the TwoPSet
+// The LWWSet bias is obtained using GetLWWBias()
+func UseSet(setType string) error {
+	switch setType {
+	case "", SetTwoPSet:
+		Node = twoPSetNode{}
+	case SetORSet:
+		Node = &orSetNode{set: orset.Initialize()}
+	case SetLWWSet:
+		bias, err := lwwset.ParseBias(GetLWWBias())
+		if err != nil {
+			return err
+		}
+		Node = &lwwSetNode{set: lwwset.Initialize(bias)}
+	default:
+		return errors.New("invalid set type provided: " + setType)
+	}
+	return nil
+}
+
+// twoPSetNode serves the package level TwoPSet
+type twoPSetNode struct{}
+
+// Addition adds a value to the TwoPSet
+// writing it through to the Storage first
+// Values removed & purged stay removed, so
+// their Addition returns ErrPurged
+func (twoPSetNode) Addition(value string) error {
+	delta, err := twopset.Initialize().Addition(value)
+	if err != nil {
+		return err
+	}
+
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	// Checked with the storeMutex held so the
+	// value can not be purged before it is added
+	if Collector.Purged(value) {
+		return twopset.ErrPurged
+	}
+
+	if err := Storage.ApplyAdd(value); err != nil {
+		return err
+	}
+
+	applyDelta(delta)
+	pushDelta(delta)
+	return nil
+}
+
+// Removal removes a value from the TwoPSet
+// writing it through to the Storage first
+// In strict mode only values present can be removed
+// Values removed & purged are already removed
+func (twoPSetNode) Removal(value string) error {
+	delta, err := twopset.Initialize().Removal(value)
+	if err != nil {
+		return err
+	}
+
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	if Collector.Purged(value) {
+		if GetStrictMode() {
+			return twopset.ErrAlreadyRemoved
+		}
+		return nil
+	}
+
+	if GetStrictMode() {
+		if err := TwoPSet.CanRemove(value); err != nil {
+			return err
+		}
+	}
+
+	if err := Storage.ApplyRemove(value); err != nil {
+		return err
+	}
+
+	applyDelta(delta)
+	pushDelta(delta)
+
+	// Track the Removal so its tombstone
+	// can be purged once stable
+	_, err = Collector.Removal(value)
+	return err
+}
+
+func (twoPSetNode) Lookup(value string) (bool, error) {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	return TwoPSet.Lookup(value)
+}
+
+func (twoPSetNode) List() []string {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+	return TwoPSet.List()
+}
+
+// Values returns a copy of the TwoPSet as it
+// is encoded after the storeMutex is released
+func (twoPSetNode) Values() interface{} {
+	return copyState()
+}
+
+// Sync merges a copy of the TwoPSet with the peers
+// and joins the result back into the TwoPSet, so the
+// changes made while syncing are kept
+func (twoPSetNode) Sync(ctx context.Context, peers []string) (SyncReport, error) {
+	set, report, err := Sync(ctx, copyState().TwoPSet, peers)
+	if _, _, storeErr := mergeTwoPSet(set); storeErr != nil {
+		return report, storeErr
+	}
+	return report, err
+}
+
+// applyDelta joins a delta into the TwoPSet
+// keeping its Merkle tree up to date
+func applyDelta(delta twopset.TwoPSet) {
+	Tree.Apply(TwoPSet, delta)
+	TwoPSet = twopset.MergeDelta(TwoPSet, delta)
+}
+
+// setTwoPSet replaces the TwoPSet
+// rebuilding its Merkle tree
+func setTwoPSet(set twopset.TwoPSet) {
+	TwoPSet = set
+	Tree = twopset.NewMerkleTree(TwoPSet)
+}
+
+// orSetNode serves an ORSet
+type orSetNode struct {
+	mutex sync.Mutex
+	set   orset.ORSet
+}
+
+func (node *orSetNode) Addition(value string) error {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	var err error
+	node.set, err = node.set.Addition(value)
+	return err
+}
+
+func (node *orSetNode) Removal(value string) error {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	var err error
+	node.set, err = node.set.Removal(value)
+	return err
+}
+
+func (node *orSetNode) Lookup(value string) (bool, error) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	return node.set.Lookup(value)
+}
+
+func (node *orSetNode) List() []string {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	return node.set.List()
+}
+
+func (node *orSetNode) Values() interface{} {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	return node.set.Copy()
+}
+
+// Sync merges the ORSets obtained
+// from each of the given peers
+func (node *orSetNode) Sync(ctx context.Context, peers []string) (SyncReport, error) {
+	if len(peers) == 0 {
+		return SyncReport{}, errors.New("nil peers present")
+	}
+
+	node.mutex.Lock()
+	merged := node.set.Copy()
+	node.mutex.Unlock()
+
+	states, report := syncPeers(ctx, peers, func(ctx context.Context, peer string) (interface{}, error) {
+		var peerORSet orset.ORSet
+		err := SendValuesRequest(ctx, peer, &peerORSet)
+		return peerORSet, err
+	})
+
+	for index, result := range report.Peers {
+		if result.Status != SyncOK {
+			logSyncFailure(result, "failed sending orset values request")
+			continue
+		}
+
+		merged = orset.Merge(merged, states[index].(orset.ORSet))
+	}
+
+	node.mutex.Lock()
+	node.set = orset.Merge(node.set, merged)
+	node.mutex.Unlock()
+
+	log.WithFields(log.Fields{
+		"set": merged,
+	}).Debug("successful orset sync")
+
+	return report, nil
+}
+
+// lwwSetNode serves an LWWSet
+type lwwSetNode struct {
+	mutex sync.Mutex
+	set   lwwset.LWWSet
+}
+
+func (node *lwwSetNode) Addition(value string) error {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	var err error
+	node.set, err = node.set.Addition(value)
+	return err
+}
+
+func (node *lwwSetNode) Removal(value string) error {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+
+	var err error
+	node.set, err = node.set.Removal(value)
+	return err
+}
+
+func (node *lwwSetNode) Lookup(value string) (bool, error) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	return node.set.Lookup(value)
+}
+
+func (node *lwwSetNode) List() []string {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	return node.set.List()
+}
+
+func (node *lwwSetNode) Values() interface{} {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	return node.set.Copy()
+}
+
+// Sync merges the LWWSets obtained
+// from each of the given peers
+func (node *lwwSetNode) Sync(ctx context.Context, peers []string) (SyncReport, error) {
+	if len(peers) == 0 {
+		return SyncReport{}, errors.New("nil peers present")
+	}
+
+	node.mutex.Lock()
+	merged := node.set.Copy()
+	node.mutex.Unlock()
+
+	states, report := syncPeers(ctx, peers, func(ctx context.Context, peer string) (interface{}, error) {
+		var peerLWWSet lwwset.LWWSet
+		err := SendValuesRequest(ctx, peer, &peerLWWSet)
+		return peerLWWSet, err
+	})
+
+	for index, result := range report.Peers {
+		if result.Status != SyncOK {
+			logSyncFailure(result, "failed sending lwwset values request")
+			continue
+		}
+
+		// The local LWWSet is passed first
+		// to keep its bias & clock
+		merged = lwwset.Merge(merged, states[index].(lwwset.LWWSet))
+	}
+
+	node.mutex.Lock()
+	node.set = lwwset.Merge(node.set, merged)
+	node.mutex.Unlock()
+
+	log.WithFields(log.Fields{
+		"set": merged,
+	}).Debug("successful lwwset sync")
+
+	return report, nil
+}
+
+// compile time check that the
+// set adapters implement Set
+var (
+	_ Set = twoPSetNode{}
+	_ Set = &orSetNode{}
+	_ Set = &lwwSetNode{}
+)
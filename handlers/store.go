@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/store"
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+const (
+	// DefaultSnapshotInterval is the default
+	// interval between snapshots of the TwoPSet
+	DefaultSnapshotInterval = 5 * time.Minute
+)
+
+var (
+	// Storage is the Store the changes to
+	// the TwoPSet are written through to
+	Storage store.Store = store.NewMemoryStore()
+
+	// storeMutex guards the TwoPSet & its Merkle tree and
+	// serializes the changes to them with the writes to the
+	// Storage so a snapshot holds every change applied before it
+	// As the TwoPSet is synced in the background while requests
+	// are served, every reader holds it or reads from copyState()
+	storeMutex sync.Mutex
+)
+
+// UseStore selects the Store the TwoPSet is written
+// through to and loads the TwoPSet stored in it
+func UseStore(backend store.Store) error {
+	state, err := backend.Load()
+	if err != nil {
+		return err
+	}
+
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	if state.GC != nil {
+		if err := Collector.Restore(*state.GC); err != nil {
+			return err
+		}
+	}
+
+	// Track the Removals since the last snapshot
+	// so their tombstones can be purged once stable
+	for _, value := range state.Removals {
+		if _, err := Collector.Removal(value); err != nil {
+			return err
+		}
+	}
+
+	setTwoPSet(state.TwoPSet)
+	Storage = backend
+
+	return nil
+}
+
+// Snapshot writes a snapshot of the TwoPSet, its
+// tombstones & the values purged to the Storage
+func Snapshot() error {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	return Storage.Snapshot(TwoPSet, Collector.Metadata())
+}
+
+// StartSnapshots takes a snapshot of the
+// TwoPSet every interval in the background
+// A non positive interval disables snapshots
+func StartSnapshots(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			if err := Snapshot(); err != nil {
+				log.WithFields(log.Fields{"error": err}).Error("failed to snapshot twopset")
+			}
+		}
+	}()
+}
+
+// copyState returns a copy of the TwoPSet along with its
+// garbage collection Metadata taken with the storeMutex held,
+// so it can be read once the storeMutex is released
+func copyState() State {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	metadata := Collector.Metadata()
+	return State{TwoPSet: TwoPSet.Copy(), GC: &metadata}
+}
+
+// mergeTwoPSet joins the TwoPSet obtained by merging with
+// peers into the TwoPSet, keeping the changes made while
+// syncing, and writes it through to the Storage
+// It returns the delta joined & the Merkle root of the result
+func mergeTwoPSet(set twopset.TwoPSet) (twopset.TwoPSet, twopset.Hash, error) {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	return joinTwoPSet(set)
+}
+
+// mergeState merges a State pushed by a peer into the TwoPSet
+// through the Collector, so the values purged locally stay
+// removed & the Dots of its tombstones are tracked, and
+// writes it through to the Storage
+// It returns the delta joined & the Merkle root of the result
+func mergeState(state State) (twopset.TwoPSet, twopset.Hash, error) {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	return joinTwoPSet(Collector.Merge(TwoPSet, state.TwoPSet, state.GC))
+}
+
+// joinTwoPSet joins the additions & tombstones of the set
+// missing in the TwoPSet into it and writes it through
+// to the Storage. It must be called with the storeMutex held
+func joinTwoPSet(set twopset.TwoPSet) (twopset.TwoPSet, twopset.Hash, error) {
+	delta := twopset.Diff(TwoPSet, set)
+	if delta.IsEmpty() {
+		return delta, Tree.Root(), nil
+	}
+
+	applyDelta(delta)
+	return delta, Tree.Root(), Storage.ApplyMerged(delta)
+}
+
+// collectTwoPSet purges the TwoPSet tombstones that are causally
+// stable across the given nodes and writes it through to the Storage
+func collectTwoPSet(nodes []string) (twopset.Report, error) {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	set, report := Collector.Collect(TwoPSet, nodes)
+	if report.Purged == 0 {
+		return report, nil
+	}
+
+	setTwoPSet(set)
+	return report, Storage.ApplyPurged(set)
+}
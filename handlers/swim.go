@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
+)
+
+// maxSWIMMessageBytes is the maximum
+// size of a SWIM message received
+const maxSWIMMessageBytes = 1 << 20
+
+var (
+	// Detector is the SWIM failure detector agreeing on
+	// the members alive with the peers. It is nil when
+	// failure detection is disabled
+	Detector *cluster.SWIM
+)
+
+// httpTransport sends the SWIM messages
+// to the peers with a POST /cluster/swim
+type httpTransport struct{}
+
+// Send sends the SWIM message to the
+// node and returns its reply
+func (httpTransport) Send(ctx context.Context, node string, message cluster.Message) (cluster.Message, error) {
+	var reply cluster.Message
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return reply, err
+	}
+
+	url := fmt.Sprintf("http://%s/cluster/swim", GetPeerAddress(node))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return reply, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := DoRequest(request)
+	if err != nil {
+		return reply, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return reply, StatusError{StatusCode: response.StatusCode}
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&reply); err != nil {
+		return cluster.Message{}, DecodeError{Err: err}
+	}
+	return reply, nil
+}
+
+// StartSWIM detects the failed members of the cluster using
+// SWIM, running a protocol period every interval in the
+// background. The members suspected or dead are not synced
+// with or pushed to until they are alive again
+func StartSWIM(interval time.Duration, config cluster.SWIMConfig) {
+	if interval <= 0 {
+		return
+	}
+
+	Detector = cluster.NewSWIM(Members.Self(), httpTransport{}, config, nil)
+	Detector.SetMembers(Members.Members())
+
+	go func() {
+		alive := Detector.Alive()
+
+		for range time.Tick(interval) {
+			Detector.SetMembers(Members.Members())
+			Detector.Probe(context.Background())
+
+			// INFO log the members alive
+			// whenever they change
+			if current := Detector.Alive(); !equalStrings(alive, current) {
+				log.WithFields(log.Fields{
+					"alive":   current,
+					"members": Detector.Members(),
+				}).Info("cluster members alive changed")
+				alive = current
+			}
+		}
+	}()
+}
+
+// alivePeers returns the peers passed
+// not suspected or dead by the Detector
+func alivePeers(peers []string) []string {
+	if Detector == nil {
+		return peers
+	}
+
+	failed := map[string]struct{}{}
+	for _, member := range Detector.Members() {
+		if member.State != cluster.StateAlive {
+			failed[member.Node] = struct{}{}
+		}
+	}
+
+	alive := []string{}
+	for _, peer := range peers {
+		if _, present := failed[peer]; !present {
+			alive = append(alive, peer)
+		}
+	}
+	return alive
+}
+
+// equalStrings returns true if the
+// slices have the same strings in order
+func equalStrings(first []string, second []string) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	for index := range first {
+		if first[index] != second[index] {
+			return false
+		}
+	}
+	return true
+}
+
+// SWIM is the HTTP handler used to handle
+// the SWIM messages sent by the peers
+func SWIM(w http.ResponseWriter, r *http.Request) {
+	if Detector == nil {
+		http.Error(w, "failure detection is disabled", http.StatusNotImplemented)
+		return
+	}
+
+	var message cluster.Message
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSWIMMessageBytes)).Decode(&message); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reply, err := Detector.Handle(r.Context(), message)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reply)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
+)
+
+// postSWIM sends the body to the SWIM endpoint and returns
+// the response recorded along with the reply decoded
+func postSWIM(body []byte) (*httptest.ResponseRecorder, cluster.Message) {
+	request := httptest.NewRequest(http.MethodPost, "/cluster/swim", bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	Router().ServeHTTP(recorder, request)
+
+	var reply cluster.Message
+	json.NewDecoder(bytes.NewReader(recorder.Body.Bytes())).Decode(&reply)
+	return recorder, reply
+}
+
+// TestSWIM checks the basic functionality of the SWIM handler
+// pings should be acknowledged, ping_reqs should be acknowledged
+// only if the target replied to the ping sent over HTTP and
+// invalid messages should return HTTP 400 Bad Request
+func TestSWIM(t *testing.T) {
+	Detector = cluster.NewSWIM(Members.Self(), httpTransport{}, cluster.DefaultSWIMConfig(), nil)
+	defer func() { Detector = nil }()
+
+	target, closeTarget := newPeer(Router())
+	defer closeTarget()
+
+	body, _ := json.Marshal(cluster.Message{Type: cluster.MessagePing, From: "peer-1"})
+	recorder, reply := postSWIM(body)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, cluster.MessageAck, reply.Type)
+	assert.Equal(t, Members.Self(), reply.From)
+
+	body, _ = json.Marshal(cluster.Message{Type: cluster.MessagePingReq, From: "peer-1", Target: target})
+	recorder, reply = postSWIM(body)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, cluster.MessageAck, reply.Type)
+	assert.Equal(t, target, reply.Target)
+
+	// Nothing listens on the target
+	body, _ = json.Marshal(cluster.Message{Type: cluster.MessagePingReq, From: "peer-1", Target: "127.0.0.1:1"})
+	recorder, reply = postSWIM(body)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, cluster.MessageNack, reply.Type)
+
+	body, _ = json.Marshal(cluster.Message{Type: cluster.MessagePingReq, From: "peer-1"})
+	recorder, _ = postSWIM(body)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder, _ = postSWIM([]byte("xx"))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// TestSWIM_Disabled checks the functionality of the SWIM handler
+// when failure detection is disabled, it should return
+// HTTP 501 Not Implemented
+func TestSWIM_Disabled(t *testing.T) {
+	body, _ := json.Marshal(cluster.Message{Type: cluster.MessagePing, From: "peer-1"})
+	recorder, _ := postSWIM(body)
+	assert.Equal(t, http.StatusNotImplemented, recorder.Code)
+}
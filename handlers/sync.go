@@ -1,83 +1,445 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/el10savio/twoPSet-crdt/twopset"
 )
 
-// Sync merges multiple TwoPSet present in a network to get them in sync
-// It does so by obtaining the TwoPSet from each node in the cluster
-// and performs a merge operation with the local TwoPSet
-func Sync(TwoPSet twopset.TwoPSet) (twopset.TwoPSet, error) {
-	// Obtain addresses of peer nodes in the cluster
-	peers := GetPeerList()
+// State is the TwoPSet served at /twopset/values along
+// with its tombstone garbage collection metadata
+// Peers not aware of the metadata ignore it
+type State struct {
+	twopset.TwoPSet
+	GC *twopset.Metadata `json:"gc,omitempty"`
+}
+
+const (
+	// SyncMerkle only obtains the Merkle
+	// tree buckets that differ from each peer
+	SyncMerkle = "merkle"
+	// SyncIBLT only obtains the additions & tombstones
+	// that differ from each peer using IBLTs
+	SyncIBLT = "iblt"
+	// SyncFull obtains the full
+	// TwoPSet from each peer
+	SyncFull = "full"
+
+	// DefaultSyncWorkers is the default number
+	// of peers synced with concurrently
+	DefaultSyncWorkers = 8
+	// DefaultSyncPeerTimeout is the default
+	// deadline for syncing with each peer
+	DefaultSyncPeerTimeout = 10 * time.Second
+)
+
+// SyncStatus is the outcome of syncing with a peer
+type SyncStatus string
+
+const (
+	// SyncOK is reported when the peer's
+	// state was obtained & merged
+	SyncOK SyncStatus = "ok"
+	// SyncTimeout is reported when the peer did
+	// not reply within the per peer deadline
+	SyncTimeout SyncStatus = "timeout"
+	// SyncBadStatus is reported when the peer replied
+	// with an unexpected HTTP response status
+	SyncBadStatus SyncStatus = "bad_status"
+	// SyncDecodeError is reported when the
+	// peer's reply could not be decoded
+	SyncDecodeError SyncStatus = "decode_error"
+	// SyncCanceled is reported when the sync was
+	// canceled before the peer's state was obtained
+	SyncCanceled SyncStatus = "canceled"
+	// SyncCircuitOpen is reported when the peer was skipped
+	// as its circuit is open after repeated failures
+	SyncCircuitOpen SyncStatus = "circuit_open"
+	// SyncFailed is reported for any other error
+	// such as the peer being unreachable
+	SyncFailed SyncStatus = "error"
+)
+
+// PeerResult is the JSON struct
+// encapsulating the outcome of syncing with a peer
+type PeerResult struct {
+	Peer   string     `json:"peer"`
+	Status SyncStatus `json:"status"`
+	Error  string     `json:"error,omitempty"`
+	// Duration is the time taken
+	// to obtain the peer's state
+	Duration time.Duration `json:"duration"`
+}
 
+// SyncReport is the JSON struct
+// encapsulating the outcome of a Sync
+type SyncReport struct {
+	Peers []PeerResult `json:"peers"`
+}
+
+// OK returns the number of
+// peers synced successfully
+func (report SyncReport) OK() int {
+	ok := 0
+	for _, result := range report.Peers {
+		if result.Status == SyncOK {
+			ok++
+		}
+	}
+	return ok
+}
+
+// SyncConfig configures how peers are synced with
+type SyncConfig struct {
+	// Workers is the number of
+	// peers synced with concurrently
+	Workers int
+	// PeerTimeout is the deadline
+	// for syncing with each peer
+	PeerTimeout time.Duration
+}
+
+// Sync merges multiple TwoPSet present in a network to get them in sync
+// It does so by obtaining the TwoPSet from each of the given peers
+// concurrently and performs a merge operation with the local TwoPSet
+// The parts of the TwoPSet obtained from each peer depend on the
+// strategy from GetSyncStrategy(), SyncMerkle by default
+// The peers not synced once the context is done are reported canceled
+func Sync(ctx context.Context, TwoPSet twopset.TwoPSet, peers []string) (twopset.TwoPSet, SyncReport, error) {
 	// Return the local TwoPSet back if no peers
 	// are present along with an error
 	if len(peers) == 0 {
-		return TwoPSet, errors.New("nil peers present")
+		return TwoPSet, SyncReport{}, errors.New("nil peers present")
 	}
 
-	// Iterate over the peer list and send a /twopset/values GET request
-	// to each peer to obtain its TwoPSet
-	for _, peer := range peers {
-		peerTwoPSet, err := SendListRequest(peer)
-		if err != nil {
-			log.WithFields(log.Fields{"error": err, "peer": peer}).Error("failed sending twopset values request")
+	strategy := GetSyncStrategy()
+	switch strategy {
+	case "":
+		strategy = SyncMerkle
+	case SyncMerkle, SyncIBLT, SyncFull:
+	default:
+		return TwoPSet, SyncReport{}, errors.New("invalid sync strategy provided: " + strategy)
+	}
+
+	// Merkle tree of the local TwoPSet walked
+	// with each peer to find the buckets that differ
+	// It is only read while the peers are synced
+	tree := twopset.NewMerkleTree(TwoPSet)
+
+	// Obtain the parts of each peer's TwoPSet that differ
+	states, report := syncPeers(ctx, peers, func(ctx context.Context, peer string) (interface{}, error) {
+		switch strategy {
+		case SyncIBLT:
+			return SendIBLTSyncRequest(ctx, peer, TwoPSet)
+		case SyncFull:
+			return SendStateRequest(ctx, peer)
+		default:
+			return SendMerkleSyncRequest(ctx, peer, tree)
+		}
+	})
+
+	// Merge each peer's TwoPSet with our local TwoPSet once
+	// every peer has been synced, as the local TwoPSet is
+	// read while syncing & can not be merged into concurrently
+	for index, result := range report.Peers {
+		if result.Status != SyncOK {
+			logSyncFailure(result, "failed sending twopset values request")
 			continue
 		}
+		peerState := states[index].(State)
+
+		// DEBUG log the additions & tombstones the
+		// peer has that the local TwoPSet is missing
+		log.WithFields(log.Fields{
+			"peer":    result.Peer,
+			"missing": twopset.Diff(TwoPSet, peerState.TwoPSet),
+		}).Debug("received twopset from peer")
 
 		// Merge the peer's TwoPSet with our local TwoPSet
-		TwoPSet = twopset.Merge(TwoPSet, peerTwoPSet)
+		// dropping the tombstones already purged locally
+		TwoPSet = Collector.Merge(TwoPSet, peerState.TwoPSet, peerState.GC)
 	}
 
 	// DEBUG log in the case of success
 	// indicating the new TwoPSet
 	log.WithFields(log.Fields{
-		"set": TwoPSet,
+		"set":    TwoPSet,
+		"report": report,
 	}).Debug("successful twopset sync")
 
 	// Return the synced new TwoPSet
-	return TwoPSet, nil
+	return TwoPSet, report, nil
+}
+
+// syncConfig configures how peers are synced with
+var syncConfig = SyncConfig{
+	Workers:     DefaultSyncWorkers,
+	PeerTimeout: DefaultSyncPeerTimeout,
+}
+
+// UseSyncConfig sets how peers are synced
+// with, such as from GetSyncConfig()
+func UseSyncConfig(config SyncConfig) {
+	syncConfig = config
+}
+
+// syncPeers obtains the state of each peer using the fetch
+// function concurrently on a bounded pool of workers, with a
+// deadline for each peer, and returns the states obtained
+// along with the outcome for each peer in the same order
+// Under a context from withQuorum() it returns once enough
+// peers were synced with, reporting the others as canceled
+func syncPeers(ctx context.Context, peers []string, fetch func(context.Context, string) (interface{}, error)) ([]interface{}, SyncReport) {
+	config := syncConfig
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	quorum, _ := ctx.Value(quorumKey{}).(int)
+	var synced int32
+
+	workers := config.Workers
+	if workers > len(peers) {
+		workers = len(peers)
+	}
+
+	states := make([]interface{}, len(peers))
+	report := SyncReport{Peers: make([]PeerResult, len(peers))}
+
+	jobs := make(chan int)
+	var wait sync.WaitGroup
+
+	for worker := 0; worker < workers; worker++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for index := range jobs {
+				states[index], report.Peers[index] = syncPeer(ctx, peers[index], config.PeerTimeout, fetch)
+				if report.Peers[index].Status == SyncOK && quorum > 0 && int(atomic.AddInt32(&synced, 1)) >= quorum {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for index := range peers {
+		jobs <- index
+	}
+	close(jobs)
+	wait.Wait()
+
+	return states, report
+}
+
+// syncPeer obtains the state of the peer using
+// the fetch function within the deadline passed
+func syncPeer(ctx context.Context, peer string, timeout time.Duration, fetch func(context.Context, string) (interface{}, error)) (interface{}, PeerResult) {
+	result := PeerResult{Peer: peer}
+
+	// Skip the peers not started
+	// before the sync was canceled
+	if ctx.Err() != nil {
+		result.Status = SyncCanceled
+		result.Error = ctx.Err().Error()
+		return nil, result
+	}
+
+	// Skip the peers failing repeatedly
+	// until their circuit is probed
+	if !Health.Allow(peer) {
+		result.Status = SyncCircuitOpen
+		return nil, result
+	}
+
+	peerCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	state, err := fetch(peerCtx, peer)
+	result.Duration = time.Since(start)
+
+	result.Status = syncStatus(ctx, peerCtx, err)
+
+	// The health of the peer is unknown
+	// if the sync was canceled
+	if result.Status != SyncCanceled {
+		Health.Record(peer, result.Duration, err)
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		return nil, result
+	}
+
+	return state, result
+}
+
+// logSyncFailure logs the failure to sync with a peer
+// The peers skipped as their circuit is open or canceled
+// once a quorum was synced with are only logged at DEBUG
+// to not log them on every sync
+func logSyncFailure(result PeerResult, message string) {
+	entry := log.WithFields(log.Fields{"error": result.Error, "peer": result.Peer, "status": result.Status})
+	if result.Status == SyncCircuitOpen || result.Status == SyncCanceled {
+		entry.Debug(message)
+		return
+	}
+	entry.Error(message)
+}
+
+// syncStatus classifies the error syncing with a peer
+// The context errors take precedence as a response
+// cut short by them fails decoding
+func syncStatus(ctx context.Context, peerCtx context.Context, err error) SyncStatus {
+	var statusError StatusError
+	var decodeError DecodeError
+	var netError net.Error
+
+	switch {
+	case err == nil:
+		return SyncOK
+	case ctx.Err() == context.Canceled:
+		return SyncCanceled
+	case peerCtx.Err() == context.DeadlineExceeded:
+		return SyncTimeout
+	case errors.As(err, &netError) && netError.Timeout():
+		return SyncTimeout
+	case errors.As(err, &statusError):
+		return SyncBadStatus
+	case errors.As(err, &decodeError):
+		return SyncDecodeError
+	default:
+		return SyncFailed
+	}
 }
 
 // SendListRequest is used to send a GET /twopset/values
 // to peer nodes in the cluster
-func SendListRequest(peer string) (twopset.TwoPSet, error) {
+func SendListRequest(ctx context.Context, peer string) (twopset.TwoPSet, error) {
 	var _twopset twopset.TwoPSet
 
-	// Return an empty TwoPSet followed by an error if the peer is nil
+	// Decode the peer's TwoPSet to be usable by our local TwoPSet
+	state, err := SendStateRequest(ctx, peer)
+	if err != nil {
+		return _twopset, err
+	}
+
+	// Return the decoded peer's TwoPSet
+	_twopset = state.TwoPSet
+	return _twopset, nil
+}
+
+// SendStateRequest is used to send a GET /twopset/values
+// to peer nodes in the cluster returning the peer's
+// TwoPSet along with its garbage collection metadata
+// The binary encoding is requested and used if the peer
+// supports it, falling back to JSON otherwise
+func SendStateRequest(ctx context.Context, peer string) (State, error) {
+	var state State
+
+	// Return an empty State followed by an error if the peer is nil
 	if peer == "" {
-		return _twopset, errors.New("empty peer provided")
+		return state, errors.New("empty peer provided")
 	}
 
 	// Resolve the Peer ID and network to generate the request URL
-	url := fmt.Sprintf("http://%s.%s/twopset/values", peer, GetNetwork())
-	response, err := SendRequest(url)
+	url := fmt.Sprintf("http://%s/twopset/values", GetPeerAddress(peer))
+	return sendStateRequest(ctx, url)
+}
+
+// sendStateRequest is used to send a GET request
+// for a State to the URL of a peer node
+func sendStateRequest(ctx context.Context, url string) (State, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return _twopset, err
+		return State{}, err
+	}
+
+	return doStateRequest(request)
+}
+
+// doStateRequest sends the request for a State to a peer node
+// negotiating the binary encoding and decoding the State
+func doStateRequest(request *http.Request) (State, error) {
+	var state State
+
+	request.Header.Set("Accept", twopset.StateMediaType+", "+twopset.MediaType+";q=0.9, application/json;q=0.8")
+	response, err := DoRequest(request)
+	if err != nil {
+		return state, err
 	}
+	defer response.Body.Close()
 
-	// Return an empty TwoPSet followed by an error
+	// Return an empty State followed by an error
 	// if the peer's response is not HTTP 200 OK
 	if response.StatusCode != http.StatusOK {
-		return _twopset, errors.New("received invalid http response status:" + fmt.Sprint(response.StatusCode))
+		return state, StatusError{StatusCode: response.StatusCode}
 	}
 
-	// Decode the peer's TwoPSet to be usable by our local TwoPSet
-	var twoPSet twopset.TwoPSet
-	err = json.NewDecoder(response.Body).Decode(&twoPSet)
+	// Decode the peer's State based on the encoding it replied with
+	// Peers replying with the TwoPSet alone send no Metadata
+	contentType := response.Header.Get("Content-Type")
+	if hasContentType(contentType, twopset.StateMediaType) || hasContentType(contentType, twopset.MediaType) {
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return State{}, err
+		}
+		if hasContentType(contentType, twopset.StateMediaType) {
+			err = state.UnmarshalBinary(body)
+		} else {
+			err = state.TwoPSet.UnmarshalBinary(body)
+		}
+		if err != nil {
+			return State{}, DecodeError{Err: err}
+		}
+		return state, nil
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&state)
 	if err != nil {
-		return _twopset, err
+		return State{}, DecodeError{Err: err}
 	}
 
-	// Return the decoded peer's TwoPSet
-	_twopset = twoPSet
-	return _twopset, nil
+	return state, nil
+}
+
+// SendValuesRequest is used to send a GET /twopset/values
+// to peer nodes in the cluster and JSON decode the
+// peer's set into the value passed
+func SendValuesRequest(ctx context.Context, peer string, value interface{}) error {
+	// Return an error if the peer is nil
+	if peer == "" {
+		return errors.New("empty peer provided")
+	}
+
+	// Resolve the Peer ID and network to generate the request URL
+	url := fmt.Sprintf("http://%s/twopset/values", GetPeerAddress(peer))
+	response, err := SendRequest(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	// Return an error if the peer's
+	// response is not HTTP 200 OK
+	if response.StatusCode != http.StatusOK {
+		return StatusError{StatusCode: response.StatusCode}
+	}
+
+	// Decode the peer's set
+	if err := json.NewDecoder(response.Body).Decode(value); err != nil {
+		return DecodeError{Err: err}
+	}
+	return nil
 }
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// useTwoPSet replaces the TwoPSet served by
+// the node along with its Collector
+func useTwoPSet(set twopset.TwoPSet) {
+	storeMutex.Lock()
+	defer storeMutex.Unlock()
+
+	Collector, _ = twopset.NewReplicaCollector(GetNodeID(), replicaID())
+	setTwoPSet(set)
+}
+
+// newPeer serves the handler over HTTP and returns
+// its host:port address used as the peer along
+// with a function shutting it down
+func newPeer(handler http.Handler) (string, func()) {
+	server := httptest.NewServer(handler)
+	return strings.TrimPrefix(server.URL, "http://"), server.Close
+}
+
+// setEnv sets the environment variable and
+// returns a function restoring its value
+func setEnv(key string, value string) func() {
+	previous, present := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if present {
+			os.Setenv(key, previous)
+			return
+		}
+		os.Unsetenv(key)
+	}
+}
+
+// without returns the handler replying
+// 404 Not Found to the paths passed
+func without(handler http.Handler, paths ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range paths {
+			if r.URL.Path == path {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// syncFixture returns the TwoPSet served by the peer,
+// the local TwoPSet synced with it & their merge
+func syncFixture() (twopset.TwoPSet, twopset.TwoPSet, twopset.TwoPSet) {
+	remote := twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy", "zz"), Remove: twopset.NewGSet("xx")}
+	local := twopset.TwoPSet{Add: twopset.NewGSet("yy", "ww"), Remove: twopset.NewGSet("ww")}
+	return remote, local, twopset.Merge(local, remote)
+}
+
+// TestSync_Strategies checks the basic functionality of Sync()
+// every sync strategy should merge the peer's TwoPSet
+func TestSync_Strategies(t *testing.T) {
+	remote, local, expectedValue := syncFixture()
+	useTwoPSet(remote)
+
+	peer, closePeer := newPeer(Router())
+	defer closePeer()
+
+	for _, strategy := range []string{SyncMerkle, SyncIBLT, SyncFull} {
+		restore := setEnv("SYNC_STRATEGY", strategy)
+		actualValue, report, actualError := Sync(context.Background(), local.Copy(), []string{peer})
+		restore()
+
+		assert.Nil(t, actualError, strategy)
+		assert.Equal(t, 1, report.OK(), strategy)
+		assert.Equal(t, expectedValue, actualValue, strategy)
+	}
+}
+
+// TestSync_Unsupported checks the functionality of Sync() when the peer
+// serves neither Merkle trees nor IBLTs, the Merkle & IBLT strategies
+// should fall back to merging the peer's full TwoPSet
+func TestSync_Unsupported(t *testing.T) {
+	remote, local, expectedValue := syncFixture()
+	useTwoPSet(remote)
+
+	peer, closePeer := newPeer(without(Router(), "/twopset/merkle", "/twopset/buckets", "/twopset/iblt"))
+	defer closePeer()
+
+	for _, strategy := range []string{SyncMerkle, SyncIBLT} {
+		restore := setEnv("SYNC_STRATEGY", strategy)
+		actualValue, report, actualError := Sync(context.Background(), local.Copy(), []string{peer})
+		restore()
+
+		assert.Nil(t, actualError, strategy)
+		assert.Equal(t, 1, report.OK(), strategy)
+		assert.Equal(t, expectedValue, actualValue, strategy)
+	}
+}
+
+// TestSync_IBLTTooLarge checks the functionality of Sync() when the
+// difference with the peer can not be decoded from IBLTs, the IBLT
+// strategy should fall back to merging the peer's full TwoPSet
+func TestSync_IBLTTooLarge(t *testing.T) {
+	remote, local, expectedValue := syncFixture()
+	useTwoPSet(remote)
+
+	// Every cell holds several keys
+	// so no IBLT can be decoded
+	router := Router()
+	peer, closePeer := newPeer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/twopset/iblt" {
+			router.ServeHTTP(w, r)
+			return
+		}
+
+		var cells int
+		fmt.Sscan(r.URL.Query().Get("cells"), &cells)
+		iblt, _ := twopset.NewIBLT(cells)
+		for index := range iblt.Cells {
+			iblt.Cells[index] = twopset.Cell{Count: 2, KeySum: uint64(index + 1)}
+		}
+		json.NewEncoder(w).Encode(IBLTResponse{IBLT: iblt})
+	}))
+	defer closePeer()
+
+	defer setEnv("SYNC_STRATEGY", SyncIBLT)()
+
+	actualValue, report, actualError := Sync(context.Background(), local.Copy(), []string{peer})
+	assert.Nil(t, actualError)
+	assert.Equal(t, 1, report.OK())
+	assert.Equal(t, expectedValue, actualValue)
+}
+
+// TestSync_ConcurrentWrites checks the functionality of Sync() while
+// the peer's TwoPSet is written to, the Merkle tree, buckets, IBLTs &
+// elements should be served from consistent copies of it
+// It is meant to be run with the race detector
+func TestSync_ConcurrentWrites(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+
+	peer, closePeer := newPeer(Router())
+	defer closePeer()
+
+	var wait sync.WaitGroup
+	done := make(chan struct{})
+
+	wait.Add(1)
+	go func() {
+		defer wait.Done()
+		defer close(done)
+		for index := 0; index < 1000; index++ {
+			Node.Addition(fmt.Sprintf("value-%d", index))
+			if index%3 == 0 {
+				Node.Removal(fmt.Sprintf("value-%d", index))
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	// Sync until every write is done
+	for synced := false; !synced; {
+		select {
+		case <-done:
+			synced = true
+		default:
+		}
+
+		for _, strategy := range []string{SyncMerkle, SyncIBLT} {
+			restore := setEnv("SYNC_STRATEGY", strategy)
+			_, report, actualError := Sync(context.Background(), twopset.Initialize(), []string{peer})
+			restore()
+
+			assert.Nil(t, actualError, strategy)
+			assert.Equal(t, 1, report.OK(), strategy)
+		}
+	}
+
+	wait.Wait()
+}
+
+// TestSync_Report checks the functionality of the SyncReport
+// the outcome of syncing with each peer should be reported
+// in order and only the peers synced with merged
+func TestSync_Report(t *testing.T) {
+	remote, local, expectedValue := syncFixture()
+	useTwoPSet(remote)
+
+	defer UseSyncConfig(syncConfig)
+	UseSyncConfig(SyncConfig{Workers: 2, PeerTimeout: 200 * time.Millisecond})
+
+	defer setEnv("SYNC_STRATEGY", SyncFull)()
+
+	ok, closeOK := newPeer(Router())
+	defer closeOK()
+
+	timeout, closeTimeout := newPeer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer closeTimeout()
+
+	badStatus, closeBadStatus := newPeer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "xx", http.StatusInternalServerError)
+	}))
+	defer closeBadStatus()
+
+	decodeError, closeDecodeError := newPeer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{xx"))
+	}))
+	defer closeDecodeError()
+
+	peers := []string{ok, timeout, badStatus, decodeError}
+	actualValue, report, actualError := Sync(context.Background(), local.Copy(), peers)
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, actualValue)
+
+	expectedStatus := []SyncStatus{SyncOK, SyncTimeout, SyncBadStatus, SyncDecodeError}
+	assert.Equal(t, len(peers), len(report.Peers))
+	for index, result := range report.Peers {
+		assert.Equal(t, peers[index], result.Peer)
+		assert.Equal(t, expectedStatus[index], result.Status, result.Error)
+	}
+	assert.Equal(t, 1, report.OK())
+}
+
+// TestGetSyncConfig checks the basic functionality of GetSyncConfig()
+// invalid values should return an error instead of the defaults
+func TestGetSyncConfig(t *testing.T) {
+	defer setEnv("SYNC_WORKERS", "4")()
+	defer setEnv("SYNC_PEER_TIMEOUT", "2s")()
+
+	actualValue, actualError := GetSyncConfig()
+	assert.Nil(t, actualError)
+	assert.Equal(t, SyncConfig{Workers: 4, PeerTimeout: 2 * time.Second}, actualValue)
+
+	for _, env := range [][2]string{
+		{"SYNC_WORKERS", "xx"},
+		{"SYNC_WORKERS", "0"},
+		{"SYNC_PEER_TIMEOUT", "xx"},
+		{"SYNC_PEER_TIMEOUT", "-1s"},
+	} {
+		restore := setEnv(env[0], env[1])
+		_, actualError = GetSyncConfig()
+		restore()
+		assert.NotNil(t, actualError, env[0]+"="+env[1])
+	}
+}
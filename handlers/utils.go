@@ -1,39 +1,373 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/el10savio/twoPSet-crdt/cluster"
 )
 
-// GetPeerList Obtains Peer List
-// From Environment Variable
+// GetPeerList Obtains the Peer List From the Live
+// Cluster Membership, Without the Peers Suspected
+// or Dead by the SWIM Failure Detector
 func GetPeerList() []string {
+	return alivePeers(Members.Peers())
+}
+
+// GetSeedPeers Obtains the Seed Peer List the
+// Cluster Membership Starts From Environment Variable
+func GetSeedPeers() []string {
 	if os.Getenv("PEERS") == "" {
 		return []string{}
 	}
 	return strings.Split(os.Getenv("PEERS"), ",")
 }
 
+// GetSetType Obtains the CRDT Set Type
+// From Environment Variable
+func GetSetType() string {
+	return os.Getenv("SET_TYPE")
+}
+
+// GetLWWBias Obtains the LWWSet Bias
+// From Environment Variable
+func GetLWWBias() string {
+	return os.Getenv("LWW_BIAS")
+}
+
+// GetNodeID Obtains the Node ID From Environment
+// Variable, defaulting to the hostname
+func GetNodeID() string {
+	if os.Getenv("NODE") != "" {
+		return os.Getenv("NODE")
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "localhost"
+	}
+	return hostname
+}
+
+// GetStrictMode Obtains if TwoPSet Removals
+// are Strict From Environment Variable
+func GetStrictMode() bool {
+	return os.Getenv("STRICT") == "true"
+}
+
+// GetStoreType Obtains the TwoPSet
+// Store Type From Environment Variable
+func GetStoreType() string {
+	return os.Getenv("STORE")
+}
+
+// GetDataDir Obtains the Directory the
+// TwoPSet is Stored in From Environment Variable
+func GetDataDir() string {
+	return os.Getenv("DATA_DIR")
+}
+
+// GetSnapshotInterval Obtains the Interval Between
+// TwoPSet Snapshots From Environment Variable
+// It defaults to DefaultSnapshotInterval
+func GetSnapshotInterval() (time.Duration, error) {
+	if os.Getenv("SNAPSHOT_INTERVAL") == "" {
+		return DefaultSnapshotInterval, nil
+	}
+	return time.ParseDuration(os.Getenv("SNAPSHOT_INTERVAL"))
+}
+
+// GetGossipConfig Obtains the Gossip Interval, Fanout
+// & Jitter From Environment Variables, defaulting to
+// DefaultGossipInterval, DefaultGossipFanout & DefaultGossipJitter
+func GetGossipConfig() (GossipConfig, error) {
+	config := GossipConfig{
+		Interval: DefaultGossipInterval,
+		Fanout:   DefaultGossipFanout,
+		Jitter:   DefaultGossipJitter,
+	}
+
+	var err error
+	if os.Getenv("GOSSIP_INTERVAL") != "" {
+		if config.Interval, err = time.ParseDuration(os.Getenv("GOSSIP_INTERVAL")); err != nil {
+			return config, err
+		}
+	}
+	if os.Getenv("GOSSIP_FANOUT") != "" {
+		if config.Fanout, err = strconv.Atoi(os.Getenv("GOSSIP_FANOUT")); err != nil {
+			return config, err
+		}
+	}
+	if os.Getenv("GOSSIP_JITTER") != "" {
+		if config.Jitter, err = time.ParseDuration(os.Getenv("GOSSIP_JITTER")); err != nil {
+			return config, err
+		}
+	}
+
+	return config, nil
+}
+
+// GetPushConfig Obtains the Push Interval, Retries,
+// Backoff & Queue From Environment Variables, defaulting to
+// DefaultPushInterval, DefaultPushRetries, DefaultPushBackoff
+// & DefaultPushQueue
+func GetPushConfig() (PushConfig, error) {
+	config := PushConfig{
+		Interval: DefaultPushInterval,
+		Retries:  DefaultPushRetries,
+		Backoff:  DefaultPushBackoff,
+		Queue:    DefaultPushQueue,
+	}
+
+	var err error
+	if os.Getenv("PUSH_INTERVAL") != "" {
+		if config.Interval, err = time.ParseDuration(os.Getenv("PUSH_INTERVAL")); err != nil {
+			return config, err
+		}
+	}
+	if os.Getenv("PUSH_RETRIES") != "" {
+		if config.Retries, err = strconv.Atoi(os.Getenv("PUSH_RETRIES")); err != nil {
+			return config, err
+		}
+	}
+	if os.Getenv("PUSH_BACKOFF") != "" {
+		if config.Backoff, err = time.ParseDuration(os.Getenv("PUSH_BACKOFF")); err != nil {
+			return config, err
+		}
+	}
+	if os.Getenv("PUSH_QUEUE") != "" {
+		if config.Queue, err = strconv.Atoi(os.Getenv("PUSH_QUEUE")); err != nil {
+			return config, err
+		}
+		if config.Queue <= 0 {
+			return config, errors.New("invalid push queue provided")
+		}
+	}
+
+	return config, nil
+}
+
+// GetHealthConfig Obtains the Peer Failure Threshold,
+// Probe Backoff & Maximum Probe Backoff From Environment
+// Variables, defaulting to cluster.DefaultFailureThreshold,
+// cluster.DefaultProbeBackoff & cluster.DefaultMaxProbeBackoff
+func GetHealthConfig() (cluster.HealthConfig, error) {
+	config := cluster.DefaultHealthConfig()
+
+	var err error
+	if os.Getenv("HEALTH_FAILURE_THRESHOLD") != "" {
+		if config.Threshold, err = strconv.Atoi(os.Getenv("HEALTH_FAILURE_THRESHOLD")); err != nil {
+			return config, err
+		}
+	}
+	if os.Getenv("HEALTH_PROBE_BACKOFF") != "" {
+		if config.Backoff, err = time.ParseDuration(os.Getenv("HEALTH_PROBE_BACKOFF")); err != nil {
+			return config, err
+		}
+	}
+	if os.Getenv("HEALTH_MAX_PROBE_BACKOFF") != "" {
+		if config.MaxBackoff, err = time.ParseDuration(os.Getenv("HEALTH_MAX_PROBE_BACKOFF")); err != nil {
+			return config, err
+		}
+	}
+
+	return config, nil
+}
+
+// GetSWIMInterval Obtains the Interval Between SWIM
+// Protocol Periods From Environment Variable
+// It defaults to cluster.DefaultProbeInterval
+func GetSWIMInterval() (time.Duration, error) {
+	if os.Getenv("SWIM_INTERVAL") == "" {
+		return cluster.DefaultProbeInterval, nil
+	}
+	return time.ParseDuration(os.Getenv("SWIM_INTERVAL"))
+}
+
+// GetSWIMConfig Obtains the SWIM Probe Timeout, Indirect
+// Probes & Suspicion Timeout From Environment Variables,
+// defaulting to cluster.DefaultProbeTimeout,
+// cluster.DefaultIndirectProbes & cluster.DefaultSuspicionTimeout
+func GetSWIMConfig() (cluster.SWIMConfig, error) {
+	config := cluster.DefaultSWIMConfig()
+
+	var err error
+	if os.Getenv("SWIM_PROBE_TIMEOUT") != "" {
+		if config.ProbeTimeout, err = time.ParseDuration(os.Getenv("SWIM_PROBE_TIMEOUT")); err != nil {
+			return config, err
+		}
+	}
+	if os.Getenv("SWIM_INDIRECT_PROBES") != "" {
+		if config.IndirectProbes, err = strconv.Atoi(os.Getenv("SWIM_INDIRECT_PROBES")); err != nil {
+			return config, err
+		}
+	}
+	if os.Getenv("SWIM_SUSPICION_TIMEOUT") != "" {
+		if config.SuspicionTimeout, err = time.ParseDuration(os.Getenv("SWIM_SUSPICION_TIMEOUT")); err != nil {
+			return config, err
+		}
+	}
+
+	return config, nil
+}
+
+// GetDiscoveryConfig Obtains the Peer Discovery Providers,
+// Interval, Peers File, DNS Name & Port and Seeds From
+// Environment Variables, defaulting to DefaultDiscoveryInterval
+// & DefaultDiscoveryPort. DISCOVERY_DNS_PORT=srv looks up
+// the DNS SRV records of the name instead
+func GetDiscoveryConfig() (DiscoveryConfig, error) {
+	config := DiscoveryConfig{
+		Providers: []string{},
+		Interval:  DefaultDiscoveryInterval,
+		PeersFile: os.Getenv("PEERS_FILE"),
+		DNSName:   os.Getenv("DISCOVERY_DNS_NAME"),
+		DNSPort:   DefaultDiscoveryPort,
+		Seeds:     []string{},
+	}
+
+	if os.Getenv("DISCOVERY") != "" {
+		config.Providers = strings.Split(os.Getenv("DISCOVERY"), ",")
+	}
+	if os.Getenv("SEEDS") != "" {
+		config.Seeds = strings.Split(os.Getenv("SEEDS"), ",")
+	}
+
+	var err error
+	if os.Getenv("DISCOVERY_INTERVAL") != "" {
+		if config.Interval, err = time.ParseDuration(os.Getenv("DISCOVERY_INTERVAL")); err != nil {
+			return config, err
+		}
+	}
+	switch os.Getenv("DISCOVERY_DNS_PORT") {
+	case "":
+	case "srv":
+		config.DNSPort = 0
+	default:
+		if config.DNSPort, err = strconv.Atoi(os.Getenv("DISCOVERY_DNS_PORT")); err != nil {
+			return config, err
+		}
+		if config.DNSPort <= 0 || config.DNSPort > 65535 {
+			return config, errors.New("invalid discovery dns port provided")
+		}
+	}
+
+	return config, nil
+}
+
+// GetMergeMaxBytes Obtains the Maximum Size of a Merged
+// State From Environment Variable, defaulting to
+// DefaultMergeMaxBytes
+func GetMergeMaxBytes() (int64, error) {
+	if os.Getenv("MERGE_MAX_BYTES") == "" {
+		return DefaultMergeMaxBytes, nil
+	}
+
+	maxBytes, err := strconv.ParseInt(os.Getenv("MERGE_MAX_BYTES"), 10, 64)
+	if err != nil {
+		return DefaultMergeMaxBytes, err
+	}
+	if maxBytes <= 0 {
+		return DefaultMergeMaxBytes, errors.New("invalid merge max bytes provided")
+	}
+	return maxBytes, nil
+}
+
+// GetSyncConfig Obtains the Number of Sync Workers &
+// the Per Peer Sync Timeout From Environment Variables,
+// defaulting to DefaultSyncWorkers & DefaultSyncPeerTimeout
+func GetSyncConfig() (SyncConfig, error) {
+	config := SyncConfig{
+		Workers:     DefaultSyncWorkers,
+		PeerTimeout: DefaultSyncPeerTimeout,
+	}
+
+	var err error
+	if os.Getenv("SYNC_WORKERS") != "" {
+		if config.Workers, err = strconv.Atoi(os.Getenv("SYNC_WORKERS")); err != nil {
+			return config, err
+		}
+		if config.Workers <= 0 {
+			return config, errors.New("invalid sync workers provided")
+		}
+	}
+	if os.Getenv("SYNC_PEER_TIMEOUT") != "" {
+		if config.PeerTimeout, err = time.ParseDuration(os.Getenv("SYNC_PEER_TIMEOUT")); err != nil {
+			return config, err
+		}
+		if config.PeerTimeout <= 0 {
+			return config, errors.New("invalid sync peer timeout provided")
+		}
+	}
+
+	return config, nil
+}
+
+// GetSyncStrategy Obtains the TwoPSet
+// Sync Strategy From Environment Variable
+func GetSyncStrategy() string {
+	return os.Getenv("SYNC_STRATEGY")
+}
+
 // GetNetwork Obtains Network
 // From Environment Variable
 func GetNetwork() string {
 	return os.Getenv("NETWORK") + ":8080"
 }
 
+// GetPort Obtains the Port the Node Listens
+// on From Environment Variable
+// It defaults to 8080
+func GetPort() string {
+	if os.Getenv("PORT") == "" {
+		return "8080"
+	}
+	return os.Getenv("PORT")
+}
+
+// GetPeerAddress Obtains the host:port Address of a Peer
+// Peers discovered by their address are used as is, the
+// others are resolved in the Docker network from GetNetwork()
+func GetPeerAddress(peer string) string {
+	if strings.Contains(peer, ":") {
+		return peer
+	}
+	return peer + "." + GetNetwork()
+}
+
 // SendRequest handles sending of an HTTP GET Request
-func SendRequest(url string) (http.Response, error) {
+func SendRequest(ctx context.Context, url string) (http.Response, error) {
+	return SendAcceptRequest(ctx, url, "")
+}
+
+// SendAcceptRequest handles sending of an HTTP GET Request
+// with the given Accept header used for content negotiation
+func SendAcceptRequest(ctx context.Context, url string, accept string) (http.Response, error) {
 	if url == "" {
 		return http.Response{}, errors.New("empty url provided")
 	}
 
-	client := http.Client{
-		Timeout: time.Duration(5 * 60 * time.Second),
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return http.Response{}, err
+	}
+
+	if accept != "" {
+		request.Header.Set("Accept", accept)
 	}
 
-	response, err := client.Get(url)
+	return DoRequest(request)
+}
+
+// DoRequest handles sending of an HTTP Request
+// using the shared connection pooled Client
+// The caller must close the response body
+func DoRequest(request *http.Request) (http.Response, error) {
+	response, err := Client.Do(request)
 	if err != nil {
 		return http.Response{}, err
 	}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// getValues requests the values endpoint accepting
+// the given media types and returns the response recorded
+func getValues(accept string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, "/twopset/values", nil)
+	request.Header.Set("Accept", accept)
+
+	recorder := httptest.NewRecorder()
+	Router().ServeHTTP(recorder, request)
+	return recorder
+}
+
+// TestValues_Binary checks the functionality of the Values handler
+// the TwoPSet served under twopset.MediaType should decode using the
+// TwoPSet's binary encoding, and the State served under
+// twopset.StateMediaType should hold its garbage collection Metadata
+func TestValues_Binary(t *testing.T) {
+	useTwoPSet(twopset.Initialize())
+	Node.Addition("xx")
+	Node.Addition("yy")
+	Node.Removal("xx")
+
+	expectedValue := twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy"), Remove: twopset.NewGSet("xx")}
+
+	recorder := getValues(twopset.MediaType)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, twopset.MediaType, recorder.Header().Get("Content-Type"))
+
+	var actualValue twopset.TwoPSet
+	assert.Nil(t, actualValue.UnmarshalBinary(recorder.Body.Bytes()))
+	assert.Equal(t, expectedValue, actualValue)
+
+	recorder = getValues(twopset.StateMediaType + ", " + twopset.MediaType + ";q=0.9")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, twopset.StateMediaType, recorder.Header().Get("Content-Type"))
+
+	var state State
+	assert.Nil(t, state.UnmarshalBinary(recorder.Body.Bytes()))
+	assert.Equal(t, expectedValue, state.TwoPSet)
+	assert.NotNil(t, state.GC)
+	assert.Equal(t, 1, len(state.GC.Dots["xx"]))
+}
@@ -0,0 +1,128 @@
+package hlc
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// package hlc implements Hybrid Logical Clocks (HLC) which combine the physical
+// wall clock time with a logical counter, so timestamps stay close to physical time
+// while still respecting causality between events on different nodes
+
+// Timestamp is a Hybrid Logical Clock timestamp
+type Timestamp struct {
+	// Wall is the physical component in
+	// nanoseconds since the Unix epoch
+	Wall int64 `json:"wall"`
+	// Logical orders events with
+	// the same physical component
+	Logical uint32 `json:"logical"`
+}
+
+// Compare returns -1, 0 or 1 if the timestamp is
+// before, equal to or after the other timestamp
+func (timestamp Timestamp) Compare(other Timestamp) int {
+	switch {
+	case timestamp.Wall < other.Wall:
+		return -1
+	case timestamp.Wall > other.Wall:
+		return 1
+	case timestamp.Logical < other.Logical:
+		return -1
+	case timestamp.Logical > other.Logical:
+		return 1
+	}
+	return 0
+}
+
+// Before returns true if the timestamp
+// happened before the other timestamp
+func (timestamp Timestamp) Before(other Timestamp) bool {
+	return timestamp.Compare(other) < 0
+}
+
+// IsZero returns true if the timestamp is unset
+func (timestamp Timestamp) IsZero() bool {
+	return timestamp == Timestamp{}
+}
+
+// String returns the timestamp as <wall>.<logical>
+func (timestamp Timestamp) String() string {
+	return fmt.Sprintf("%d.%d", timestamp.Wall, timestamp.Logical)
+}
+
+// Max returns the latest of the timestamps passed
+func Max(timestamps ...Timestamp) Timestamp {
+	var latest Timestamp
+	for _, timestamp := range timestamps {
+		if latest.Before(timestamp) {
+			latest = timestamp
+		}
+	}
+	return latest
+}
+
+// Clock is a Hybrid Logical Clock
+// It is safe for concurrent use
+type Clock struct {
+	mutex sync.Mutex
+	// last is the latest timestamp
+	// issued or observed by the clock
+	last Timestamp
+	// physical returns the physical time
+	physical func() time.Time
+}
+
+// NewClock returns a new Clock reading the physical time
+// from the function passed, or time.Now if it is nil
+func NewClock(physical func() time.Time) *Clock {
+	if physical == nil {
+		physical = time.Now
+	}
+	return &Clock{physical: physical}
+}
+
+// Now returns a new timestamp for a local
+// event later than any issued or observed
+func (clock *Clock) Now() Timestamp {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	wall := clock.physical().UnixNano()
+
+	if wall > clock.last.Wall {
+		clock.last = Timestamp{Wall: wall}
+	} else {
+		clock.last.Logical++
+	}
+
+	return clock.last
+}
+
+// Update advances the clock on receiving a timestamp from
+// another node and returns the new timestamp for the event
+func (clock *Clock) Update(remote Timestamp) Timestamp {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	wall := clock.physical().UnixNano()
+	last := clock.last
+
+	switch {
+	case wall > last.Wall && wall > remote.Wall:
+		clock.last = Timestamp{Wall: wall}
+	case last.Wall == remote.Wall:
+		logical := last.Logical
+		if remote.Logical > logical {
+			logical = remote.Logical
+		}
+		clock.last = Timestamp{Wall: last.Wall, Logical: logical + 1}
+	case last.Wall > remote.Wall:
+		clock.last.Logical++
+	default:
+		clock.last = Timestamp{Wall: remote.Wall, Logical: remote.Logical + 1}
+	}
+
+	return clock.last
+}
@@ -0,0 +1,95 @@
+package hlc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedTime returns a physical time source
+// that always returns the given nanoseconds
+func fixedTime(nanoseconds *int64) func() time.Time {
+	return func() time.Time {
+		return time.Unix(0, *nanoseconds)
+	}
+}
+
+// TestNow checks the basic functionality of Clock Now()
+// it should follow the physical time when it moves forward
+func TestNow(t *testing.T) {
+	wall := int64(100)
+	clock := NewClock(fixedTime(&wall))
+
+	assert.Equal(t, Timestamp{Wall: 100}, clock.Now())
+
+	wall = 200
+	assert.Equal(t, Timestamp{Wall: 200}, clock.Now())
+}
+
+// TestNow_Monotonic checks the functionality of Clock Now()
+// when the physical time stalls or goes backwards, the logical
+// counter should keep the timestamps increasing
+func TestNow_Monotonic(t *testing.T) {
+	wall := int64(100)
+	clock := NewClock(fixedTime(&wall))
+
+	first := clock.Now()
+	second := clock.Now()
+
+	wall = 50
+	third := clock.Now()
+
+	assert.Equal(t, Timestamp{Wall: 100, Logical: 1}, second)
+	assert.Equal(t, Timestamp{Wall: 100, Logical: 2}, third)
+	assert.True(t, first.Before(second))
+	assert.True(t, second.Before(third))
+}
+
+// TestUpdate checks the basic functionality of Clock Update()
+// a remote timestamp ahead of the local clock should be adopted
+// so the next local event happens after it
+func TestUpdate(t *testing.T) {
+	wall := int64(100)
+	clock := NewClock(fixedTime(&wall))
+
+	received := clock.Update(Timestamp{Wall: 500, Logical: 3})
+	next := clock.Now()
+
+	assert.Equal(t, Timestamp{Wall: 500, Logical: 4}, received)
+	assert.Equal(t, Timestamp{Wall: 500, Logical: 5}, next)
+}
+
+// TestUpdate_Behind checks the functionality of Clock Update()
+// a remote timestamp behind the physical time should be ignored
+func TestUpdate_Behind(t *testing.T) {
+	wall := int64(100)
+	clock := NewClock(fixedTime(&wall))
+
+	assert.Equal(t, Timestamp{Wall: 100}, clock.Update(Timestamp{Wall: 50, Logical: 9}))
+}
+
+// TestUpdate_SameWall checks the functionality of Clock Update()
+// when both clocks have the same physical component, the logical
+// counter should move past both of them
+func TestUpdate_SameWall(t *testing.T) {
+	wall := int64(100)
+	clock := NewClock(fixedTime(&wall))
+
+	clock.Now()
+	clock.Now()
+
+	assert.Equal(t, Timestamp{Wall: 100, Logical: 8}, clock.Update(Timestamp{Wall: 100, Logical: 7}))
+}
+
+// TestCompare checks the basic functionality of Timestamp Compare() & Max()
+func TestCompare(t *testing.T) {
+	earlier := Timestamp{Wall: 100, Logical: 5}
+	later := Timestamp{Wall: 200}
+
+	assert.Equal(t, -1, earlier.Compare(later))
+	assert.Equal(t, 1, later.Compare(earlier))
+	assert.Equal(t, 0, later.Compare(later))
+	assert.Equal(t, later, Max(earlier, later, Timestamp{}))
+	assert.True(t, Timestamp{}.IsZero())
+}
@@ -0,0 +1,228 @@
+package lwwset
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/el10savio/twoPSet-crdt/hlc"
+)
+
+// package lwwset implements the Last-Writer-Wins Element Set (LWWSet) CRDT data type
+// along with the functionality to append, remove, list & lookup values in an LWWSet.
+// It also provides the functionality to merge multiple LWWSets together and a utility
+// function to clear an LWWSet used in tests. Every Addition & Removal is timestamped
+// by a Hybrid Logical Clock and the latest one for a value decides if it is present
+
+// Bias decides if a value is present when its
+// Addition & Removal have the same timestamp
+type Bias string
+
+const (
+	// BiasAdd keeps the value present
+	BiasAdd Bias = "add"
+	// BiasRemove keeps the value removed
+	BiasRemove Bias = "remove"
+)
+
+// ParseBias returns the Bias for the given
+// string, an empty one defaults to BiasAdd
+func ParseBias(bias string) (Bias, error) {
+	switch Bias(bias) {
+	case "", BiasAdd:
+		return BiasAdd, nil
+	case BiasRemove:
+		return BiasRemove, nil
+	}
+	return "", errors.New("invalid bias provided: " + bias)
+}
+
+// LWWSet is the LWWSet CRDT data type
+// It stores the latest Addition & Removal
+// timestamp of each value in two maps
+// LWWSets share their underlying maps when
+// copied, use Copy() to obtain an independent one
+type LWWSet struct {
+	// Add maps each value to the
+	// timestamp of its latest Addition
+	Add map[string]hlc.Timestamp `json:"add"`
+	// Remove maps each value to the
+	// timestamp of its latest Removal
+	Remove map[string]hlc.Timestamp `json:"remove"`
+	// Bias is the local policy used to resolve ties
+	// and is not part of the replicated state
+	Bias Bias `json:"-"`
+	// clock timestamps the local
+	// Additions & Removals
+	clock *hlc.Clock
+}
+
+// Initialize returns a new empty LWWSet with the
+// given bias timestamped by the system clock
+func Initialize(bias Bias) LWWSet {
+	return InitializeWithClock(bias, hlc.NewClock(nil))
+}
+
+// InitializeWithClock returns a new empty LWWSet
+// with the given bias timestamped by the clock passed
+func InitializeWithClock(bias Bias, clock *hlc.Clock) LWWSet {
+	return LWWSet{
+		Add:    make(map[string]hlc.Timestamp),
+		Remove: make(map[string]hlc.Timestamp),
+		Bias:   bias,
+		clock:  clock,
+	}
+}
+
+// init allocates the maps & clock of a zero value LWWSet
+func (lwwset LWWSet) init() LWWSet {
+	if lwwset.Add == nil {
+		lwwset.Add = make(map[string]hlc.Timestamp)
+	}
+	if lwwset.Remove == nil {
+		lwwset.Remove = make(map[string]hlc.Timestamp)
+	}
+	if lwwset.clock == nil {
+		lwwset.clock = hlc.NewClock(nil)
+	}
+	return lwwset
+}
+
+// Addition adds a value to the LWWSet
+// timestamped by the local clock
+func (lwwset LWWSet) Addition(value string) (LWWSet, error) {
+	// Return an error if the value passed is nil
+	if value == "" {
+		return lwwset, errors.New("empty value provided")
+	}
+
+	lwwset = lwwset.init()
+	lwwset.Add = latest(lwwset.Add, value, lwwset.clock.Now())
+
+	// Return the new LWWSet followed by nil error
+	return lwwset, nil
+}
+
+// Removal removes a value from the LWWSet
+// timestamped by the local clock
+func (lwwset LWWSet) Removal(value string) (LWWSet, error) {
+	// Return an error if the value passed is nil
+	if value == "" {
+		return lwwset, errors.New("empty value provided")
+	}
+
+	lwwset = lwwset.init()
+	lwwset.Remove = latest(lwwset.Remove, value, lwwset.clock.Now())
+
+	// Return the new LWWSet followed by nil error
+	return lwwset, nil
+}
+
+// Lookup returns either boolean true/false indicating
+// if a given value is present in the LWWSet or not
+func (lwwset LWWSet) Lookup(value string) (bool, error) {
+	// Return an error if the value passed is nil
+	if value == "" {
+		return false, errors.New("empty value provided")
+	}
+
+	added, isAdded := lwwset.Add[value]
+	if !isAdded {
+		return false, nil
+	}
+
+	removed, isRemoved := lwwset.Remove[value]
+	if !isRemoved {
+		return true, nil
+	}
+
+	// The latest of the Addition & Removal wins
+	// with ties resolved using the bias
+	switch added.Compare(removed) {
+	case 1:
+		return true, nil
+	case 0:
+		return lwwset.Bias != BiasRemove, nil
+	}
+	return false, nil
+}
+
+// List returns all the elements present
+// in the LWWSet in sorted order
+func (lwwset LWWSet) List() []string {
+	list := make([]string, 0, len(lwwset.Add))
+
+	for value := range lwwset.Add {
+		if present, _ := lwwset.Lookup(value); present {
+			list = append(list, value)
+		}
+	}
+
+	sort.Strings(list)
+	return list
+}
+
+// Merge conbines multiple LWWSets together keeping the latest
+// timestamp for each value and returns a single merged LWWSet
+// The merged LWWSet uses the bias & clock of the first LWWSet,
+// the clock is advanced past every timestamp merged
+// The LWWSets passed are left unmodified
+func Merge(LWWSets ...LWWSet) LWWSet {
+	var lwwSetMerged LWWSet
+	if len(LWWSets) > 0 {
+		lwwSetMerged.Bias = LWWSets[0].Bias
+		lwwSetMerged.clock = LWWSets[0].clock
+	}
+	lwwSetMerged = lwwSetMerged.init()
+
+	var observed hlc.Timestamp
+	for _, lwwset := range LWWSets {
+		for value, timestamp := range lwwset.Add {
+			lwwSetMerged.Add = latest(lwwSetMerged.Add, value, timestamp)
+			observed = hlc.Max(observed, timestamp)
+		}
+		for value, timestamp := range lwwset.Remove {
+			lwwSetMerged.Remove = latest(lwwSetMerged.Remove, value, timestamp)
+			observed = hlc.Max(observed, timestamp)
+		}
+	}
+
+	// Later local operations must
+	// happen after those merged
+	if !observed.IsZero() {
+		lwwSetMerged.clock.Update(observed)
+	}
+
+	// Return the merged LWWSet
+	return lwwSetMerged
+}
+
+// Copy returns an independent copy of the
+// LWWSet sharing the same bias & clock
+func (lwwset LWWSet) Copy() LWWSet {
+	copied := lwwset.Clear()
+	for value, timestamp := range lwwset.Add {
+		copied.Add[value] = timestamp
+	}
+	for value, timestamp := range lwwset.Remove {
+		copied.Remove[value] = timestamp
+	}
+	return copied
+}
+
+// Clear is utility function used only for tests
+// to empty the contents of a given LWWSet
+func (lwwset LWWSet) Clear() LWWSet {
+	return LWWSet{Bias: lwwset.Bias, clock: lwwset.clock}.init()
+}
+
+// latest stores the timestamp for a value in the
+// given map if it is later than the one present
+func latest(set map[string]hlc.Timestamp, value string, timestamp hlc.Timestamp) map[string]hlc.Timestamp {
+	if value == "" {
+		return set
+	}
+	if current, exists := set[value]; !exists || current.Before(timestamp) {
+		set[value] = timestamp
+	}
+	return set
+}
@@ -0,0 +1,164 @@
+package lwwset
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/hlc"
+)
+
+// stalledClock returns a Clock whose physical
+// time never moves, so only the logical
+// counter orders the timestamps
+func stalledClock() *hlc.Clock {
+	return hlc.NewClock(func() time.Time {
+		return time.Unix(0, 100)
+	})
+}
+
+// TestList checks the basic functionality of LWWSet List()
+// List() should return all unique values added to the LWWSet
+func TestList(t *testing.T) {
+	lwwset := InitializeWithClock(BiasAdd, stalledClock())
+
+	lwwset, _ = lwwset.Addition("yy")
+	lwwset, _ = lwwset.Addition("xx")
+	lwwset, _ = lwwset.Addition("xx")
+
+	assert.Equal(t, []string{"xx", "yy"}, lwwset.List())
+}
+
+// TestList_NoValue checks the functionality of LWWSet List() when
+// no values are added to LWWSet, it should return an empty string slice
+func TestList_NoValue(t *testing.T) {
+	assert.Equal(t, []string{}, Initialize(BiasAdd).List())
+}
+
+// TestAddition_NoValue checks the functionality of LWWSet Addition()
+// & Removal() when a nil value is passed to it, it should return an error
+func TestAddition_NoValue(t *testing.T) {
+	expectedError := errors.New("empty value provided")
+
+	_, actualError := Initialize(BiasAdd).Addition("")
+	assert.Equal(t, expectedError, actualError)
+
+	_, actualError = Initialize(BiasAdd).Removal("")
+	assert.Equal(t, expectedError, actualError)
+
+	_, actualError = Initialize(BiasAdd).Lookup("")
+	assert.Equal(t, expectedError, actualError)
+}
+
+// TestRemoval checks the basic functionality of LWWSet Removal()
+// a later Removal should remove the value & a later Addition add it back
+func TestRemoval(t *testing.T) {
+	lwwset := InitializeWithClock(BiasAdd, stalledClock())
+
+	lwwset, _ = lwwset.Addition("xx")
+	lwwset, _ = lwwset.Removal("xx")
+
+	present, _ := lwwset.Lookup("xx")
+	assert.False(t, present)
+
+	lwwset, _ = lwwset.Addition("xx")
+
+	present, _ = lwwset.Lookup("xx")
+	assert.True(t, present)
+}
+
+// TestLookup_Bias checks the functionality of LWWSet Lookup()
+// when the Addition & Removal have the same timestamp
+// the bias should decide if the value is present
+func TestLookup_Bias(t *testing.T) {
+	timestamp := hlc.Timestamp{Wall: 100}
+
+	addWins := LWWSet{
+		Add:    map[string]hlc.Timestamp{"xx": timestamp},
+		Remove: map[string]hlc.Timestamp{"xx": timestamp},
+		Bias:   BiasAdd,
+	}
+	removeWins := addWins
+	removeWins.Bias = BiasRemove
+
+	present, _ := addWins.Lookup("xx")
+	assert.True(t, present)
+
+	present, _ = removeWins.Lookup("xx")
+	assert.False(t, present)
+}
+
+// TestMerge checks the basic functionality of the Merge() function on multiple LWWSets
+// it keeps the latest timestamp for each value irrespective of the merge order
+func TestMerge(t *testing.T) {
+	lwwset1 := LWWSet{
+		Add:    map[string]hlc.Timestamp{"xx": {Wall: 100}, "yy": {Wall: 100}},
+		Remove: map[string]hlc.Timestamp{},
+	}
+	lwwset2 := LWWSet{
+		Add:    map[string]hlc.Timestamp{"xx": {Wall: 300}},
+		Remove: map[string]hlc.Timestamp{"xx": {Wall: 200}, "yy": {Wall: 200}},
+	}
+
+	actualValue := Merge(lwwset1, lwwset2)
+
+	assert.Equal(t, []string{"xx"}, actualValue.List())
+	assert.Equal(t, actualValue.Add, Merge(lwwset2, lwwset1).Add)
+	assert.Equal(t, actualValue.Remove, Merge(lwwset2, lwwset1).Remove)
+	assert.Equal(t, map[string]hlc.Timestamp{"xx": {Wall: 100}, "yy": {Wall: 100}}, lwwset1.Add)
+}
+
+// TestMerge_Clock checks the functionality of the Merge() function
+// a local operation after a merge should win over the merged ones
+// even when the local physical clock lags behind
+func TestMerge_Clock(t *testing.T) {
+	local := InitializeWithClock(BiasAdd, stalledClock())
+	remote := LWWSet{
+		Add:    map[string]hlc.Timestamp{"xx": {Wall: 500}},
+		Remove: map[string]hlc.Timestamp{},
+	}
+
+	local = Merge(local, remote)
+	local, _ = local.Removal("xx")
+
+	present, _ := local.Lookup("xx")
+	assert.False(t, present)
+}
+
+// TestParseBias checks the basic functionality of ParseBias()
+func TestParseBias(t *testing.T) {
+	bias, err := ParseBias("")
+	assert.Nil(t, err)
+	assert.Equal(t, BiasAdd, bias)
+
+	bias, err = ParseBias("remove")
+	assert.Nil(t, err)
+	assert.Equal(t, BiasRemove, bias)
+
+	_, err = ParseBias("none")
+	assert.Equal(t, errors.New("invalid bias provided: none"), err)
+}
+
+// TestJSON checks that an LWWSet survives a JSON
+// round trip so it can be sent between nodes
+func TestJSON(t *testing.T) {
+	set := LWWSet{
+		Add:    map[string]hlc.Timestamp{"xx": {Wall: 100, Logical: 1}},
+		Remove: map[string]hlc.Timestamp{},
+	}
+
+	expectedValue := `{"add":{"xx":{"wall":100,"logical":1}},"remove":{}}`
+	actualValue, actualError := json.Marshal(set)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, string(actualValue))
+
+	var decoded LWWSet
+	actualError = json.Unmarshal(actualValue, &decoded)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, set, decoded)
+}
@@ -4,18 +4,21 @@ package main
 // package starting up the twopset server
 
 import (
+	"errors"
 	"net/http"
 	"os"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/el10savio/twoPSet-crdt/handlers"
+	"github.com/el10savio/twoPSet-crdt/store"
 )
 
 const (
-	// PORT is the 2PSet
-	// server port
-	PORT = "8080"
+	// StoreMemory keeps the TwoPSet only in memory
+	StoreMemory = "memory"
+	// StoreFile stores the TwoPSet in the data directory
+	StoreFile = "file"
 )
 
 func init() {
@@ -24,11 +27,107 @@ func init() {
 }
 
 func main() {
+	err := handlers.UseSet(handlers.GetSetType())
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to select set type")
+	}
+
+	backend, err := openStore(handlers.GetStoreType(), handlers.GetDataDir())
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to open store")
+	}
+
+	err = handlers.UseStore(backend)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to load store")
+	}
+
+	sync, err := handlers.GetSyncConfig()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to obtain sync config")
+	}
+	handlers.UseSyncConfig(sync)
+
+	interval, err := handlers.GetSnapshotInterval()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to obtain snapshot interval")
+	}
+	handlers.StartSnapshots(interval)
+
+	discovery, err := handlers.GetDiscoveryConfig()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to obtain discovery config")
+	}
+	err = handlers.StartDiscovery(discovery)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to start peer discovery")
+	}
+
+	health, err := handlers.GetHealthConfig()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to obtain health config")
+	}
+	handlers.StartHealth(health)
+
+	swimInterval, err := handlers.GetSWIMInterval()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to obtain swim interval")
+	}
+	swim, err := handlers.GetSWIMConfig()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to obtain swim config")
+	}
+	handlers.StartSWIM(swimInterval, swim)
+
+	gossip, err := handlers.GetGossipConfig()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to obtain gossip config")
+	}
+	handlers.StartGossip(gossip)
+
+	push, err := handlers.GetPushConfig()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to obtain push config")
+	}
+	handlers.StartPush(push)
+
+	mergeMaxBytes, err := handlers.GetMergeMaxBytes()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Fatal("failed to obtain merge max bytes")
+	}
+	handlers.UseMergeMaxBytes(mergeMaxBytes)
+
 	r := handlers.Router()
 
 	log.WithFields(log.Fields{
-		"port": PORT,
+		"port":  handlers.GetPort(),
+		"set":   handlers.GetSetType(),
+		"store": handlers.GetStoreType(),
 	}).Info("started TwoPSet node server")
 
-	http.ListenAndServe(":"+PORT, r)
+	http.ListenAndServe(":"+handlers.GetPort(), r)
+}
+
+// openStore opens the Store of the given type
+// An empty store type defaults to the FileStore if
+// a data directory is provided & the MemoryStore if not
+func openStore(storeType string, dir string) (store.Store, error) {
+	if storeType == "" {
+		storeType = StoreMemory
+		if dir != "" {
+			storeType = StoreFile
+		}
+	}
+
+	switch storeType {
+	case StoreMemory:
+		return store.NewMemoryStore(), nil
+	case StoreFile:
+		if dir == "" {
+			return nil, errors.New("no data directory provided for the file store")
+		}
+		return store.OpenFileStore(dir)
+	default:
+		return nil, errors.New("invalid store type provided: " + storeType)
+	}
 }
@@ -0,0 +1,208 @@
+package orset
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// package orset implements the Observed-Remove Set (ORSet) CRDT data type along with
+// the functionality to append, remove, list & lookup values in an ORSet. It also provides
+// the functionality to merge multiple ORSets together and a utility function to clear
+// an ORSet used in tests. Unlike the TwoPSet a value removed can be added back again
+
+// Tags is a set of unique tags identifying
+// the additions of a value to the ORSet
+type Tags map[string]struct{}
+
+// ORSet is the ORSet CRDT data type
+// Each Addition of a value is identified by a unique tag
+// and a Removal only removes the tags it has observed,
+// so a value is present as long as it has a tag
+// that has not been removed
+// ORSets share their underlying maps when
+// copied, use Copy() to obtain an independent one
+type ORSet struct {
+	// Add maps each value to the
+	// tags of its additions
+	Add map[string]Tags `json:"add"`
+	// Remove maps each value to the
+	// tags of its additions removed
+	Remove map[string]Tags `json:"remove"`
+}
+
+// Initialize returns a new empty ORSet
+func Initialize() ORSet {
+	return ORSet{
+		Add:    make(map[string]Tags),
+		Remove: make(map[string]Tags),
+	}
+}
+
+// init allocates the maps of a zero value ORSet
+func (orset ORSet) init() ORSet {
+	if orset.Add == nil {
+		orset.Add = make(map[string]Tags)
+	}
+	if orset.Remove == nil {
+		orset.Remove = make(map[string]Tags)
+	}
+	return orset
+}
+
+// NewTag returns a new random unique tag
+func NewTag() (string, error) {
+	tag := make([]byte, 16)
+	if _, err := rand.Read(tag); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(tag), nil
+}
+
+// Addition adds a value to the ORSet
+// identified by a new unique tag
+func (orset ORSet) Addition(value string) (ORSet, error) {
+	// Return an error if the value passed is nil
+	if value == "" {
+		return orset, errors.New("empty value provided")
+	}
+
+	tag, err := NewTag()
+	if err != nil {
+		return orset, err
+	}
+
+	orset = orset.init()
+	orset.Add = insert(orset.Add, value, tag)
+
+	// Return the new ORSet followed by nil error
+	return orset, nil
+}
+
+// Removal removes a value from the ORSet by
+// removing all the tags of it observed locally
+// Removing a value not present has no effect
+func (orset ORSet) Removal(value string) (ORSet, error) {
+	// Return an error if the value passed is nil
+	if value == "" {
+		return orset, errors.New("empty value provided")
+	}
+
+	orset = orset.init()
+
+	for tag := range orset.Add[value] {
+		orset.Remove = insert(orset.Remove, value, tag)
+	}
+
+	// Return the new ORSet followed by nil error
+	return orset, nil
+}
+
+// Lookup returns either boolean true/false indicating
+// if a given value is present in the ORSet or not
+func (orset ORSet) Lookup(value string) (bool, error) {
+	// Return an error if the value passed is nil
+	if value == "" {
+		return false, errors.New("empty value provided")
+	}
+
+	// A value is present if any of its
+	// tags has not been removed
+	for tag := range orset.Add[value] {
+		if _, removed := orset.Remove[value][tag]; !removed {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// List returns all the elements present
+// in the ORSet in sorted order
+func (orset ORSet) List() []string {
+	list := make([]string, 0, len(orset.Add))
+
+	for value := range orset.Add {
+		if present, _ := orset.Lookup(value); present {
+			list = append(list, value)
+		}
+	}
+
+	sort.Strings(list)
+	return list
+}
+
+// Merge conbines multiple ORSets together using Union
+// of their tags and returns a single merged ORSet
+// The ORSets passed are left unmodified
+func Merge(ORSets ...ORSet) ORSet {
+	orSetMerged := Initialize()
+
+	for _, orset := range ORSets {
+		for value, tags := range orset.Add {
+			for tag := range tags {
+				orSetMerged.Add = insert(orSetMerged.Add, value, tag)
+			}
+		}
+		for value, tags := range orset.Remove {
+			for tag := range tags {
+				orSetMerged.Remove = insert(orSetMerged.Remove, value, tag)
+			}
+		}
+	}
+
+	// Return the merged ORSet
+	return orSetMerged
+}
+
+// Copy returns an independent copy of the ORSet
+func (orset ORSet) Copy() ORSet {
+	return Merge(orset)
+}
+
+// Clear is utility function used only for tests
+// to empty the contents of a given ORSet
+func (orset ORSet) Clear() ORSet {
+	return Initialize()
+}
+
+// insert adds the tag of a value to the given map
+func insert(set map[string]Tags, value string, tag string) map[string]Tags {
+	if value == "" || tag == "" {
+		return set
+	}
+	if set[value] == nil {
+		set[value] = make(Tags)
+	}
+	set[value][tag] = struct{}{}
+	return set
+}
+
+// MarshalJSON encodes the Tags as a sorted array
+func (tags Tags) MarshalJSON() ([]byte, error) {
+	list := make([]string, 0, len(tags))
+	for tag := range tags {
+		list = append(list, tag)
+	}
+	sort.Strings(list)
+	return json.Marshal(list)
+}
+
+// UnmarshalJSON decodes the Tags from an array
+func (tags *Tags) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	*tags = make(Tags, len(list))
+	for _, tag := range list {
+		if tag == "" {
+			continue
+		}
+		(*tags)[tag] = struct{}{}
+	}
+	return nil
+}
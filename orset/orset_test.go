@@ -0,0 +1,175 @@
+package orset
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	orset ORSet
+)
+
+func init() {
+	orset = Initialize()
+}
+
+// TestList checks the basic functionality of ORSet List()
+// List() should return all unique values added to the ORSet
+func TestList(t *testing.T) {
+	orset, _ = orset.Addition("yy")
+	orset, _ = orset.Addition("xx")
+	orset, _ = orset.Addition("xx")
+
+	expectedValue := []string{"xx", "yy"}
+	actualValue := orset.List()
+
+	assert.Equal(t, expectedValue, actualValue)
+
+	orset = orset.Clear()
+}
+
+// TestList_NoValue checks the functionality of ORSet List() when
+// no values are added to ORSet, it should return an empty string slice
+func TestList_NoValue(t *testing.T) {
+	expectedValue := []string{}
+	actualValue := orset.List()
+
+	assert.Equal(t, expectedValue, actualValue)
+
+	orset = orset.Clear()
+}
+
+// TestAddition_NoValue checks the functionality of ORSet Addition()
+// when a nil value is passed to it, it should return an error
+func TestAddition_NoValue(t *testing.T) {
+	expectedError := errors.New("empty value provided")
+	actualValue, actualError := orset.Addition("")
+
+	assert.Equal(t, expectedError, actualError)
+	assert.Equal(t, Initialize(), actualValue)
+
+	orset = orset.Clear()
+}
+
+// TestAddition_UniqueTags checks the functionality of ORSet Addition()
+// each addition of the same value should be identified by a new tag
+func TestAddition_UniqueTags(t *testing.T) {
+	orset, _ = orset.Addition("xx")
+	orset, _ = orset.Addition("xx")
+
+	assert.Len(t, orset.Add["xx"], 2)
+
+	orset = orset.Clear()
+}
+
+// TestRemoval checks the basic functionality of ORSet Removal()
+// a value removed should no longer be present in the ORSet
+func TestRemoval(t *testing.T) {
+	orset, _ = orset.Addition("xx")
+	orset, _ = orset.Addition("yy")
+	orset, _ = orset.Removal("xx")
+
+	expectedValue := []string{"yy"}
+	actualValue := orset.List()
+
+	assert.Equal(t, expectedValue, actualValue)
+
+	orset = orset.Clear()
+}
+
+// TestRemoval_NotPresent checks the functionality of ORSet Removal()
+// when a value never added is removed, it should have no effect
+func TestRemoval_NotPresent(t *testing.T) {
+	orset, _ = orset.Removal("xx")
+	orset, _ = orset.Addition("xx")
+
+	expectedValue := true
+	actualValue, actualError := orset.Lookup("xx")
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, actualValue)
+
+	orset = orset.Clear()
+}
+
+// TestAddition_AfterRemoval checks the functionality of ORSet Addition()
+// unlike a TwoPSet, a value removed can be added back again
+func TestAddition_AfterRemoval(t *testing.T) {
+	orset, _ = orset.Addition("xx")
+	orset, _ = orset.Removal("xx")
+	orset, _ = orset.Addition("xx")
+
+	expectedValue := true
+	actualValue, actualError := orset.Lookup("xx")
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, actualValue)
+
+	orset = orset.Clear()
+}
+
+// TestLookup_EmptyLookup checks the functionality of ORSet Lookup() function
+// it returns an error if the value passed is nil irrespective of the ORSet
+func TestLookup_EmptyLookup(t *testing.T) {
+	expectedError := errors.New("empty value provided")
+	actualValue, actualError := orset.Lookup("")
+
+	assert.Equal(t, expectedError, actualError)
+	assert.Equal(t, false, actualValue)
+
+	orset = orset.Clear()
+}
+
+// TestMerge checks the basic functionality of the Merge() function on multiple ORSets
+// it returns all the ORSets merged together with the union of their tags
+func TestMerge(t *testing.T) {
+	orset1, _ := Initialize().Addition("xx")
+	orset2 := Merge(orset1)
+	orset2, _ = orset2.Removal("xx")
+	orset3, _ := Initialize().Addition("yy")
+
+	actualValue := Merge(orset1, orset2, orset3)
+
+	assert.Equal(t, []string{"yy"}, actualValue.List())
+	assert.Equal(t, []string{"xx"}, orset1.List())
+}
+
+// TestMerge_ConcurrentAdd checks the functionality of the Merge() function
+// when a value is removed on one node while being concurrently added on
+// another, the concurrent addition is not observed so it wins
+func TestMerge_ConcurrentAdd(t *testing.T) {
+	orset1, _ := Initialize().Addition("xx")
+	orset2 := orset1.Copy()
+
+	orset1, _ = orset1.Removal("xx")
+	orset2, _ = orset2.Addition("xx")
+
+	actualValue := Merge(orset1, orset2)
+
+	assert.Equal(t, []string{"xx"}, actualValue.List())
+	assert.Equal(t, actualValue, Merge(orset2, orset1))
+}
+
+// TestJSON checks that an ORSet survives a JSON
+// round trip so it can be sent between nodes
+func TestJSON(t *testing.T) {
+	set := ORSet{
+		Add:    map[string]Tags{"xx": {"b": {}, "a": {}}},
+		Remove: map[string]Tags{"xx": {"a": {}}},
+	}
+
+	expectedValue := `{"add":{"xx":["a","b"]},"remove":{"xx":["a"]}}`
+	actualValue, actualError := json.Marshal(set)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, string(actualValue))
+
+	var decoded ORSet
+	actualError = json.Unmarshal(actualValue, &decoded)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, set, decoded)
+}
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+const (
+	// LogFile is the name of the TwoPSet
+	// write-ahead log in the data directory
+	LogFile = "twopset.wal"
+
+	// KeepSnapshots is the number of newest snapshots kept
+	// in the data directory, so an older one can be
+	// recovered from if the newest is corrupt. The
+	// write-ahead log is kept from the oldest one
+	KeepSnapshots = 2
+)
+
+// FileStore stores the TwoPSet in a data directory. Additions &
+// Removals, also those merged in from peers, are written to an
+// fsync'd write-ahead log, which is truncated whenever a Snapshot
+// of the TwoPSet is written. TwoPSets purged of stable tombstones
+// are only stored by the next Snapshot, as the log can not
+// record values being dropped
+type FileStore struct {
+	// dir is the data directory
+	dir string
+	// log is the write-ahead log
+	log *twopset.WAL
+	// state is the state recovered
+	// when the FileStore was opened
+	state State
+	// snapshot is the offset of the newest snapshot
+	// written or recovered from, its offset being
+	// 0 if there are no snapshots yet
+	snapshot int64
+	// purged is true if the TwoPSet was purged
+	// of stable tombstones since the last Snapshot
+	purged bool
+}
+
+// OpenFileStore opens the FileStore in the given data directory
+// creating it if not present. The TwoPSet is recovered from the
+// newest valid snapshot, skipping corrupt ones, followed by the
+// operations in the write-ahead log after it
+func OpenFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	snapshot, path, err := loadSnapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	wal, operations, err := twopset.OpenWAL(filepath.Join(dir, LogFile), snapshot.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// WARN log indicating a torn record at the end
+	// of the log was discarded after a crash
+	if discarded := wal.Discarded(); discarded != 0 {
+		log.WithFields(log.Fields{
+			"log":       filepath.Join(dir, LogFile),
+			"offset":    wal.Offset(),
+			"discarded": discarded,
+		}).Warn("discarded torn record at the end of the write-ahead log")
+	}
+
+	// The operations between the snapshot & the start
+	// of the log are lost when an older snapshot
+	// is recovered from as the newest was corrupt
+	if snapshot.Offset < wal.Base() {
+		log.WithFields(log.Fields{
+			"snapshot": path,
+			"offset":   snapshot.Offset,
+			"base":     wal.Base(),
+		}).Warn("write-ahead log truncated past the snapshot recovered from")
+	}
+
+	state := State{
+		TwoPSet: twopset.MergeDelta(snapshot.TwoPSet, twopset.Replay(operations)),
+		GC:      snapshot.GC,
+	}
+	for _, operation := range operations {
+		if operation.Type == twopset.OperationRemove && !operation.Merged {
+			state.Removals = append(state.Removals, operation.Value)
+		}
+	}
+
+	// INFO log indicating the snapshot & log
+	// offset the TwoPSet was recovered from
+	log.WithFields(log.Fields{
+		"dir":        dir,
+		"snapshot":   path,
+		"offset":     snapshot.Offset,
+		"operations": len(operations),
+	}).Info("recovered twopset from snapshot & write-ahead log")
+
+	return &FileStore{dir: dir, log: wal, state: state, snapshot: snapshot.Offset}, nil
+}
+
+// loadSnapshot reads the newest valid snapshot in the given
+// directory and returns it along with the path to it
+// An empty TwoPSet is returned if there are no valid snapshots
+func loadSnapshot(dir string) (twopset.Snapshot, string, error) {
+	paths, err := twopset.ListSnapshots(dir)
+	if err != nil {
+		return twopset.Snapshot{}, "", err
+	}
+
+	for _, path := range paths {
+		snapshot, err := twopset.ReadSnapshot(path)
+		if err != nil {
+			log.WithFields(log.Fields{"error": err, "snapshot": path}).Warn("skipping corrupt snapshot")
+			continue
+		}
+		return snapshot, path, nil
+	}
+
+	return twopset.Snapshot{TwoPSet: twopset.Initialize()}, "", nil
+}
+
+// oldestSnapshot returns the write-ahead log offset
+// of the oldest snapshot in the given directory
+func oldestSnapshot(dir string) (int64, error) {
+	paths, err := twopset.ListSnapshots(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(paths) == 0 {
+		return 0, nil
+	}
+	return twopset.SnapshotOffset(paths[len(paths)-1])
+}
+
+// Load returns the state recovered
+// when the FileStore was opened
+func (store *FileStore) Load() (State, error) {
+	return store.state, nil
+}
+
+// ApplyAdd writes the Addition to the write-ahead
+// log and waits until it is flushed to disk
+func (store *FileStore) ApplyAdd(value string) error {
+	return store.log.Append(twopset.Operation{Type: twopset.OperationAdd, Value: value})
+}
+
+// ApplyRemove writes the Removal to the write-ahead
+// log and waits until it is flushed to disk
+func (store *FileStore) ApplyRemove(value string) error {
+	return store.log.Append(twopset.Operation{Type: twopset.OperationRemove, Value: value})
+}
+
+// ApplyMerged writes the Additions & Removals in the delta
+// merged in from peers to the write-ahead log and waits
+// until they are flushed to disk, so a merge acknowledged
+// is not lost if the node restarts before the next Snapshot
+func (store *FileStore) ApplyMerged(delta twopset.TwoPSet) error {
+	operations := make([]twopset.Operation, 0, delta.Add.Len()+delta.Remove.Len())
+	for _, value := range delta.Add.Values() {
+		operations = append(operations, twopset.Operation{Type: twopset.OperationAdd, Value: value, Merged: true})
+	}
+	for _, value := range delta.Remove.Values() {
+		operations = append(operations, twopset.Operation{Type: twopset.OperationRemove, Value: value, Merged: true})
+	}
+	return store.log.Append(operations...)
+}
+
+// ApplyPurged marks the TwoPSet to
+// be stored by the next Snapshot
+func (store *FileStore) ApplyPurged(set twopset.TwoPSet) error {
+	store.purged = true
+	return nil
+}
+
+// Snapshot atomically writes the TwoPSet to a snapshot in the
+// data directory and truncates the write-ahead log up to the
+// oldest snapshot kept, so every snapshot kept can be recovered
+// from along with the operations after it
+// Nothing is written if the TwoPSet has not
+// changed since the newest snapshot
+func (store *FileStore) Snapshot(set twopset.TwoPSet, metadata twopset.Metadata) error {
+	offset := store.log.Offset()
+	if offset == store.snapshot && !store.purged {
+		return nil
+	}
+
+	path, err := twopset.WriteSnapshot(store.dir, twopset.Snapshot{
+		Offset:  offset,
+		TwoPSet: set,
+		GC:      &metadata,
+	})
+	if err != nil {
+		return err
+	}
+	store.snapshot = offset
+	store.purged = false
+
+	if err := twopset.RemoveSnapshots(store.dir, KeepSnapshots); err != nil {
+		return err
+	}
+
+	oldest, err := oldestSnapshot(store.dir)
+	if err != nil {
+		return err
+	}
+
+	if err := store.log.Truncate(oldest); err != nil {
+		return err
+	}
+
+	// DEBUG log in the case of success
+	// indicating the snapshot written
+	log.WithFields(log.Fields{
+		"snapshot": path,
+		"offset":   offset,
+	}).Debug("successful twopset snapshot")
+
+	return nil
+}
+
+// Close closes the write-ahead log
+func (store *FileStore) Close() error {
+	return store.log.Close()
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// tempDir returns a new temporary directory
+// along with a function removing it
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "twopset-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// TestFileStore checks the basic functionality of the FileStore
+// the Additions & Removals applied should be loaded when reopened
+func TestFileStore(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	store, actualError := OpenFileStore(dir)
+	assert.Nil(t, actualError)
+
+	state, _ := store.Load()
+	assert.Equal(t, State{TwoPSet: twopset.Initialize()}, state)
+
+	assert.Nil(t, store.ApplyAdd("xx"))
+	assert.Nil(t, store.ApplyAdd("yy"))
+	assert.Nil(t, store.ApplyRemove("xx"))
+	store.Close()
+
+	expectedValue := State{
+		TwoPSet:  twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy"), Remove: twopset.NewGSet("xx")},
+		Removals: []string{"xx"},
+	}
+
+	store, _ = OpenFileStore(dir)
+	actualValue, actualError := store.Load()
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, actualValue)
+	store.Close()
+}
+
+// TestFileStore_Snapshot checks the functionality of FileStore Snapshot()
+// the TwoPSet & Metadata snapshot should be loaded along with the
+// changes applied after it, and purged TwoPSets should only be stored by it
+func TestFileStore_Snapshot(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	metadata := twopset.Metadata{
+		Node:    "peer-0",
+		Dots:    map[string][]twopset.Dot{"xx": {{Node: "peer-0", Sequence: 1}}},
+		Version: twopset.VersionVector{"peer-0": 1},
+		Purged:  []string{"ww"},
+	}
+
+	store, _ := OpenFileStore(dir)
+	store.ApplyAdd("xx")
+	store.ApplyRemove("xx")
+
+	purged := twopset.TwoPSet{Add: twopset.NewGSet("zz"), Remove: twopset.NewGSet()}
+	assert.Nil(t, store.ApplyPurged(purged))
+	assert.Nil(t, store.Snapshot(purged, metadata))
+
+	store.ApplyAdd("yy")
+	store.Close()
+
+	expectedValue := State{
+		TwoPSet: twopset.TwoPSet{Add: twopset.NewGSet("yy", "zz"), Remove: twopset.NewGSet()},
+		GC:      &metadata,
+	}
+
+	store, _ = OpenFileStore(dir)
+	actualValue, actualError := store.Load()
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, actualValue)
+	store.Close()
+}
+
+// TestFileStore_Merged checks the functionality of FileStore ApplyMerged()
+// the deltas merged in from peers should be loaded when reopened
+// without a snapshot, while only the local Removals are tracked
+func TestFileStore_Merged(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	store, _ := OpenFileStore(dir)
+	store.ApplyAdd("xx")
+	store.ApplyRemove("xx")
+	assert.Nil(t, store.ApplyMerged(twopset.TwoPSet{Add: twopset.NewGSet("yy", "zz"), Remove: twopset.NewGSet("yy")}))
+	assert.Nil(t, store.ApplyMerged(twopset.Initialize()))
+	store.Close()
+
+	expectedValue := State{
+		TwoPSet:  twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy", "zz"), Remove: twopset.NewGSet("xx", "yy")},
+		Removals: []string{"xx"},
+	}
+
+	store, _ = OpenFileStore(dir)
+	actualValue, actualError := store.Load()
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, actualValue)
+	store.Close()
+}
+
+// TestFileStore_CorruptSnapshot checks the functionality of OpenFileStore()
+// a corrupt snapshot should be skipped for the older valid one
+// along with the operations logged after it
+func TestFileStore_CorruptSnapshot(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	store, _ := OpenFileStore(dir)
+	store.ApplyAdd("xx")
+	store.Snapshot(twopset.TwoPSet{Add: twopset.NewGSet("xx"), Remove: twopset.NewGSet()}, twopset.Metadata{})
+	store.ApplyAdd("yy")
+	store.Snapshot(twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy"), Remove: twopset.NewGSet()}, twopset.Metadata{})
+	store.ApplyAdd("zz")
+	store.Snapshot(twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy", "zz"), Remove: twopset.NewGSet()}, twopset.Metadata{})
+	store.Close()
+
+	paths, _ := twopset.ListSnapshots(dir)
+	assert.Equal(t, KeepSnapshots, len(paths))
+	ioutil.WriteFile(paths[0], []byte("xx"), 0644)
+
+	store, actualError := OpenFileStore(dir)
+	assert.Nil(t, actualError)
+
+	actualValue, _ := store.Load()
+	assert.Equal(t, twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy", "zz"), Remove: twopset.NewGSet()}, actualValue.TwoPSet)
+	store.Close()
+}
+
+// TestFileStore_IdleSnapshot checks the functionality of FileStore Snapshot()
+// when nothing has changed since the newest snapshot, also once reopened,
+// it should not write another one over it
+func TestFileStore_IdleSnapshot(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	set := twopset.TwoPSet{Add: twopset.NewGSet("xx", "yy"), Remove: twopset.NewGSet()}
+
+	store, _ := OpenFileStore(dir)
+	store.ApplyAdd("xx")
+	assert.Nil(t, store.Snapshot(twopset.TwoPSet{Add: twopset.NewGSet("xx"), Remove: twopset.NewGSet()}, twopset.Metadata{}))
+	store.ApplyAdd("yy")
+	assert.Nil(t, store.Snapshot(set, twopset.Metadata{}))
+
+	// Snapshots are written to a new file renamed over
+	// the previous one, so an idle Snapshot keeps the file
+	paths, _ := twopset.ListSnapshots(dir)
+	expectedValue, _ := os.Stat(paths[0])
+
+	assert.Nil(t, store.Snapshot(set, twopset.Metadata{}))
+	actualValue, _ := os.Stat(paths[0])
+	assert.True(t, os.SameFile(expectedValue, actualValue))
+	store.Close()
+
+	store, _ = OpenFileStore(dir)
+	assert.Nil(t, store.Snapshot(set, twopset.Metadata{}))
+	actualValue, _ = os.Stat(paths[0])
+	assert.True(t, os.SameFile(expectedValue, actualValue))
+	store.Close()
+}
@@ -0,0 +1,87 @@
+package store
+
+// The following implements the storage backends persisting the
+// state of a TwoPSet node. The node keeps serving its TwoPSet from
+// memory & writes every change to it through to the Store, so the
+// TwoPSet can be loaded back from the Store when the node restarts
+
+import (
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// Store persists the TwoPSet served by a node
+// Calls to the Store must not be made concurrently
+// so a Snapshot holds every change applied before it
+type Store interface {
+	// Load returns the state stored
+	Load() (State, error)
+	// ApplyAdd stores an Addition of the value
+	ApplyAdd(value string) error
+	// ApplyRemove stores a Removal of the value
+	ApplyRemove(value string) error
+	// ApplyMerged stores the delta of the Additions
+	// & Removals merged in from peers
+	ApplyMerged(delta twopset.TwoPSet) error
+	// ApplyPurged stores the TwoPSet obtained by
+	// purging stable tombstones, which replaces
+	// the TwoPSet with the changes applied
+	ApplyPurged(set twopset.TwoPSet) error
+	// Snapshot stores the TwoPSet & its garbage
+	// collection Metadata with the changes applied
+	Snapshot(set twopset.TwoPSet, metadata twopset.Metadata) error
+}
+
+// State is the state of a node loaded from a Store
+type State struct {
+	// TwoPSet is the TwoPSet stored
+	TwoPSet twopset.TwoPSet
+	// GC is the garbage collection Metadata
+	// of the last Snapshot stored, if any
+	GC *twopset.Metadata
+	// Removals are the values removed locally, not
+	// merged in from peers, since the last Snapshot
+	// in the order they were removed, to be
+	// tracked for garbage collection
+	Removals []string
+}
+
+// MemoryStore keeps the TwoPSet only in memory
+// so it is lost when the node restarts
+type MemoryStore struct{}
+
+// NewMemoryStore returns a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Load returns an empty TwoPSet
+func (*MemoryStore) Load() (State, error) {
+	return State{TwoPSet: twopset.Initialize()}, nil
+}
+
+func (*MemoryStore) ApplyAdd(value string) error {
+	return nil
+}
+
+func (*MemoryStore) ApplyRemove(value string) error {
+	return nil
+}
+
+func (*MemoryStore) ApplyMerged(delta twopset.TwoPSet) error {
+	return nil
+}
+
+func (*MemoryStore) ApplyPurged(set twopset.TwoPSet) error {
+	return nil
+}
+
+func (*MemoryStore) Snapshot(set twopset.TwoPSet, metadata twopset.Metadata) error {
+	return nil
+}
+
+// compile time check that the
+// stores implement Store
+var (
+	_ Store = &MemoryStore{}
+	_ Store = &FileStore{}
+)
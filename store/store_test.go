@@ -0,0 +1,25 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/el10savio/twoPSet-crdt/twopset"
+)
+
+// TestMemoryStore checks the basic functionality of the MemoryStore
+// nothing applied to it should be loaded back
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	assert.Nil(t, store.ApplyAdd("xx"))
+	assert.Nil(t, store.ApplyRemove("xx"))
+	assert.Nil(t, store.ApplyMerged(twopset.Initialize()))
+	assert.Nil(t, store.ApplyPurged(twopset.Initialize()))
+	assert.Nil(t, store.Snapshot(twopset.Initialize(), twopset.Metadata{}))
+
+	actualValue, actualError := store.Load()
+	assert.Nil(t, actualError)
+	assert.Equal(t, State{TwoPSet: twopset.Initialize()}, actualValue)
+}
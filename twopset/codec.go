@@ -0,0 +1,392 @@
+package twopset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"sort"
+)
+
+// The following implements the compact versioned binary encoding of the
+// TwoPSet & its garbage collection Metadata. An encoding is laid out as
+//
+//	magic (3 bytes) | format version (1 byte) | body | CRC-32 IEEE (4 bytes)
+//
+// where the body is made of uvarint length prefixed strings & counts,
+// with every collection sorted so equal states always encode the same
+// The checksum covers everything preceding it
+// A TwoPSet sent along with its Metadata is encoded as a state made of
+// the length prefixed encodings of both, the Metadata left empty if none
+
+const (
+	// MediaType is the media type of the
+	// binary encoding used in content negotiation
+	MediaType = "application/vnd.twopset"
+
+	// StateMediaType is the media type of the binary
+	// encoding of a TwoPSet along with its Metadata
+	StateMediaType = "application/vnd.twopset.state"
+
+	// FormatVersion is the version of
+	// the binary encoding written
+	FormatVersion = 1
+)
+
+var (
+	// twoPSetMagic prefixes a binary encoded TwoPSet
+	twoPSetMagic = []byte("2PS")
+	// metadataMagic prefixes a binary encoded Metadata
+	metadataMagic = []byte("2PG")
+	// stateMagic prefixes a binary encoded
+	// TwoPSet along with its Metadata
+	stateMagic = []byte("2PT")
+
+	// ErrChecksum is returned when decoding
+	// an encoding whose checksum does not match
+	ErrChecksum = errors.New("invalid binary checksum")
+	// ErrFormatVersion is returned when decoding an
+	// encoding of a format version not supported
+	ErrFormatVersion = errors.New("unsupported binary format version")
+)
+
+// MarshalBinary encodes the TwoPSet using the binary encoding
+func (twopset TwoPSet) MarshalBinary() ([]byte, error) {
+	encoder := newEncoder(twoPSetMagic)
+	encoder.strings(twopset.Add.Values())
+	encoder.strings(twopset.Remove.Values())
+	return encoder.finish(), nil
+}
+
+// UnmarshalBinary decodes a TwoPSet from the binary encoding
+func (twopset *TwoPSet) UnmarshalBinary(data []byte) error {
+	decoder, err := newDecoder(data, twoPSetMagic)
+	if err != nil {
+		return err
+	}
+
+	add, err := decoder.strings()
+	if err != nil {
+		return err
+	}
+	remove, err := decoder.strings()
+	if err != nil {
+		return err
+	}
+	if err := decoder.finish(); err != nil {
+		return err
+	}
+
+	*twopset = TwoPSet{Add: NewGSet(add...), Remove: NewGSet(remove...)}
+	return nil
+}
+
+// MarshalState encodes the TwoPSet along with its garbage
+// collection Metadata, if any, using the binary encoding
+func MarshalState(twopset TwoPSet, metadata *Metadata) ([]byte, error) {
+	set, err := twopset.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	var gc []byte
+	if metadata != nil {
+		if gc, err = metadata.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+
+	encoder := newEncoder(stateMagic)
+	encoder.string(string(set))
+	encoder.string(string(gc))
+	return encoder.finish(), nil
+}
+
+// UnmarshalState decodes a TwoPSet along with its garbage collection
+// Metadata from the binary encoding, the Metadata is nil if none
+func UnmarshalState(data []byte) (TwoPSet, *Metadata, error) {
+	decoder, err := newDecoder(data, stateMagic)
+	if err != nil {
+		return TwoPSet{}, nil, err
+	}
+
+	set, err := decoder.string()
+	if err != nil {
+		return TwoPSet{}, nil, err
+	}
+	gc, err := decoder.string()
+	if err != nil {
+		return TwoPSet{}, nil, err
+	}
+	if err := decoder.finish(); err != nil {
+		return TwoPSet{}, nil, err
+	}
+
+	var twopset TwoPSet
+	if err := twopset.UnmarshalBinary([]byte(set)); err != nil {
+		return TwoPSet{}, nil, err
+	}
+	if gc == "" {
+		return twopset, nil, nil
+	}
+
+	metadata := &Metadata{}
+	if err := metadata.UnmarshalBinary([]byte(gc)); err != nil {
+		return TwoPSet{}, nil, err
+	}
+	return twopset, metadata, nil
+}
+
+// MarshalBinary encodes the Metadata using the binary encoding
+func (metadata Metadata) MarshalBinary() ([]byte, error) {
+	encoder := newEncoder(metadataMagic)
+	encoder.string(metadata.Node)
+
+	values := make([]string, 0, len(metadata.Dots))
+	for value := range metadata.Dots {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	encoder.uvarint(uint64(len(values)))
+	for _, value := range values {
+		removals := append([]Dot{}, metadata.Dots[value]...)
+		sort.Slice(removals, func(i, j int) bool {
+			if removals[i].Node != removals[j].Node {
+				return removals[i].Node < removals[j].Node
+			}
+			return removals[i].Sequence < removals[j].Sequence
+		})
+
+		encoder.string(value)
+		encoder.uvarint(uint64(len(removals)))
+		for _, dot := range removals {
+			encoder.string(dot.Node)
+			encoder.uvarint(dot.Sequence)
+		}
+	}
+
+	nodes := make([]string, 0, len(metadata.Version))
+	for node := range metadata.Version {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	encoder.uvarint(uint64(len(nodes)))
+	for _, node := range nodes {
+		encoder.string(node)
+		encoder.uvarint(metadata.Version[node])
+	}
+
+	// The values purged are only written if there are any
+	if len(metadata.Purged) != 0 {
+		purged := append([]string{}, metadata.Purged...)
+		sort.Strings(purged)
+
+		encoder.uvarint(uint64(len(purged)))
+		for _, value := range purged {
+			encoder.string(value)
+		}
+	}
+
+	return encoder.finish(), nil
+}
+
+// UnmarshalBinary decodes a Metadata from the binary encoding
+func (metadata *Metadata) UnmarshalBinary(data []byte) error {
+	decoder, err := newDecoder(data, metadataMagic)
+	if err != nil {
+		return err
+	}
+
+	decoded := Metadata{Dots: make(map[string][]Dot), Version: make(VersionVector)}
+	if decoded.Node, err = decoder.string(); err != nil {
+		return err
+	}
+
+	values, err := decoder.count()
+	if err != nil {
+		return err
+	}
+	for index := uint64(0); index < values; index++ {
+		value, err := decoder.string()
+		if err != nil {
+			return err
+		}
+		removals, err := decoder.count()
+		if err != nil {
+			return err
+		}
+		for removal := uint64(0); removal < removals; removal++ {
+			var dot Dot
+			if dot.Node, err = decoder.string(); err != nil {
+				return err
+			}
+			if dot.Sequence, err = decoder.uvarint(); err != nil {
+				return err
+			}
+			decoded.Dots[value] = append(decoded.Dots[value], dot)
+		}
+	}
+
+	nodes, err := decoder.count()
+	if err != nil {
+		return err
+	}
+	for index := uint64(0); index < nodes; index++ {
+		node, err := decoder.string()
+		if err != nil {
+			return err
+		}
+		if decoded.Version[node], err = decoder.uvarint(); err != nil {
+			return err
+		}
+	}
+
+	// Metadata without any value
+	// purged ends after the Version
+	if len(decoder.body) != 0 {
+		purged, err := decoder.count()
+		if err != nil {
+			return err
+		}
+		decoded.Purged = make([]string, 0, purged)
+		for index := uint64(0); index < purged; index++ {
+			value, err := decoder.string()
+			if err != nil {
+				return err
+			}
+			decoded.Purged = append(decoded.Purged, value)
+		}
+	}
+
+	if err := decoder.finish(); err != nil {
+		return err
+	}
+
+	*metadata = decoded
+	return nil
+}
+
+// encoder writes the binary encoding
+type encoder struct {
+	buffer  bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+// newEncoder returns an encoder with the
+// magic & format version header written
+func newEncoder(magic []byte) *encoder {
+	encoder := &encoder{}
+	encoder.buffer.Write(magic)
+	encoder.buffer.WriteByte(FormatVersion)
+	return encoder
+}
+
+func (encoder *encoder) uvarint(value uint64) {
+	length := binary.PutUvarint(encoder.scratch[:], value)
+	encoder.buffer.Write(encoder.scratch[:length])
+}
+
+func (encoder *encoder) string(value string) {
+	encoder.uvarint(uint64(len(value)))
+	encoder.buffer.WriteString(value)
+}
+
+func (encoder *encoder) strings(values []string) {
+	encoder.uvarint(uint64(len(values)))
+	for _, value := range values {
+		encoder.string(value)
+	}
+}
+
+// finish appends the checksum & returns the encoding
+func (encoder *encoder) finish() []byte {
+	checksum := crc32.ChecksumIEEE(encoder.buffer.Bytes())
+	binary.BigEndian.PutUint32(encoder.scratch[:4], checksum)
+	encoder.buffer.Write(encoder.scratch[:4])
+	return encoder.buffer.Bytes()
+}
+
+// decoder reads the binary encoding
+type decoder struct {
+	body []byte
+}
+
+// newDecoder returns a decoder for the body of the
+// encoding after checking its header & checksum
+func newDecoder(data []byte, magic []byte) (*decoder, error) {
+	if len(data) < len(magic)+1+4 {
+		return nil, errors.New("invalid binary encoding: too short")
+	}
+	if !bytes.Equal(data[:len(magic)], magic) {
+		return nil, errors.New("invalid binary encoding: bad magic")
+	}
+
+	content, trailer := data[:len(data)-4], data[len(data)-4:]
+	if crc32.ChecksumIEEE(content) != binary.BigEndian.Uint32(trailer) {
+		return nil, ErrChecksum
+	}
+
+	if content[len(magic)] != FormatVersion {
+		return nil, ErrFormatVersion
+	}
+
+	return &decoder{body: content[len(magic)+1:]}, nil
+}
+
+func (decoder *decoder) uvarint() (uint64, error) {
+	value, length := binary.Uvarint(decoder.body)
+	if length <= 0 {
+		return 0, errors.New("invalid binary encoding: bad varint")
+	}
+	decoder.body = decoder.body[length:]
+	return value, nil
+}
+
+// count reads the number of entries that follow, which
+// can not exceed the bytes left as each takes at least one
+func (decoder *decoder) count() (uint64, error) {
+	count, err := decoder.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if count > uint64(len(decoder.body)) {
+		return 0, errors.New("invalid binary encoding: bad count")
+	}
+	return count, nil
+}
+
+func (decoder *decoder) string() (string, error) {
+	length, err := decoder.count()
+	if err != nil {
+		return "", err
+	}
+	value := string(decoder.body[:length])
+	decoder.body = decoder.body[length:]
+	return value, nil
+}
+
+func (decoder *decoder) strings() ([]string, error) {
+	count, err := decoder.count()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, count)
+	for index := uint64(0); index < count; index++ {
+		value, err := decoder.string()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// finish checks the whole body has been read
+func (decoder *decoder) finish() error {
+	if len(decoder.body) != 0 {
+		return errors.New("invalid binary encoding: trailing bytes")
+	}
+	return nil
+}
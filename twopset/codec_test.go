@@ -0,0 +1,116 @@
+package twopset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMarshalBinary checks the basic functionality of TwoPSet MarshalBinary()
+// a TwoPSet should survive a binary round trip
+func TestMarshalBinary(t *testing.T) {
+	set := TwoPSet{Add: NewGSet("xx", "yy", "zz"), Remove: NewGSet("xx")}
+
+	encoded, actualError := set.MarshalBinary()
+	assert.Nil(t, actualError)
+
+	var decoded TwoPSet
+	actualError = decoded.UnmarshalBinary(encoded)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, set, decoded)
+}
+
+// TestMarshalBinary_Canonical checks the functionality of TwoPSet MarshalBinary()
+// equal TwoPSets should have the same encoding irrespective of insertion order
+func TestMarshalBinary_Canonical(t *testing.T) {
+	a, _ := TwoPSet{Add: NewGSet("yy", "xx"), Remove: NewGSet()}.MarshalBinary()
+	b, _ := TwoPSet{Add: NewGSet("xx", "yy")}.MarshalBinary()
+
+	expectedValue := []byte{'2', 'P', 'S', FormatVersion, 2, 2, 'x', 'x', 2, 'y', 'y', 0}
+
+	assert.Equal(t, a, b)
+	assert.Equal(t, expectedValue, a[:len(a)-4])
+}
+
+// TestUnmarshalBinary_Corrupt checks the functionality of TwoPSet UnmarshalBinary()
+// when the encoding is corrupt, truncated or of another version, it should return an error
+func TestUnmarshalBinary_Corrupt(t *testing.T) {
+	encoded, _ := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}.MarshalBinary()
+	var decoded TwoPSet
+
+	corrupt := append([]byte{}, encoded...)
+	corrupt[5] = 'z'
+	assert.Equal(t, ErrChecksum, decoded.UnmarshalBinary(corrupt))
+
+	assert.Error(t, decoded.UnmarshalBinary(encoded[:6]))
+	assert.Error(t, decoded.UnmarshalBinary([]byte(`{"add":{"set":[]},"remove":{"set":[]}}`)))
+
+	future := newEncoder(twoPSetMagic)
+	future.buffer.Bytes()[3] = FormatVersion + 1
+	future.strings([]string{})
+	future.strings([]string{})
+	assert.Equal(t, ErrFormatVersion, decoded.UnmarshalBinary(future.finish()))
+}
+
+// TestUnmarshalBinary_BadCount checks the functionality of TwoPSet UnmarshalBinary()
+// when a count exceeds the bytes left, it should return an error without allocating
+func TestUnmarshalBinary_BadCount(t *testing.T) {
+	encoder := newEncoder(twoPSetMagic)
+	encoder.uvarint(1 << 40)
+
+	var decoded TwoPSet
+	assert.EqualError(t, decoded.UnmarshalBinary(encoder.finish()), "invalid binary encoding: bad count")
+}
+
+// TestMetadata_MarshalBinary checks that the garbage collection
+// Metadata survives a binary round trip
+func TestMetadata_MarshalBinary(t *testing.T) {
+	metadata := Metadata{
+		Node: "peer-0",
+		Dots: map[string][]Dot{
+			"xx": {{Node: "peer-1", Sequence: 2}, {Node: "peer-0", Sequence: 1}},
+		},
+		Version: VersionVector{"peer-0": 1, "peer-1": 2},
+	}
+
+	encoded, actualError := metadata.MarshalBinary()
+	assert.Nil(t, actualError)
+
+	var decoded Metadata
+	actualError = decoded.UnmarshalBinary(encoded)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, metadata.Node, decoded.Node)
+	assert.Equal(t, metadata.Version, decoded.Version)
+	assert.ElementsMatch(t, metadata.Dots["xx"], decoded.Dots["xx"])
+
+	var set TwoPSet
+	assert.Error(t, set.UnmarshalBinary(encoded))
+}
+
+// TestMarshalState checks the basic functionality of MarshalState() & UnmarshalState()
+// a TwoPSet should survive a binary round trip with & without its Metadata
+// and the encoding of a TwoPSet alone should not be taken for a state
+func TestMarshalState(t *testing.T) {
+	set := TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet("xx")}
+	metadata := &Metadata{
+		Node:    "peer-0",
+		Dots:    map[string][]Dot{"xx": {{Node: "peer-0", Sequence: 1}}},
+		Version: VersionVector{"peer-0": 1},
+	}
+
+	for _, expectedValue := range []*Metadata{metadata, nil} {
+		encoded, actualError := MarshalState(set, expectedValue)
+		assert.Nil(t, actualError)
+
+		decoded, actualValue, actualError := UnmarshalState(encoded)
+		assert.Nil(t, actualError)
+		assert.Equal(t, set, decoded)
+		assert.Equal(t, expectedValue, actualValue)
+	}
+
+	encoded, _ := set.MarshalBinary()
+	_, _, actualError := UnmarshalState(encoded)
+	assert.Error(t, actualError)
+}
@@ -0,0 +1,87 @@
+package twopset
+
+// The following implements the partial order of TwoPSet states
+// A TwoPSet is less than or equal to another when both its Add &
+// Remove GSets are subsets of the other's, i.e. the other has
+// observed every Addition & Removal it has
+
+// Ordering is the result of comparing two TwoPSets
+type Ordering int
+
+const (
+	// OrderEqual indicates both TwoPSets
+	// have the same Add & Remove GSets
+	OrderEqual Ordering = iota
+	// OrderLess indicates the first TwoPSet
+	// is behind the second one
+	OrderLess
+	// OrderGreater indicates the first TwoPSet
+	// is ahead of the second one
+	OrderGreater
+	// OrderConcurrent indicates each TwoPSet has
+	// values the other has not observed
+	OrderConcurrent
+)
+
+// String returns the name of the Ordering
+func (ordering Ordering) String() string {
+	switch ordering {
+	case OrderEqual:
+		return "equal"
+	case OrderLess:
+		return "less"
+	case OrderGreater:
+		return "greater"
+	case OrderConcurrent:
+		return "concurrent"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the Ordering as its name
+func (ordering Ordering) MarshalText() ([]byte, error) {
+	return []byte(ordering.String()), nil
+}
+
+// IsSubset returns true if every value in
+// the GSet is present in the other GSet
+func (gset GSet) IsSubset(other GSet) bool {
+	if len(gset) > len(other) {
+		return false
+	}
+	for value := range gset {
+		if !other.Contains(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// LessOrEqual returns true if every Addition & Removal
+// in the TwoPSet has been observed by the other TwoPSet
+func (twopset TwoPSet) LessOrEqual(other TwoPSet) bool {
+	return twopset.Add.IsSubset(other.Add) && twopset.Remove.IsSubset(other.Remove)
+}
+
+// Compare returns the Ordering of the TwoPSet a relative
+// to the TwoPSet b in the lattice of TwoPSet states
+func Compare(a TwoPSet, b TwoPSet) Ordering {
+	aLessOrEqual := a.LessOrEqual(b)
+	bLessOrEqual := b.LessOrEqual(a)
+
+	switch {
+	case aLessOrEqual && bLessOrEqual:
+		return OrderEqual
+	case aLessOrEqual:
+		return OrderLess
+	case bLessOrEqual:
+		return OrderGreater
+	}
+	return OrderConcurrent
+}
+
+// Equal returns true if both TwoPSets have the same Add & Remove
+// GSets irrespective of ordering or them being nil or empty
+func Equal(a TwoPSet, b TwoPSet) bool {
+	return Compare(a, b) == OrderEqual
+}
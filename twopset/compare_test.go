@@ -0,0 +1,59 @@
+package twopset
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCompare checks the basic functionality of Compare()
+// it should order TwoPSets by the Additions & Removals observed
+func TestCompare(t *testing.T) {
+	behind := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+	ahead := TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet("xx")}
+
+	assert.Equal(t, OrderLess, Compare(behind, ahead))
+	assert.Equal(t, OrderGreater, Compare(ahead, behind))
+	assert.Equal(t, OrderEqual, Compare(ahead, ahead.Copy()))
+}
+
+// TestCompare_Concurrent checks the functionality of Compare()
+// when each TwoPSet has values the other lacks, they are concurrent
+// and merging them should be greater than both
+func TestCompare_Concurrent(t *testing.T) {
+	a := TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet()}
+	b := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet("yy")}
+
+	assert.Equal(t, OrderConcurrent, Compare(a, b))
+	assert.Equal(t, OrderGreater, Compare(Merge(a, b), a))
+	assert.Equal(t, OrderGreater, Compare(Merge(a, b), b))
+}
+
+// TestCompare_RemoveOnly checks the functionality of Compare()
+// when only the Remove GSets differ, the TwoPSets are still ordered
+func TestCompare_RemoveOnly(t *testing.T) {
+	a := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet("xx")}
+	b := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+
+	assert.Equal(t, OrderGreater, Compare(a, b))
+}
+
+// TestEqual checks the basic functionality of Equal()
+// it should ignore the ordering of values and nil GSets
+func TestEqual(t *testing.T) {
+	var decoded TwoPSet
+	json.Unmarshal([]byte(`{"add":{"set":["yy","xx"]},"remove":{"set":[]}}`), &decoded)
+
+	assert.True(t, Equal(decoded, TwoPSet{Add: NewGSet("xx", "yy")}))
+	assert.True(t, Equal(TwoPSet{}, Initialize()))
+	assert.False(t, Equal(decoded, Initialize()))
+}
+
+// TestOrdering_String checks the names of the Orderings
+func TestOrdering_String(t *testing.T) {
+	encoded, _ := json.Marshal(map[string]Ordering{"order": OrderConcurrent})
+
+	assert.Equal(t, "less", OrderLess.String())
+	assert.Equal(t, `{"order":"concurrent"}`, string(encoded))
+}
@@ -0,0 +1,48 @@
+package twopset
+
+import (
+	"testing"
+
+	"github.com/el10savio/twoPSet-crdt/crdttest"
+)
+
+// conformance adapts the TwoPSet to the
+// crdttest CRDT interface
+type conformance struct {
+	set TwoPSet
+}
+
+func (state conformance) Merge(other crdttest.CRDT) crdttest.CRDT {
+	return conformance{Merge(state.set, other.(conformance).set)}
+}
+
+func (state conformance) LessOrEqual(other crdttest.CRDT) bool {
+	return state.set.LessOrEqual(other.(conformance).set)
+}
+
+func (state conformance) Equal(other crdttest.CRDT) bool {
+	return Equal(state.set, other.(conformance).set)
+}
+
+func (state conformance) Copy() crdttest.CRDT {
+	return conformance{state.set.Copy()}
+}
+
+// TestConformance checks the TwoPSet satisfies the CRDT laws
+// for random states built from Additions & Removals
+func TestConformance(t *testing.T) {
+	crdttest.Run(t, crdttest.Config{
+		Empty: func() crdttest.CRDT { return conformance{Initialize()} },
+		Mutators: map[string]crdttest.Mutator{
+			"addition": func(state crdttest.CRDT, value string) (crdttest.CRDT, error) {
+				set, err := state.(conformance).set.Addition(value)
+				return conformance{set}, err
+			},
+			"removal": func(state crdttest.CRDT, value string) (crdttest.CRDT, error) {
+				set, err := state.(conformance).set.Removal(value)
+				return conformance{set}, err
+			},
+		},
+		Seed: 2020,
+	})
+}
@@ -0,0 +1,245 @@
+package twopset
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// The following implements delta-state support for the TwoPSet
+// A delta is itself a TwoPSet holding only the values changed by
+// a mutation, which can be joined into any replica using MergeDelta
+// The DeltaBuffer keeps the deltas not yet acknowledged by each peer
+// so that only the changes are shipped during replication
+
+// AdditionDelta adds a value to the TwoPSet like Addition
+// and also returns the delta TwoPSet for the change
+func (twopset TwoPSet) AdditionDelta(value string) (TwoPSet, TwoPSet, error) {
+	twopset, err := twopset.Addition(value)
+	if err != nil {
+		return twopset, Initialize(), err
+	}
+
+	// The delta only contains the value added
+	delta := TwoPSet{Add: NewGSet(value), Remove: NewGSet()}
+
+	return twopset, delta, nil
+}
+
+// RemovalDelta removes a value from the TwoPSet like Removal
+// and also returns the delta TwoPSet for the change
+func (twopset TwoPSet) RemovalDelta(value string) (TwoPSet, TwoPSet, error) {
+	twopset, err := twopset.Removal(value)
+	if err != nil {
+		return twopset, Initialize(), err
+	}
+
+	// The delta only contains the value removed
+	delta := TwoPSet{Add: NewGSet(), Remove: NewGSet(value)}
+
+	return twopset, delta, nil
+}
+
+// MergeDelta joins the given deltas into the TwoPSet
+// Unlike Merge it updates the TwoPSet in place,
+// so its cost is linear in the size of the deltas
+func MergeDelta(twopset TwoPSet, deltas ...TwoPSet) TwoPSet {
+	twopset = twopset.init()
+
+	// Set = Set U Delta
+	for _, delta := range deltas {
+		for value := range delta.Add {
+			twopset.Add.Insert(value)
+		}
+		for value := range delta.Remove {
+			twopset.Remove.Insert(value)
+		}
+	}
+
+	return twopset
+}
+
+// deltaEntry is a delta stored in the
+// DeltaBuffer along with its sequence number
+type deltaEntry struct {
+	sequence uint64
+	delta    TwoPSet
+}
+
+// DeltaBuffer accumulates the deltas produced by a node
+// and tracks per peer which of them have been acknowledged
+// It is safe for concurrent use
+type DeltaBuffer struct {
+	mutex sync.Mutex
+	// sequence is the sequence number
+	// of the latest delta pushed
+	sequence uint64
+	// entries are the deltas not yet
+	// acknowledged by every peer
+	entries []deltaEntry
+	// acknowledged is the latest sequence
+	// number acknowledged by each peer
+	acknowledged map[string]uint64
+}
+
+// NewDeltaBuffer returns a new empty
+// DeltaBuffer for the given peers
+func NewDeltaBuffer(peers ...string) *DeltaBuffer {
+	buffer := &DeltaBuffer{acknowledged: make(map[string]uint64)}
+	for _, peer := range peers {
+		buffer.acknowledged[peer] = 0
+	}
+	return buffer
+}
+
+// AddPeer starts tracking deltas for a new peer
+// A new peer has acknowledged nothing, so the
+// caller should ship it the full state first
+// and then Acknowledge the current Sequence()
+func (buffer *DeltaBuffer) AddPeer(peer string) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	if _, exists := buffer.acknowledged[peer]; !exists {
+		buffer.acknowledged[peer] = 0
+	}
+}
+
+// RemovePeer stops tracking deltas for a peer
+func (buffer *DeltaBuffer) RemovePeer(peer string) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	delete(buffer.acknowledged, peer)
+	buffer.compact()
+}
+
+// Push appends a delta to the buffer and
+// returns the sequence number assigned to it
+func (buffer *DeltaBuffer) Push(delta TwoPSet) uint64 {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	buffer.sequence++
+	buffer.entries = append(buffer.entries, deltaEntry{
+		sequence: buffer.sequence,
+		delta:    delta.Copy(),
+	})
+
+	return buffer.sequence
+}
+
+// Sequence returns the sequence number
+// of the latest delta pushed
+func (buffer *DeltaBuffer) Sequence() uint64 {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	return buffer.sequence
+}
+
+// Pending returns the join of all the deltas not yet
+// acknowledged by the peer along with the sequence
+// number to Acknowledge once it has been delivered
+func (buffer *DeltaBuffer) Pending(peer string) (TwoPSet, uint64, error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	acknowledged, exists := buffer.acknowledged[peer]
+	if !exists {
+		return Initialize(), 0, errors.New("unknown peer provided")
+	}
+
+	group := Initialize()
+	for _, entry := range buffer.entries {
+		if entry.sequence <= acknowledged {
+			continue
+		}
+		group = MergeDelta(group, entry.delta)
+	}
+
+	return group, buffer.sequence, nil
+}
+
+// Acknowledge marks all the deltas up to the given sequence
+// number as delivered to the peer and discards the
+// deltas that have been acknowledged by every peer
+func (buffer *DeltaBuffer) Acknowledge(peer string, sequence uint64) error {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	acknowledged, exists := buffer.acknowledged[peer]
+	if !exists {
+		return errors.New("unknown peer provided")
+	}
+
+	if sequence > buffer.sequence {
+		return errors.New("invalid sequence number provided")
+	}
+
+	// Acknowledgements can arrive out of order
+	// so only ever move the peer forward
+	if sequence > acknowledged {
+		buffer.acknowledged[peer] = sequence
+	}
+
+	buffer.compact()
+	return nil
+}
+
+// Drop discards the oldest deltas so at most the given number
+// of deltas are buffered. The peers that had not acknowledged
+// the deltas discarded are returned, as they can only catch up
+// by being shipped the full state like a new peer
+func (buffer *DeltaBuffer) Drop(limit int) []string {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	if limit < 0 {
+		limit = 0
+	}
+	if len(buffer.entries) <= limit {
+		return []string{}
+	}
+
+	// The sequence number of the latest delta discarded
+	discarded := buffer.entries[len(buffer.entries)-limit-1].sequence
+
+	peers := []string{}
+	for peer, acknowledged := range buffer.acknowledged {
+		if acknowledged < discarded {
+			buffer.acknowledged[peer] = discarded
+			peers = append(peers, peer)
+		}
+	}
+	sort.Strings(peers)
+
+	buffer.entries = buffer.entries[len(buffer.entries)-limit:]
+	return peers
+}
+
+// Len returns the number of deltas in the buffer
+func (buffer *DeltaBuffer) Len() int {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	return len(buffer.entries)
+}
+
+// compact discards the deltas acknowledged by every peer
+// It must be called with the mutex held
+func (buffer *DeltaBuffer) compact() {
+	minimum := buffer.sequence
+	for _, acknowledged := range buffer.acknowledged {
+		if acknowledged < minimum {
+			minimum = acknowledged
+		}
+	}
+
+	index := 0
+	for index < len(buffer.entries) && buffer.entries[index].sequence <= minimum {
+		index++
+	}
+
+	buffer.entries = buffer.entries[index:]
+}
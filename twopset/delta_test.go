@@ -0,0 +1,165 @@
+package twopset
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAdditionDelta checks the basic functionality of TwoPSet AdditionDelta()
+// it should return the updated TwoPSet along with a delta holding only the value added
+func TestAdditionDelta(t *testing.T) {
+	set := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+
+	actualValue, actualDelta, actualError := set.AdditionDelta("yy")
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet()}, actualValue)
+	assert.Equal(t, TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()}, actualDelta)
+}
+
+// TestAdditionDelta_NoValue checks the functionality of TwoPSet AdditionDelta()
+// when a nil value is passed to it, it should return an empty delta along with an error
+func TestAdditionDelta_NoValue(t *testing.T) {
+	_, actualDelta, actualError := Initialize().AdditionDelta("")
+
+	assert.Equal(t, errors.New("empty value provided"), actualError)
+	assert.Equal(t, Initialize(), actualDelta)
+}
+
+// TestRemovalDelta checks the basic functionality of TwoPSet RemovalDelta()
+// it should return the updated TwoPSet along with a delta holding only the value removed
+func TestRemovalDelta(t *testing.T) {
+	set := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+
+	actualValue, actualDelta, actualError := set.RemovalDelta("xx")
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, TwoPSet{Add: NewGSet("xx"), Remove: NewGSet("xx")}, actualValue)
+	assert.Equal(t, TwoPSet{Add: NewGSet(), Remove: NewGSet("xx")}, actualDelta)
+}
+
+// TestMergeDelta checks the basic functionality of MergeDelta()
+// joining the deltas of a replica should converge to its full state
+func TestMergeDelta(t *testing.T) {
+	replica := Initialize()
+	deltas := []TwoPSet{}
+
+	var delta TwoPSet
+	replica, delta, _ = replica.AdditionDelta("xx")
+	deltas = append(deltas, delta)
+	replica, delta, _ = replica.AdditionDelta("yy")
+	deltas = append(deltas, delta)
+	replica, delta, _ = replica.RemovalDelta("xx")
+	deltas = append(deltas, delta)
+
+	actualValue := MergeDelta(TwoPSet{}, deltas...)
+
+	assert.Equal(t, replica, actualValue)
+	assert.Equal(t, []string{"yy"}, actualValue.List())
+}
+
+// TestMergeDelta_Idempotent checks the functionality of MergeDelta()
+// when the same delta is joined multiple times the state should not change
+func TestMergeDelta_Idempotent(t *testing.T) {
+	delta := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+
+	actualValue := MergeDelta(Initialize(), delta, delta, delta)
+
+	assert.Equal(t, TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}, actualValue)
+}
+
+// TestDeltaBuffer checks the basic functionality of the DeltaBuffer
+// Pending() should return the join of the unacknowledged deltas for each peer
+func TestDeltaBuffer(t *testing.T) {
+	buffer := NewDeltaBuffer("peer-1", "peer-2")
+
+	buffer.Push(TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()})
+	buffer.Push(TwoPSet{Add: NewGSet(), Remove: NewGSet("xx")})
+
+	actualValue, actualSequence, actualError := buffer.Pending("peer-1")
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, uint64(2), actualSequence)
+	assert.Equal(t, TwoPSet{Add: NewGSet("xx"), Remove: NewGSet("xx")}, actualValue)
+}
+
+// TestDeltaBuffer_Acknowledge checks the functionality of the DeltaBuffer
+// when a peer acknowledges deltas, they should no longer be pending for it
+// and be discarded once every peer has acknowledged them
+func TestDeltaBuffer_Acknowledge(t *testing.T) {
+	buffer := NewDeltaBuffer("peer-1", "peer-2")
+
+	first := buffer.Push(TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()})
+	buffer.Push(TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()})
+
+	assert.Nil(t, buffer.Acknowledge("peer-1", first))
+	assert.Equal(t, 2, buffer.Len())
+
+	actualValue, _, _ := buffer.Pending("peer-1")
+	assert.Equal(t, TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()}, actualValue)
+
+	assert.Nil(t, buffer.Acknowledge("peer-2", first))
+	assert.Equal(t, 1, buffer.Len())
+
+	assert.Nil(t, buffer.Acknowledge("peer-1", buffer.Sequence()))
+	assert.Nil(t, buffer.Acknowledge("peer-2", buffer.Sequence()))
+	assert.Equal(t, 0, buffer.Len())
+
+	actualValue, _, _ = buffer.Pending("peer-2")
+	assert.Equal(t, Initialize(), actualValue)
+}
+
+// TestDeltaBuffer_UnknownPeer checks the functionality of the DeltaBuffer
+// when an untracked peer is passed, it should return an error
+func TestDeltaBuffer_UnknownPeer(t *testing.T) {
+	buffer := NewDeltaBuffer("peer-1")
+
+	_, _, actualError := buffer.Pending("peer-2")
+	assert.Equal(t, errors.New("unknown peer provided"), actualError)
+
+	actualError = buffer.Acknowledge("peer-2", 0)
+	assert.Equal(t, errors.New("unknown peer provided"), actualError)
+
+	actualError = buffer.Acknowledge("peer-1", 5)
+	assert.Equal(t, errors.New("invalid sequence number provided"), actualError)
+}
+
+// TestDeltaBuffer_RemovePeer checks the functionality of the DeltaBuffer
+// when a lagging peer is removed, its pending deltas should be discarded
+func TestDeltaBuffer_RemovePeer(t *testing.T) {
+	buffer := NewDeltaBuffer("peer-1", "peer-2")
+
+	sequence := buffer.Push(TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()})
+	buffer.Acknowledge("peer-1", sequence)
+
+	buffer.RemovePeer("peer-2")
+
+	assert.Equal(t, 0, buffer.Len())
+}
+
+// TestDeltaBuffer_Drop checks the functionality of DeltaBuffer Drop()
+// the oldest deltas should be discarded and only the peers
+// that had not acknowledged them should be returned
+func TestDeltaBuffer_Drop(t *testing.T) {
+	buffer := NewDeltaBuffer("peer-1", "peer-2", "peer-3")
+
+	buffer.Push(TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()})
+	sequence := buffer.Push(TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()})
+	buffer.Push(TwoPSet{Add: NewGSet("zz"), Remove: NewGSet()})
+
+	buffer.Acknowledge("peer-1", sequence)
+	buffer.Acknowledge("peer-2", 1)
+
+	assert.Equal(t, []string{}, buffer.Drop(3))
+	assert.Equal(t, []string{"peer-2", "peer-3"}, buffer.Drop(1))
+	assert.Equal(t, 1, buffer.Len())
+
+	expectedValue := TwoPSet{Add: NewGSet("zz"), Remove: NewGSet()}
+
+	for _, peer := range []string{"peer-1", "peer-2", "peer-3"} {
+		actualValue, _, _ := buffer.Pending(peer)
+		assert.Equal(t, expectedValue, actualValue)
+	}
+}
@@ -0,0 +1,29 @@
+package twopset
+
+// Diff returns the delta TwoPSet holding the Additions & Removals the
+// remote TwoPSet has that the local TwoPSet lacks, which is the minimal
+// delta to join into the local TwoPSet for it to observe the remote one
+// i.e. MergeDelta(local, Diff(local, remote)) equals Merge(local, remote)
+func Diff(local TwoPSet, remote TwoPSet) TwoPSet {
+	delta := Initialize()
+
+	for value := range remote.Add {
+		if !local.Add.Contains(value) {
+			delta.Add.Insert(value)
+		}
+	}
+
+	for value := range remote.Remove {
+		if !local.Remove.Contains(value) {
+			delta.Remove.Insert(value)
+		}
+	}
+
+	return delta
+}
+
+// IsEmpty returns true if the TwoPSet
+// has no Additions or Removals
+func (twopset TwoPSet) IsEmpty() bool {
+	return twopset.Add.Len() == 0 && twopset.Remove.Len() == 0
+}
@@ -0,0 +1,44 @@
+package twopset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDiff checks the basic functionality of Diff()
+// it should return only the Additions & Removals the remote
+// TwoPSet has that the local TwoPSet lacks
+func TestDiff(t *testing.T) {
+	local := TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet("yy")}
+	remote := TwoPSet{Add: NewGSet("xx", "zz"), Remove: NewGSet("xx")}
+
+	expectedValue := TwoPSet{Add: NewGSet("zz"), Remove: NewGSet("xx")}
+	actualValue := Diff(local, remote)
+
+	assert.Equal(t, expectedValue, actualValue)
+	assert.Equal(t, TwoPSet{Add: NewGSet("yy"), Remove: NewGSet("yy")}, Diff(remote, local))
+}
+
+// TestDiff_Merge checks the functionality of Diff()
+// joining the diff into the local TwoPSet should
+// be the same as merging the remote TwoPSet
+func TestDiff_Merge(t *testing.T) {
+	local := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+	remote := TwoPSet{Add: NewGSet("yy"), Remove: NewGSet("xx")}
+
+	expectedValue := Merge(local, remote)
+	actualValue := MergeDelta(local.Copy(), Diff(local, remote))
+
+	assert.Equal(t, expectedValue, actualValue)
+}
+
+// TestDiff_Empty checks the functionality of Diff()
+// when the remote TwoPSet is not ahead, the diff should be empty
+func TestDiff_Empty(t *testing.T) {
+	local := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet("xx")}
+
+	assert.True(t, Diff(local, local).IsEmpty())
+	assert.True(t, Diff(local, TwoPSet{}).IsEmpty())
+	assert.False(t, Diff(TwoPSet{}, local).IsEmpty())
+}
@@ -0,0 +1,413 @@
+package twopset
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// The following implements the garbage collection of TwoPSet tombstones
+// Each local Removal is identified by a Dot (replica, sequence number) and
+// every node keeps a VersionVector of the Dots it has observed
+// A replica is an incarnation of a node, so a node restarted without its
+// VersionVector issues Dots its peers can not have observed already
+// Nodes exchange their Dots & VersionVectors while syncing, so each node
+// learns which tombstones every other node has observed. Once every node
+// has observed all the Removals of a value they are causally stable,
+// no node can add the value back by merging, and both its add entry
+// & tombstone are purged
+// The Collector keeps the values purged, which replace both their add
+// entry & tombstone, so a value purged stays removed: Additions of it
+// are rejected and it is dropped from any TwoPSet merged in. They are
+// sent to peers along with the Metadata, so a node that missed the
+// Removal, such as one joining later, does not let the value back in
+
+// Dot identifies a Removal by the replica it was
+// issued on & its per replica sequence number
+type Dot struct {
+	Node     string `json:"node"`
+	Sequence uint64 `json:"sequence"`
+}
+
+// VersionVector maps each replica to the sequence
+// number of the latest Dot observed from it
+type VersionVector map[string]uint64
+
+// Covers returns true if the Dot has been observed
+func (version VersionVector) Covers(dot Dot) bool {
+	return version[dot.Node] >= dot.Sequence
+}
+
+// Copy returns an independent copy of the VersionVector
+func (version VersionVector) Copy() VersionVector {
+	copied := make(VersionVector, len(version))
+	for node, sequence := range version {
+		copied[node] = sequence
+	}
+	return copied
+}
+
+// Metadata is the garbage collection state
+// a node sends to its peers while syncing
+type Metadata struct {
+	// Node is the ID of the node
+	Node string `json:"node"`
+	// Dots maps each tombstone to the
+	// Removals of it known to the node
+	Dots map[string][]Dot `json:"dots"`
+	// Version is the VersionVector of
+	// the Dots observed by the node
+	Version VersionVector `json:"version"`
+	// Purged are the sorted values
+	// purged by the node, if any
+	Purged []string `json:"purged,omitempty"`
+}
+
+// Report summarizes the tombstones
+// tracked & purged by a Collector
+type Report struct {
+	// Node is the ID of the node
+	Node string `json:"node"`
+	// Tombstones is the number of
+	// tombstones in the TwoPSet
+	Tombstones int `json:"tombstones"`
+	// Tracked is the number of tombstones
+	// whose Removals are known
+	Tracked int `json:"tracked"`
+	// Stable is the number of tombstones
+	// observed by every node
+	Stable int `json:"stable"`
+	// Purged is the number of tombstones
+	// purged by this collection
+	Purged int `json:"purged"`
+	// TotalPurged is the number of tombstones
+	// purged since the node started
+	TotalPurged uint64 `json:"total_purged"`
+	// Unobserved are the nodes whose
+	// VersionVector is not known yet
+	Unobserved []string `json:"unobserved"`
+}
+
+// Collector tracks the causal stability of the tombstones
+// of a TwoPSet and purges them. It is safe for concurrent use
+type Collector struct {
+	mutex sync.Mutex
+	// node is the ID of the local node
+	node string
+	// replica is the ID the local
+	// Removals are issued with
+	replica string
+	// dots maps each tombstone to
+	// the Removals of it known
+	dots map[string][]Dot
+	// version is the VersionVector of
+	// the Dots observed locally
+	version VersionVector
+	// peers is the latest VersionVector
+	// received from each peer
+	peers map[string]VersionVector
+	// purged is the number of tombstones
+	// purged since the Collector started
+	purged uint64
+	// values are the values purged
+	values map[string]struct{}
+}
+
+// NewCollector returns a new Collector for the
+// given node issuing Dots with the node's ID
+func NewCollector(node string) (*Collector, error) {
+	return NewReplicaCollector(node, node)
+}
+
+// NewReplicaCollector returns a new Collector for the given
+// node issuing Dots with the replica's ID. A node keeping no
+// VersionVector across restarts should pass a replica ID
+// unique to each run, as peers have observed the Dots
+// issued before the restart with the same sequence numbers
+func NewReplicaCollector(node string, replica string) (*Collector, error) {
+	// Return an error if the node passed is nil
+	if node == "" {
+		return nil, errors.New("empty node provided")
+	}
+
+	// Return an error if the replica passed is nil
+	if replica == "" {
+		return nil, errors.New("empty replica provided")
+	}
+
+	return &Collector{
+		node:    node,
+		replica: replica,
+		dots:    make(map[string][]Dot),
+		version: make(VersionVector),
+		peers:   make(map[string]VersionVector),
+		values:  make(map[string]struct{}),
+	}, nil
+}
+
+// Purged returns true if the value has been removed
+// & purged, in which case it stays removed
+func (collector *Collector) Purged(value string) bool {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	_, purged := collector.values[value]
+	return purged
+}
+
+// Removal records a local Removal of the value
+// and returns the Dot identifying it
+func (collector *Collector) Removal(value string) (Dot, error) {
+	// Return an error if the value passed is nil
+	if value == "" {
+		return Dot{}, errors.New("empty value provided")
+	}
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	collector.version[collector.replica]++
+	dot := Dot{Node: collector.replica, Sequence: collector.version[collector.replica]}
+	collector.dots[value] = append(collector.dots[value], dot)
+
+	return dot, nil
+}
+
+// Metadata returns a copy of the garbage
+// collection state to send to peers
+func (collector *Collector) Metadata() Metadata {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	dots := make(map[string][]Dot, len(collector.dots))
+	for value, removals := range collector.dots {
+		dots[value] = append([]Dot{}, removals...)
+	}
+
+	var purged []string
+	if len(collector.values) != 0 {
+		purged = make([]string, 0, len(collector.values))
+		for value := range collector.values {
+			purged = append(purged, value)
+		}
+		sort.Strings(purged)
+	}
+
+	return Metadata{
+		Node:    collector.node,
+		Dots:    dots,
+		Version: collector.version.Copy(),
+		Purged:  purged,
+	}
+}
+
+// Restore replaces the Dots & VersionVector tracked with the ones
+// in the Metadata, such as the Metadata of a Snapshot of the node
+// and adds the values purged in it
+// The VersionVectors received from peers are kept
+func (collector *Collector) Restore(metadata Metadata) error {
+	// Return an error if the Metadata is of another node
+	if metadata.Node != collector.node {
+		return errors.New("metadata of another node provided: " + metadata.Node)
+	}
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	collector.dots = make(map[string][]Dot, len(metadata.Dots))
+	for value, removals := range metadata.Dots {
+		collector.dots[value] = append([]Dot{}, removals...)
+	}
+	collector.version = metadata.Version.Copy()
+	for _, value := range metadata.Purged {
+		collector.values[value] = struct{}{}
+	}
+
+	return nil
+}
+
+// Merge combines the local TwoPSet with a peer's TwoPSet & Metadata
+// Tombstones the peer sends back after they were purged locally are
+// dropped along with their add entries instead of being merged again
+// Peers not sending any Metadata are merged using Merge()
+// The values purged locally or by the peer are dropped in either case
+// The TwoPSets passed are left unmodified
+func (collector *Collector) Merge(local TwoPSet, peer TwoPSet, metadata *Metadata) TwoPSet {
+	merged := Merge(local, peer)
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	if metadata != nil {
+		for _, value := range metadata.Purged {
+			collector.values[value] = struct{}{}
+		}
+	}
+
+	collector.dropPurged(merged)
+	if metadata == nil {
+		return merged
+	}
+
+	for value, removals := range metadata.Dots {
+		// A tombstone unknown locally whose Removals have all been
+		// observed has been purged locally, so it is dropped
+		if !local.Remove.Contains(value) && collector.coversAll(removals) {
+			delete(merged.Add, value)
+			delete(merged.Remove, value)
+			continue
+		}
+
+		for _, dot := range removals {
+			collector.dots[value] = appendDot(collector.dots[value], dot)
+		}
+	}
+
+	// Observe the Dots the peer has observed
+	for node, sequence := range metadata.Version {
+		if sequence > collector.version[node] {
+			collector.version[node] = sequence
+		}
+	}
+
+	if metadata.Node != "" {
+		collector.peers[metadata.Node] = metadata.Version.Copy()
+	}
+
+	return merged
+}
+
+// Status reports on the tombstones of the TwoPSet
+// that are stable across the given nodes
+func (collector *Collector) Status(twopset TwoPSet, nodes []string) Report {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	report, _ := collector.stable(twopset, nodes)
+	return report
+}
+
+// Collect purges the add entries & tombstones of the TwoPSet
+// that are stable across the given nodes and reports on them
+// The TwoPSet is updated in place and returned
+func (collector *Collector) Collect(twopset TwoPSet, nodes []string) (TwoPSet, Report) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	report, stable := collector.stable(twopset, nodes)
+
+	for _, value := range stable {
+		delete(twopset.Add, value)
+		delete(twopset.Remove, value)
+		delete(collector.dots, value)
+		collector.values[value] = struct{}{}
+	}
+
+	collector.purged += uint64(len(stable))
+
+	report.Purged = len(stable)
+	report.TotalPurged = collector.purged
+
+	return twopset, report
+}
+
+// stable returns the tombstones of the TwoPSet whose
+// Removals have all been observed by the given nodes
+// It must be called with the mutex held
+func (collector *Collector) stable(twopset TwoPSet, nodes []string) (Report, []string) {
+	report := Report{
+		Node:        collector.node,
+		Tombstones:  twopset.Remove.Len(),
+		TotalPurged: collector.purged,
+		Unobserved:  []string{},
+	}
+
+	versions := []VersionVector{collector.version}
+	for _, node := range nodes {
+		if node == collector.node {
+			continue
+		}
+		version, exists := collector.peers[node]
+		if !exists {
+			report.Unobserved = append(report.Unobserved, node)
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	stable := []string{}
+	for value, removals := range collector.dots {
+		if !twopset.Remove.Contains(value) {
+			continue
+		}
+		report.Tracked++
+
+		// Nothing is stable until every node has
+		// reported the Dots it has observed
+		if len(report.Unobserved) != 0 {
+			continue
+		}
+
+		if observedByAll(versions, removals) {
+			stable = append(stable, value)
+		}
+	}
+
+	report.Stable = len(stable)
+	return report, stable
+}
+
+// dropPurged deletes the values purged
+// from the TwoPSet in place
+// It must be called with the mutex held
+func (collector *Collector) dropPurged(twopset TwoPSet) {
+	// Walk whichever of the values purged
+	// & the TwoPSet's entries is smaller
+	if len(collector.values) <= twopset.Add.Len()+twopset.Remove.Len() {
+		for value := range collector.values {
+			delete(twopset.Add, value)
+			delete(twopset.Remove, value)
+		}
+		return
+	}
+
+	for _, gset := range []GSet{twopset.Add, twopset.Remove} {
+		for value := range gset {
+			if _, purged := collector.values[value]; purged {
+				delete(gset, value)
+			}
+		}
+	}
+}
+
+// coversAll returns true if the Dots have all been observed locally
+// It must be called with the mutex held
+func (collector *Collector) coversAll(removals []Dot) bool {
+	if len(removals) == 0 {
+		return false
+	}
+	return observedByAll([]VersionVector{collector.version}, removals)
+}
+
+// observedByAll returns true if the Dots are
+// covered by every VersionVector passed
+func observedByAll(versions []VersionVector, removals []Dot) bool {
+	for _, version := range versions {
+		for _, dot := range removals {
+			if !version.Covers(dot) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// appendDot adds a Dot to the list if not already present
+func appendDot(removals []Dot, dot Dot) []Dot {
+	for _, removal := range removals {
+		if removal == dot {
+			return removals
+		}
+	}
+	return append(removals, dot)
+}
@@ -0,0 +1,257 @@
+package twopset
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gcNode is an in memory node used to
+// test the garbage collection of tombstones
+type gcNode struct {
+	set       TwoPSet
+	collector *Collector
+}
+
+// newGCNode returns a new empty gcNode
+func newGCNode(node string) *gcNode {
+	collector, _ := NewCollector(node)
+	return &gcNode{set: Initialize(), collector: collector}
+}
+
+// removal removes a value from the node
+func (node *gcNode) removal(value string) {
+	node.set, _ = node.set.Removal(value)
+	node.collector.Removal(value)
+}
+
+// pull merges the peer's TwoPSet & Metadata into the node
+func (node *gcNode) pull(peer *gcNode) {
+	metadata := peer.collector.Metadata()
+	node.set = node.collector.Merge(node.set, peer.set.Copy(), &metadata)
+}
+
+// TestCollector checks the basic functionality of the Collector
+// a tombstone should be purged only once every node has observed it
+func TestCollector(t *testing.T) {
+	nodes := []string{"peer-0", "peer-1"}
+	node0, node1 := newGCNode("peer-0"), newGCNode("peer-1")
+
+	node0.set, _ = node0.set.Addition("xx")
+	node0.set, _ = node0.set.Addition("yy")
+	node0.removal("xx")
+
+	// peer-1 has not reported what it has observed yet
+	_, report := node0.collector.Collect(node0.set, nodes)
+	assert.Equal(t, 0, report.Purged)
+	assert.Equal(t, []string{"peer-1"}, report.Unobserved)
+
+	// peer-1 reports it has not observed the tombstone yet
+	node0.pull(node1)
+	_, report = node0.collector.Collect(node0.set, nodes)
+	assert.Equal(t, 0, report.Purged)
+	assert.Equal(t, 1, report.Tracked)
+
+	// peer-1 observes the tombstone & reports it back
+	node1.pull(node0)
+	node0.pull(node1)
+
+	node0.set, report = node0.collector.Collect(node0.set, nodes)
+	assert.Equal(t, 1, report.Purged)
+	assert.Equal(t, uint64(1), report.TotalPurged)
+	assert.Equal(t, TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()}, node0.set)
+}
+
+// TestCollector_NoResurrection checks the functionality of the Collector
+// a tombstone purged on one node should not be merged back in from a
+// node that has not purged it yet, and the value should stay removed
+func TestCollector_NoResurrection(t *testing.T) {
+	nodes := []string{"peer-0", "peer-1", "peer-2"}
+	node0, node1, node2 := newGCNode("peer-0"), newGCNode("peer-1"), newGCNode("peer-2")
+
+	node0.set, _ = node0.set.Addition("xx")
+	node1.pull(node0)
+	node1.removal("xx")
+
+	// Every node observes the tombstone & the others' VersionVectors
+	for round := 0; round < 2; round++ {
+		for _, node := range []*gcNode{node0, node1, node2} {
+			for _, peer := range []*gcNode{node0, node1, node2} {
+				node.pull(peer)
+			}
+		}
+	}
+
+	node0.set, _ = node0.collector.Collect(node0.set, nodes)
+	assert.Equal(t, Initialize(), node0.set)
+
+	node0.pull(node2)
+	assert.Equal(t, Initialize(), node0.set)
+
+	node2.pull(node0)
+	present, _ := node2.set.Lookup("xx")
+	assert.False(t, present)
+
+	node2.set, _ = node2.collector.Collect(node2.set, nodes)
+	assert.Equal(t, Initialize(), node2.set)
+}
+
+// TestCollector_ConcurrentRemoval checks the functionality of the Collector
+// when a value is removed concurrently on two nodes, it should only be
+// purged once both the Removals have been observed by every node
+func TestCollector_ConcurrentRemoval(t *testing.T) {
+	nodes := []string{"peer-0", "peer-1"}
+	node0, node1 := newGCNode("peer-0"), newGCNode("peer-1")
+
+	node0.removal("xx")
+	node1.removal("xx")
+
+	node0.pull(node1)
+	report := node0.collector.Status(node0.set, nodes)
+	assert.Equal(t, 0, report.Stable)
+
+	node1.pull(node0)
+	node0.pull(node1)
+
+	report = node0.collector.Status(node0.set, nodes)
+	assert.Equal(t, 1, report.Stable)
+	assert.Equal(t, 0, report.Purged)
+}
+
+// TestCollector_NoMetadata checks the functionality of the Collector
+// when a peer does not send any Metadata, it should be merged as is
+func TestCollector_NoMetadata(t *testing.T) {
+	collector, _ := NewCollector("peer-0")
+
+	local := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+	peer := TwoPSet{Add: NewGSet(), Remove: NewGSet("xx")}
+
+	actualValue := collector.Merge(local, peer, nil)
+
+	assert.Equal(t, Merge(local, peer), actualValue)
+}
+
+// TestCollector_Invalid checks the functionality of the Collector
+// when nil nodes or values are passed, it should return an error
+func TestCollector_Invalid(t *testing.T) {
+	_, actualError := NewCollector("")
+	assert.Equal(t, errors.New("empty node provided"), actualError)
+
+	collector, _ := NewCollector("peer-0")
+	_, actualError = collector.Removal("")
+	assert.Equal(t, errors.New("empty value provided"), actualError)
+}
+
+// TestCollector_Restore checks the functionality of Collector Restore()
+// the restored Collector should continue from the Metadata's Dots & VersionVector
+// and Metadata of another node should return an error
+func TestCollector_Restore(t *testing.T) {
+	node0 := newGCNode("peer-0")
+	node0.removal("xx")
+	node0.removal("yy")
+	metadata := node0.collector.Metadata()
+
+	collector, _ := NewCollector("peer-0")
+	assert.Nil(t, collector.Restore(metadata))
+	assert.Equal(t, metadata, collector.Metadata())
+
+	dot, _ := collector.Removal("zz")
+	assert.Equal(t, Dot{Node: "peer-0", Sequence: 3}, dot)
+
+	other, _ := NewCollector("peer-1")
+	assert.NotNil(t, other.Restore(metadata))
+}
+
+// TestCollector_MergeImmutable checks the functionality of Collector Merge()
+// dropping the tombstones purged locally should leave the TwoPSets passed unmodified
+func TestCollector_MergeImmutable(t *testing.T) {
+	nodes := []string{"peer-0", "peer-1"}
+	node0, node1 := newGCNode("peer-0"), newGCNode("peer-1")
+
+	node0.set, _ = node0.set.Addition("xx")
+	node0.removal("xx")
+	node1.pull(node0)
+	node0.pull(node1)
+	node0.set, _ = node0.collector.Collect(node0.set, nodes)
+
+	local := TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()}
+	peer := node1.set.Copy()
+	metadata := node1.collector.Metadata()
+
+	actualValue := node0.collector.Merge(local, peer, &metadata)
+
+	assert.Equal(t, TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()}, actualValue)
+	assert.Equal(t, TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()}, local)
+	assert.Equal(t, TwoPSet{Add: NewGSet("xx"), Remove: NewGSet("xx")}, peer)
+}
+
+// TestCollector_Purged checks the functionality of Collector Purged()
+// a value purged should stay removed, being dropped from any TwoPSet
+// merged in even without Metadata, also once restored from its Metadata
+func TestCollector_Purged(t *testing.T) {
+	nodes := []string{"peer-0"}
+	node0 := newGCNode("peer-0")
+
+	node0.set, _ = node0.set.Addition("xx")
+	node0.removal("xx")
+	assert.False(t, node0.collector.Purged("xx"))
+
+	node0.set, _ = node0.collector.Collect(node0.set, nodes)
+	assert.True(t, node0.collector.Purged("xx"))
+	assert.False(t, node0.collector.Purged("yy"))
+
+	peer := TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet()}
+	actualValue := node0.collector.Merge(node0.set, peer, nil)
+	assert.Equal(t, TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()}, actualValue)
+
+	metadata := node0.collector.Metadata()
+	assert.Equal(t, []string{"xx"}, metadata.Purged)
+
+	collector, _ := NewCollector("peer-0")
+	assert.Nil(t, collector.Restore(metadata))
+	assert.True(t, collector.Purged("xx"))
+	assert.Equal(t, metadata, collector.Metadata())
+}
+
+// TestCollector_PurgedReplicated checks the functionality of Collector Merge()
+// a node that never observed a value purged by a peer should learn it
+// from the peer's Metadata and drop it from any TwoPSet merged in
+func TestCollector_PurgedReplicated(t *testing.T) {
+	node0, node1 := newGCNode("peer-0"), newGCNode("peer-1")
+
+	node0.set, _ = node0.set.Addition("xx")
+	node0.removal("xx")
+	node0.set, _ = node0.collector.Collect(node0.set, []string{"peer-0"})
+
+	node1.pull(node0)
+	assert.True(t, node1.collector.Purged("xx"))
+
+	peer := TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet()}
+	actualValue := node1.collector.Merge(node1.set, peer, nil)
+	assert.Equal(t, TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()}, actualValue)
+}
+
+// TestCollector_Replica checks the functionality of NewReplicaCollector()
+// a node restarted without its Metadata issues Dots with a new replica
+// so the Removals after the restart are not taken as observed by peers
+func TestCollector_Replica(t *testing.T) {
+	node0, node1 := newGCNode("peer-0"), newGCNode("peer-1")
+	node0.collector, _ = NewReplicaCollector("peer-0", "peer-0@1")
+
+	node0.removal("xx")
+	node1.pull(node0)
+
+	// peer-0 restarts with an empty TwoPSet & Collector
+	node0.set = Initialize()
+	node0.collector, _ = NewReplicaCollector("peer-0", "peer-0@2")
+	node0.set, _ = node0.set.Addition("yy")
+	node0.removal("yy")
+	assert.Equal(t, Dot{Node: "peer-0@2", Sequence: 1}, node0.collector.Metadata().Dots["yy"][0])
+
+	node1.pull(node0)
+	assert.Equal(t, TwoPSet{Add: NewGSet("yy"), Remove: NewGSet("xx", "yy")}, node1.set)
+
+	_, actualError := NewReplicaCollector("peer-0", "")
+	assert.Equal(t, errors.New("empty replica provided"), actualError)
+}
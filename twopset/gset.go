@@ -0,0 +1,90 @@
+package twopset
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// GSet is a hash indexed Grow-Only Set used to store
+// the values added & removed in a TwoPSet
+// It provides O(1) insertion & membership checks
+// while keeping the JSON wire shape {"set": [...]}
+type GSet map[string]struct{}
+
+// gsetJSON is the JSON representation of a GSet
+type gsetJSON struct {
+	Set []string `json:"set"`
+}
+
+// NewGSet returns a new GSet containing the given values
+func NewGSet(values ...string) GSet {
+	gset := make(GSet, len(values))
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		gset[value] = struct{}{}
+	}
+	return gset
+}
+
+// Insert adds a value to the GSet
+// Set = Set U value
+func (gset GSet) Insert(value string) error {
+	// Return an error if the value passed is nil
+	if value == "" {
+		return errors.New("empty value provided")
+	}
+
+	gset[value] = struct{}{}
+	return nil
+}
+
+// Contains returns true if the given
+// value is present in the GSet
+func (gset GSet) Contains(value string) bool {
+	_, present := gset[value]
+	return present
+}
+
+// Len returns the number of values in the GSet
+func (gset GSet) Len() int {
+	return len(gset)
+}
+
+// Values returns all the values in the GSet in sorted order
+func (gset GSet) Values() []string {
+	values := make([]string, 0, len(gset))
+	for value := range gset {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// Copy returns an independent copy of the GSet
+func (gset GSet) Copy() GSet {
+	copied := make(GSet, len(gset))
+	for value := range gset {
+		copied[value] = struct{}{}
+	}
+	return copied
+}
+
+// MarshalJSON encodes the GSet as {"set": [...]}
+// with the values in sorted order
+func (gset GSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(gsetJSON{Set: gset.Values()})
+}
+
+// UnmarshalJSON decodes a GSet from {"set": [...]}
+// skipping any empty values present in it
+func (gset *GSet) UnmarshalJSON(data []byte) error {
+	var decoded gsetJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*gset = NewGSet(decoded.Set...)
+	return nil
+}
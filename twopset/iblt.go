@@ -0,0 +1,274 @@
+package twopset
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// The following implements set reconciliation of TwoPSets using
+// Invertible Bloom Lookup Tables (IBLT). Each addition & tombstone
+// is identified by a 64 bit key and inserted into an IBLT. Subtracting
+// the IBLT of a peer from the local one cancels out every key both have,
+// so the keys in the symmetric difference can be decoded from an IBLT
+// whose size is proportional to the difference rather than to the sets
+// The keys only the peer has are then requested from it
+
+const (
+	// ibltHashes is the number of cells
+	// each key is inserted into
+	ibltHashes = 3
+	// IBLTMinCells is the number of cells
+	// reconciliation starts with
+	IBLTMinCells = 48
+	// IBLTMaxCells is the number of cells after which
+	// reconciliation gives up as the difference is too large
+	IBLTMaxCells = 1 << 16
+)
+
+var (
+	// ErrIBLTDecode is returned when an IBLT is too
+	// small to decode the difference it holds
+	ErrIBLTDecode = errors.New("failed to decode iblt")
+	// ErrIBLTTooLarge is returned when the difference
+	// needs more than IBLTMaxCells to be decoded
+	ErrIBLTTooLarge = errors.New("iblt difference too large")
+)
+
+// Cell is an IBLT cell
+type Cell struct {
+	// Count is the number of keys in the cell
+	Count int64 `json:"count"`
+	// KeySum is the XOR of the keys in the cell
+	KeySum uint64 `json:"key"`
+	// HashSum is the XOR of the hashes
+	// of the keys in the cell
+	HashSum uint64 `json:"hash"`
+}
+
+// IBLT is an Invertible Bloom Lookup Table of keys
+type IBLT struct {
+	Cells []Cell `json:"cells"`
+}
+
+// NewIBLT returns an empty IBLT with at least the given number of cells
+// rounded up so the cells split evenly between the hash functions
+func NewIBLT(cells int) (*IBLT, error) {
+	if cells <= 0 || cells > IBLTMaxCells {
+		return nil, errors.New("invalid iblt cells provided")
+	}
+	if remainder := cells % ibltHashes; remainder != 0 {
+		cells += ibltHashes - remainder
+	}
+	return &IBLT{Cells: make([]Cell, cells)}, nil
+}
+
+// NewTwoPSetIBLT returns an IBLT with the
+// keys of the additions & tombstones of the TwoPSet
+func NewTwoPSetIBLT(twopset TwoPSet, cells int) (*IBLT, error) {
+	iblt, err := NewIBLT(cells)
+	if err != nil {
+		return nil, err
+	}
+
+	for value := range twopset.Add {
+		iblt.Insert(AddKey(value))
+	}
+	for value := range twopset.Remove {
+		iblt.Insert(RemoveKey(value))
+	}
+
+	return iblt, nil
+}
+
+// AddKey returns the IBLT key of an addition
+func AddKey(value string) uint64 {
+	digest := sha256.Sum256([]byte("+" + value))
+	return binary.BigEndian.Uint64(digest[:8])
+}
+
+// RemoveKey returns the IBLT key of a tombstone
+func RemoveKey(value string) uint64 {
+	digest := sha256.Sum256([]byte("-" + value))
+	return binary.BigEndian.Uint64(digest[:8])
+}
+
+// Insert adds a key to the IBLT
+func (iblt *IBLT) Insert(key uint64) {
+	iblt.update(key, 1)
+}
+
+// Delete removes a key from the IBLT
+func (iblt *IBLT) Delete(key uint64) {
+	iblt.update(key, -1)
+}
+
+// update adds the key to each of its cells
+// with the count changed by the delta
+func (iblt *IBLT) update(key uint64, delta int64) {
+	check := checksum(key)
+	for _, index := range iblt.indexes(key) {
+		cell := &iblt.Cells[index]
+		cell.Count += delta
+		cell.KeySum ^= key
+		cell.HashSum ^= check
+	}
+}
+
+// indexes returns the cells of a key, one
+// in each of the subtables of the IBLT
+func (iblt *IBLT) indexes(key uint64) [ibltHashes]int {
+	var indexes [ibltHashes]int
+	subtable := uint64(len(iblt.Cells) / ibltHashes)
+	for hash := range indexes {
+		indexes[hash] = hash*int(subtable) + int(mix(key^uint64(hash+1)*0x9e3779b97f4a7c15)%subtable)
+	}
+	return indexes
+}
+
+// Subtract returns the IBLT of the keys in the IBLT minus the keys
+// in the other IBLT, which must have the same number of cells
+func (iblt *IBLT) Subtract(other *IBLT) (*IBLT, error) {
+	if len(iblt.Cells) != len(other.Cells) || len(iblt.Cells)%ibltHashes != 0 {
+		return nil, errors.New("iblt cells mismatch")
+	}
+
+	difference := &IBLT{Cells: make([]Cell, len(iblt.Cells))}
+	for index := range iblt.Cells {
+		difference.Cells[index] = Cell{
+			Count:   iblt.Cells[index].Count - other.Cells[index].Count,
+			KeySum:  iblt.Cells[index].KeySum ^ other.Cells[index].KeySum,
+			HashSum: iblt.Cells[index].HashSum ^ other.Cells[index].HashSum,
+		}
+	}
+
+	return difference, nil
+}
+
+// Decode lists the keys of an IBLT obtained by Subtract, returning
+// the keys only in the first IBLT & the keys only in the second one
+// It returns ErrIBLTDecode if the IBLT is too small for the difference
+func (iblt *IBLT) Decode() ([]uint64, []uint64, error) {
+	cells := make([]Cell, len(iblt.Cells))
+	copy(cells, iblt.Cells)
+	peeled := &IBLT{Cells: cells}
+
+	local, remote := []uint64{}, []uint64{}
+
+	// Repeatedly peel off the pure cells
+	// holding a single key until none are left
+	for progress := true; progress; {
+		progress = false
+		for index := range peeled.Cells {
+			cell := peeled.Cells[index]
+			if (cell.Count != 1 && cell.Count != -1) || checksum(cell.KeySum) != cell.HashSum {
+				continue
+			}
+
+			if cell.Count == 1 {
+				local = append(local, cell.KeySum)
+			} else {
+				remote = append(remote, cell.KeySum)
+			}
+			peeled.update(cell.KeySum, -cell.Count)
+			progress = true
+		}
+	}
+
+	for _, cell := range peeled.Cells {
+		if cell != (Cell{}) {
+			return nil, nil, ErrIBLTDecode
+		}
+	}
+
+	return local, remote, nil
+}
+
+// Elements returns the delta TwoPSet of the additions
+// & tombstones of the TwoPSet with the given keys
+func Elements(twopset TwoPSet, keys []uint64) TwoPSet {
+	wanted := make(map[uint64]bool, len(keys))
+	for _, key := range keys {
+		wanted[key] = true
+	}
+
+	elements := Initialize()
+	for value := range twopset.Add {
+		if wanted[AddKey(value)] {
+			elements.Add.Insert(value)
+		}
+	}
+	for value := range twopset.Remove {
+		if wanted[RemoveKey(value)] {
+			elements.Remove.Insert(value)
+		}
+	}
+
+	return elements
+}
+
+// IBLTPeer is a peer a TwoPSet is reconciled with
+type IBLTPeer interface {
+	// IBLT returns the IBLT of the peer's
+	// TwoPSet with the given number of cells
+	IBLT(cells int) (*IBLT, error)
+	// Elements returns the additions & tombstones
+	// of the peer's TwoPSet with the given keys
+	Elements(keys []uint64) (TwoPSet, error)
+}
+
+// Reconcile returns the delta TwoPSet of the additions & tombstones the
+// peer has that the local TwoPSet lacks, i.e. Diff(local, peer), exchanging
+// IBLTs of increasing size until the difference can be decoded
+// It returns ErrIBLTTooLarge if the difference can not be decoded
+// with IBLTMaxCells, in which case the full state should be merged
+func Reconcile(local TwoPSet, peer IBLTPeer) (TwoPSet, error) {
+	for cells := IBLTMinCells; cells <= IBLTMaxCells; cells *= 2 {
+		remote, err := peer.IBLT(cells)
+		if err != nil {
+			return Initialize(), err
+		}
+
+		own, err := NewTwoPSetIBLT(local, len(remote.Cells))
+		if err != nil {
+			return Initialize(), err
+		}
+
+		difference, err := own.Subtract(remote)
+		if err != nil {
+			return Initialize(), err
+		}
+
+		_, missing, err := difference.Decode()
+		if err == ErrIBLTDecode {
+			continue
+		}
+		if err != nil {
+			return Initialize(), err
+		}
+
+		if len(missing) == 0 {
+			return Initialize(), nil
+		}
+
+		return peer.Elements(missing)
+	}
+
+	return Initialize(), ErrIBLTTooLarge
+}
+
+// checksum returns the hash of a key used
+// to detect cells holding a single key
+func checksum(key uint64) uint64 {
+	return mix(key ^ 0x5bd1e9955bd1e995)
+}
+
+// mix is the SplitMix64 finalizer
+func mix(value uint64) uint64 {
+	value ^= value >> 30
+	value *= 0xbf58476d1ce4e5b9
+	value ^= value >> 27
+	value *= 0x94d049bb133111eb
+	value ^= value >> 31
+	return value
+}
@@ -0,0 +1,141 @@
+package twopset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryPeer is an in memory IBLTPeer
+// counting the cells & elements it sends
+type memoryPeer struct {
+	set      TwoPSet
+	cells    int
+	elements int
+}
+
+func (peer *memoryPeer) IBLT(cells int) (*IBLT, error) {
+	iblt, err := NewTwoPSetIBLT(peer.set, cells)
+	if err == nil {
+		peer.cells += len(iblt.Cells)
+	}
+	return iblt, err
+}
+
+func (peer *memoryPeer) Elements(keys []uint64) (TwoPSet, error) {
+	elements := Elements(peer.set, keys)
+	peer.elements += elements.Add.Len() + elements.Remove.Len()
+	return elements, nil
+}
+
+// largeTwoPSet returns a TwoPSet with the given number of additions
+// where every tenth value is removed
+func largeTwoPSet(size int) TwoPSet {
+	set := Initialize()
+	for index := 0; index < size; index++ {
+		set, _ = set.Addition(fmt.Sprint("value-", index))
+		if index%10 == 0 {
+			set, _ = set.Removal(fmt.Sprint("value-", index))
+		}
+	}
+	return set
+}
+
+// TestIBLT_Decode checks the basic functionality of the IBLT
+// subtracting two IBLTs should decode to the symmetric difference
+func TestIBLT_Decode(t *testing.T) {
+	a, _ := NewIBLT(30)
+	b, _ := NewIBLT(30)
+
+	for key := uint64(1); key <= 100; key++ {
+		a.Insert(key)
+		b.Insert(key)
+	}
+	a.Insert(1000)
+	b.Insert(2000)
+	b.Insert(3000)
+
+	difference, err := a.Subtract(b)
+	assert.Nil(t, err)
+
+	local, remote, err := difference.Decode()
+
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []uint64{1000}, local)
+	assert.ElementsMatch(t, []uint64{2000, 3000}, remote)
+}
+
+// TestIBLT_DecodeFailure checks the functionality of the IBLT
+// when the difference is too large for the cells, it should fail to decode
+func TestIBLT_DecodeFailure(t *testing.T) {
+	a, _ := NewIBLT(6)
+	for key := uint64(1); key <= 100; key++ {
+		a.Insert(key)
+	}
+
+	empty, _ := NewIBLT(6)
+	difference, _ := a.Subtract(empty)
+
+	_, _, err := difference.Decode()
+	assert.Equal(t, ErrIBLTDecode, err)
+
+	_, err = NewIBLT(0)
+	assert.EqualError(t, err, "invalid iblt cells provided")
+
+	other, _ := NewIBLT(9)
+	_, err = a.Subtract(other)
+	assert.EqualError(t, err, "iblt cells mismatch")
+}
+
+// TestReconcile checks the basic functionality of Reconcile()
+// against an in memory peer, merging the delta should converge to
+// the same state as merging the peer's full state while only
+// exchanging the elements that differ
+func TestReconcile(t *testing.T) {
+	local := largeTwoPSet(5000)
+	remote := local.Copy()
+
+	local, _ = local.Addition("local-only")
+	remote, _ = remote.Addition("remote-only")
+	remote, _ = remote.Removal("value-1")
+	remote, _ = remote.Removal("unseen")
+
+	peer := &memoryPeer{set: remote}
+	delta, err := Reconcile(local, peer)
+
+	assert.Nil(t, err)
+	assert.Equal(t, Diff(local, remote), delta)
+	assert.Equal(t, Merge(local, remote), MergeDelta(local.Copy(), delta))
+	assert.Equal(t, 3, peer.elements)
+	assert.Less(t, peer.cells, 200)
+}
+
+// TestReconcile_Growing checks the functionality of Reconcile()
+// when the difference is larger than the initial IBLT, it should
+// retry with larger IBLTs until it converges
+func TestReconcile_Growing(t *testing.T) {
+	local := largeTwoPSet(1000)
+	remote := Merge(local, largeTwoPSet(1300))
+
+	peer := &memoryPeer{set: remote}
+	delta, err := Reconcile(local, peer)
+
+	assert.Nil(t, err)
+	assert.Equal(t, Merge(local, remote), MergeDelta(local.Copy(), delta))
+	assert.Greater(t, peer.cells, IBLTMinCells)
+}
+
+// TestReconcile_Equal checks the functionality of Reconcile()
+// when both TwoPSets are equal, the delta should be empty
+// and no elements should be requested
+func TestReconcile_Equal(t *testing.T) {
+	local := largeTwoPSet(100)
+
+	peer := &memoryPeer{set: local.Copy()}
+	delta, err := Reconcile(local, peer)
+
+	assert.Nil(t, err)
+	assert.True(t, delta.IsEmpty())
+	assert.Equal(t, 0, peer.elements)
+}
@@ -0,0 +1,178 @@
+package twopset
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// The following implements a Merkle tree digest of the TwoPSet used
+// for anti-entropy. Values are bucketed by the hash of the value, so a
+// value's addition & tombstone always fall in the same leaf bucket
+// Each leaf is the XOR of the digests of the additions & tombstones in
+// its bucket, which makes it independent of ordering and allows it to
+// be updated incrementally. Every inner node is the hash of its two
+// children, so two nodes can walk their trees from the root down and
+// only exchange the buckets whose hashes differ
+
+const (
+	// MerkleDepth is the default depth of the Merkle
+	// tree, i.e. it has 2^MerkleDepth leaf buckets
+	MerkleDepth = 8
+	// maxMerkleDepth bounds the number of buckets
+	maxMerkleDepth = 16
+)
+
+// Hash is a Merkle tree node hash
+type Hash [sha256.Size]byte
+
+// MerkleTree is a Merkle tree digest of a TwoPSet
+type MerkleTree struct {
+	// depth is the number of levels
+	// below the root of the tree
+	depth int
+	// leaves are the XOR digests
+	// of each bucket
+	leaves []Hash
+}
+
+// NewMerkleTree returns the Merkle
+// tree of depth MerkleDepth for the TwoPSet
+func NewMerkleTree(twopset TwoPSet) *MerkleTree {
+	tree, _ := NewMerkleTreeWithDepth(twopset, MerkleDepth)
+	return tree
+}
+
+// NewMerkleTreeWithDepth returns the Merkle
+// tree of the given depth for the TwoPSet
+func NewMerkleTreeWithDepth(twopset TwoPSet, depth int) (*MerkleTree, error) {
+	if depth < 0 || depth > maxMerkleDepth {
+		return nil, errors.New("invalid merkle tree depth provided")
+	}
+
+	tree := &MerkleTree{depth: depth, leaves: make([]Hash, 1<<uint(depth))}
+	for value := range twopset.Add {
+		tree.toggle(addDigest(value), value)
+	}
+	for value := range twopset.Remove {
+		tree.toggle(removeDigest(value), value)
+	}
+
+	return tree, nil
+}
+
+// Depth returns the depth of the tree
+func (tree *MerkleTree) Depth() int {
+	return tree.depth
+}
+
+// Apply updates the tree of the TwoPSet for the delta
+// about to be joined into it, so it must be called
+// before the TwoPSet is updated
+func (tree *MerkleTree) Apply(twopset TwoPSet, delta TwoPSet) {
+	for value := range delta.Add {
+		if !twopset.Add.Contains(value) {
+			tree.toggle(addDigest(value), value)
+		}
+	}
+	for value := range delta.Remove {
+		if !twopset.Remove.Contains(value) {
+			tree.toggle(removeDigest(value), value)
+		}
+	}
+}
+
+// Bucket returns the index of the leaf bucket of the value
+func (tree *MerkleTree) Bucket(value string) int {
+	return Bucket(value, tree.depth)
+}
+
+// Bucket returns the index of the leaf bucket
+// of the value in a tree of the given depth
+func Bucket(value string, depth int) int {
+	digest := sha256.Sum256([]byte(value))
+	return int(binary.BigEndian.Uint32(digest[:4]) >> uint(32-depth) & (1<<uint(depth) - 1))
+}
+
+// Root returns the root hash of the tree
+func (tree *MerkleTree) Root() Hash {
+	return tree.Level(0)[0]
+}
+
+// Level returns the hashes of all the nodes at the given level
+// of the tree, level 0 being the root & Depth() the leaves
+// It returns nil for levels outside the tree
+func (tree *MerkleTree) Level(level int) []Hash {
+	if level < 0 || level > tree.depth {
+		return nil
+	}
+
+	hashes := append([]Hash{}, tree.leaves...)
+	for current := tree.depth; current > level; current-- {
+		parents := make([]Hash, len(hashes)/2)
+		for index := range parents {
+			parents[index] = combine(hashes[2*index], hashes[2*index+1])
+		}
+		hashes = parents
+	}
+
+	return hashes
+}
+
+// Filter returns the delta TwoPSet holding the additions
+// & tombstones of the TwoPSet in the given leaf buckets
+func (tree *MerkleTree) Filter(twopset TwoPSet, buckets []int) TwoPSet {
+	wanted := make(map[int]bool, len(buckets))
+	for _, bucket := range buckets {
+		wanted[bucket] = true
+	}
+
+	filtered := Initialize()
+	for value := range twopset.Add {
+		if wanted[tree.Bucket(value)] {
+			filtered.Add.Insert(value)
+		}
+	}
+	for value := range twopset.Remove {
+		if wanted[tree.Bucket(value)] {
+			filtered.Remove.Insert(value)
+		}
+	}
+
+	return filtered
+}
+
+// toggle XORs the digest into the bucket of the value
+func (tree *MerkleTree) toggle(digest Hash, value string) {
+	leaf := &tree.leaves[tree.Bucket(value)]
+	for index := range leaf {
+		leaf[index] ^= digest[index]
+	}
+}
+
+// addDigest returns the digest of an addition
+func addDigest(value string) Hash {
+	return sha256.Sum256([]byte("+" + value))
+}
+
+// removeDigest returns the digest of a tombstone
+func removeDigest(value string) Hash {
+	return sha256.Sum256([]byte("-" + value))
+}
+
+// combine returns the hash of an inner node
+func combine(left Hash, right Hash) Hash {
+	return sha256.Sum256(append(left[:], right[:]...))
+}
+
+// DiffLevel returns the indexes of the nodes whose hashes
+// differ between two equally long lists of hashes
+func DiffLevel(local []Hash, remote []Hash) []int {
+	differing := []int{}
+	for index := range local {
+		if index >= len(remote) || local[index] != remote[index] {
+			differing = append(differing, index)
+		}
+	}
+	return differing
+}
@@ -0,0 +1,89 @@
+package twopset
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMerkleTree checks the basic functionality of the MerkleTree
+// equal TwoPSets should have the same root irrespective of ordering
+// and different TwoPSets should have different roots
+func TestMerkleTree(t *testing.T) {
+	a := TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet("xx")}
+	b := TwoPSet{Add: NewGSet("yy", "xx"), Remove: NewGSet("xx")}
+	c := TwoPSet{Add: NewGSet("yy", "xx"), Remove: NewGSet()}
+
+	assert.Equal(t, NewMerkleTree(a).Root(), NewMerkleTree(b).Root())
+	assert.NotEqual(t, NewMerkleTree(a).Root(), NewMerkleTree(c).Root())
+	assert.NotEqual(t, NewMerkleTree(Initialize()).Root(), NewMerkleTree(c).Root())
+}
+
+// TestMerkleTree_Apply checks the functionality of MerkleTree Apply()
+// updating a tree incrementally should match rebuilding it
+// and applying values already present should not change it
+func TestMerkleTree_Apply(t *testing.T) {
+	set := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+	tree := NewMerkleTree(set)
+
+	delta := TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet("xx")}
+	tree.Apply(set, delta)
+	set = MergeDelta(set, delta)
+
+	assert.Equal(t, NewMerkleTree(set).Root(), tree.Root())
+
+	tree.Apply(set, delta)
+	assert.Equal(t, NewMerkleTree(set).Root(), tree.Root())
+}
+
+// TestMerkleTree_Walk checks the functionality of the MerkleTree
+// walking two trees from the root down should only reach
+// the leaf buckets of the values that differ
+func TestMerkleTree_Walk(t *testing.T) {
+	local := Initialize()
+	for index := 0; index < 100; index++ {
+		local, _ = local.Addition(fmt.Sprint("value-", index))
+	}
+	remote := local.Copy()
+	remote, _ = remote.Removal("value-42")
+
+	localTree, remoteTree := NewMerkleTree(local), NewMerkleTree(remote)
+
+	differing := []int{0}
+	for level := 1; level <= MerkleDepth; level++ {
+		children := []int{}
+		for _, index := range differing {
+			children = append(children, 2*index, 2*index+1)
+		}
+
+		localLevel, remoteLevel := localTree.Level(level), remoteTree.Level(level)
+		next := []int{}
+		for _, index := range children {
+			if localLevel[index] != remoteLevel[index] {
+				next = append(next, index)
+			}
+		}
+		differing = next
+	}
+
+	assert.Equal(t, []int{localTree.Bucket("value-42")}, differing)
+
+	expectedValue := TwoPSet{Add: NewGSet("value-42"), Remove: NewGSet("value-42")}
+	assert.Equal(t, expectedValue, remoteTree.Filter(remote, differing))
+}
+
+// TestMerkleTree_Level checks the functionality of MerkleTree Level()
+// each level should have twice the nodes of the level above it
+func TestMerkleTree_Level(t *testing.T) {
+	tree, err := NewMerkleTreeWithDepth(Initialize(), 3)
+
+	assert.Nil(t, err)
+	assert.Len(t, tree.Level(0), 1)
+	assert.Len(t, tree.Level(3), 8)
+	assert.Nil(t, tree.Level(4))
+	assert.Equal(t, []int{1}, DiffLevel(tree.Level(1), []Hash{tree.Level(1)[0], {}}))
+
+	_, err = NewMerkleTreeWithDepth(Initialize(), 40)
+	assert.EqualError(t, err, "invalid merkle tree depth provided")
+}
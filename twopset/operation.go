@@ -0,0 +1,284 @@
+package twopset
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// The following implements the operation-based (CmRDT) flavour of the TwoPSet
+// Additions & Removals are encoded as Operations carrying the originating
+// node, a per node sequence number & the causal context they were issued in
+// Operations received from other nodes are delivered exactly-once and in
+// causal order, so a removal is never applied before an addition it observed
+
+// OperationType is the type of a TwoPSet Operation
+type OperationType string
+
+const (
+	// OperationAdd adds a value to the TwoPSet
+	OperationAdd OperationType = "add"
+	// OperationRemove removes a value from the TwoPSet
+	OperationRemove OperationType = "remove"
+)
+
+// Operation is a TwoPSet Addition or
+// Removal message sent between nodes
+type Operation struct {
+	// Type is either add or remove
+	Type OperationType `json:"type"`
+	// Value is the value added or removed
+	Value string `json:"value"`
+	// Node is the node the operation originated on
+	Node string `json:"node"`
+	// Sequence is the per node sequence number
+	// of the operation starting at 1
+	Sequence uint64 `json:"sequence"`
+	// Context is the number of operations from each
+	// other node delivered at the originating node
+	// when the operation was issued
+	Context map[string]uint64 `json:"context"`
+	// Merged is set on the operations recorded in a
+	// write-ahead log that were merged in from peers
+	// rather than issued by the local node
+	Merged bool `json:"-"`
+}
+
+// OpTwoPSet is an operation-based TwoPSet replica
+// with a causal delivery layer. It is safe for concurrent use
+type OpTwoPSet struct {
+	mutex sync.Mutex
+	// node is the ID of the local node
+	node string
+	// state is the TwoPSet the delivered
+	// operations have been applied to
+	state TwoPSet
+	// delivered is the version vector of
+	// the operations applied to the state
+	delivered map[string]uint64
+	// pending are the operations received
+	// whose causal dependencies are missing
+	pending []Operation
+}
+
+// NewOpTwoPSet returns a new empty
+// OpTwoPSet replica for the given node
+func NewOpTwoPSet(node string) (*OpTwoPSet, error) {
+	// Return an error if the node passed is nil
+	if node == "" {
+		return nil, errors.New("empty node provided")
+	}
+
+	return &OpTwoPSet{
+		node:      node,
+		state:     Initialize(),
+		delivered: make(map[string]uint64),
+	}, nil
+}
+
+// Addition adds a value to the local replica and returns
+// the Operation to be broadcast to the other nodes
+func (set *OpTwoPSet) Addition(value string) (Operation, error) {
+	return set.prepare(OperationAdd, value)
+}
+
+// Removal removes a value from the local replica and returns
+// the Operation to be broadcast to the other nodes
+func (set *OpTwoPSet) Removal(value string) (Operation, error) {
+	return set.prepare(OperationRemove, value)
+}
+
+// prepare issues a new local operation
+// and applies it to the local replica
+func (set *OpTwoPSet) prepare(operationType OperationType, value string) (Operation, error) {
+	// Return an error if the value passed is nil
+	if value == "" {
+		return Operation{}, errors.New("empty value provided")
+	}
+
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	// The context captures every operation from
+	// other nodes the local replica has observed
+	context := make(map[string]uint64, len(set.delivered))
+	for node, sequence := range set.delivered {
+		if node == set.node {
+			continue
+		}
+		context[node] = sequence
+	}
+
+	operation := Operation{
+		Type:     operationType,
+		Value:    value,
+		Node:     set.node,
+		Sequence: set.delivered[set.node] + 1,
+		Context:  context,
+	}
+
+	set.apply(operation)
+
+	return operation, nil
+}
+
+// Deliver receives operations from other nodes, applying each one
+// exactly-once as soon as its causal dependencies have been applied
+// Operations already applied are ignored & those whose dependencies
+// are missing are buffered until the dependencies are delivered
+func (set *OpTwoPSet) Deliver(operations ...Operation) error {
+	for _, operation := range operations {
+		if err := validate(operation); err != nil {
+			return err
+		}
+	}
+
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	for _, operation := range operations {
+		if set.duplicate(operation) {
+			continue
+		}
+		set.pending = append(set.pending, operation)
+	}
+
+	// Keep applying ready operations until no more
+	// buffered operations can be delivered
+	for delivered := true; delivered; {
+		delivered = false
+		remaining := set.pending[:0]
+
+		for _, operation := range set.pending {
+			switch {
+			case set.duplicate(operation):
+			case set.ready(operation):
+				set.apply(operation)
+				delivered = true
+			default:
+				remaining = append(remaining, operation)
+			}
+		}
+
+		set.pending = remaining
+	}
+
+	return nil
+}
+
+// validate checks if an Operation is well formed
+func validate(operation Operation) error {
+	if operation.Type != OperationAdd && operation.Type != OperationRemove {
+		return errors.New("invalid operation type provided")
+	}
+	if operation.Value == "" {
+		return errors.New("empty value provided")
+	}
+	if operation.Node == "" {
+		return errors.New("empty node provided")
+	}
+	if operation.Sequence == 0 {
+		return errors.New("invalid sequence number provided")
+	}
+	return nil
+}
+
+// duplicate returns true if the operation has already been applied
+// It must be called with the mutex held
+func (set *OpTwoPSet) duplicate(operation Operation) bool {
+	return operation.Sequence <= set.delivered[operation.Node]
+}
+
+// ready returns true if the operation is the next one from its node
+// and every operation in its context has already been applied
+// It must be called with the mutex held
+func (set *OpTwoPSet) ready(operation Operation) bool {
+	if operation.Sequence != set.delivered[operation.Node]+1 {
+		return false
+	}
+
+	for node, sequence := range operation.Context {
+		if node == operation.Node {
+			continue
+		}
+		if sequence > set.delivered[node] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// apply updates the state with the operation
+// It must be called with the mutex held
+func (set *OpTwoPSet) apply(operation Operation) {
+	// The state is owned by the replica
+	// so the operation is joined in place
+	switch operation.Type {
+	case OperationAdd:
+		set.state = MergeDelta(set.state, TwoPSet{Add: NewGSet(operation.Value)})
+	case OperationRemove:
+		set.state = MergeDelta(set.state, TwoPSet{Remove: NewGSet(operation.Value)})
+	}
+
+	set.delivered[operation.Node] = operation.Sequence
+}
+
+// Lookup returns either boolean true/false indicating
+// if a given value is present in the replica or not
+func (set *OpTwoPSet) Lookup(value string) (bool, error) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	return set.state.Lookup(value)
+}
+
+// List returns all the elements present in the replica
+func (set *OpTwoPSet) List() []string {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	return set.state.List()
+}
+
+// State returns a copy of the TwoPSet the
+// delivered operations have been applied to
+func (set *OpTwoPSet) State() TwoPSet {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	return set.state.Copy()
+}
+
+// Version returns a copy of the version vector of
+// the operations applied to the replica
+func (set *OpTwoPSet) Version() map[string]uint64 {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	version := make(map[string]uint64, len(set.delivered))
+	for node, sequence := range set.delivered {
+		version[node] = sequence
+	}
+	return version
+}
+
+// Pending returns the operations buffered
+// waiting for their causal dependencies
+// ordered by node and sequence number
+func (set *OpTwoPSet) Pending() []Operation {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+
+	pending := make([]Operation, len(set.pending))
+	copy(pending, set.pending)
+
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Node != pending[j].Node {
+			return pending[i].Node < pending[j].Node
+		}
+		return pending[i].Sequence < pending[j].Sequence
+	})
+
+	return pending
+}
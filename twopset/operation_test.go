@@ -0,0 +1,135 @@
+package twopset
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOpTwoPSet checks the basic functionality of the OpTwoPSet
+// operations issued on one node and delivered to another should converge
+func TestOpTwoPSet(t *testing.T) {
+	node1, _ := NewOpTwoPSet("peer-1")
+	node2, _ := NewOpTwoPSet("peer-2")
+
+	add, _ := node1.Addition("xx")
+	remove, _ := node1.Removal("xx")
+	other, _ := node2.Addition("yy")
+
+	assert.Nil(t, node2.Deliver(add, remove))
+	assert.Nil(t, node1.Deliver(other))
+
+	assert.Equal(t, []string{"yy"}, node1.List())
+	assert.Equal(t, node1.State(), node2.State())
+	assert.Equal(t, map[string]uint64{"peer-1": 2, "peer-2": 1}, node2.Version())
+}
+
+// TestOpTwoPSet_Operation checks the Operations issued by the OpTwoPSet
+// they should carry the node, sequence number and causal context
+func TestOpTwoPSet_Operation(t *testing.T) {
+	node1, _ := NewOpTwoPSet("peer-1")
+	node2, _ := NewOpTwoPSet("peer-2")
+
+	add, _ := node1.Addition("xx")
+	node2.Deliver(add)
+	remove, _ := node2.Removal("xx")
+
+	expectedValue := Operation{
+		Type:     OperationRemove,
+		Value:    "xx",
+		Node:     "peer-2",
+		Sequence: 1,
+		Context:  map[string]uint64{"peer-1": 1},
+	}
+
+	assert.Equal(t, expectedValue, remove)
+}
+
+// TestOpTwoPSet_CausalOrder checks the functionality of the OpTwoPSet
+// when a removal arrives before the addition it observed, it should
+// be buffered until the addition is delivered
+func TestOpTwoPSet_CausalOrder(t *testing.T) {
+	node1, _ := NewOpTwoPSet("peer-1")
+	node2, _ := NewOpTwoPSet("peer-2")
+	node3, _ := NewOpTwoPSet("peer-3")
+
+	add, _ := node1.Addition("xx")
+	node2.Deliver(add)
+	remove, _ := node2.Removal("xx")
+
+	assert.Nil(t, node3.Deliver(remove))
+	assert.Equal(t, []Operation{remove}, node3.Pending())
+	assert.Equal(t, Initialize(), node3.State())
+
+	assert.Nil(t, node3.Deliver(add))
+	assert.Equal(t, []Operation{}, node3.Pending())
+	assert.Equal(t, node2.State(), node3.State())
+}
+
+// TestOpTwoPSet_FIFO checks the functionality of the OpTwoPSet
+// when operations from a node arrive out of order, they should
+// be applied in the order of their sequence numbers
+func TestOpTwoPSet_FIFO(t *testing.T) {
+	node1, _ := NewOpTwoPSet("peer-1")
+	node2, _ := NewOpTwoPSet("peer-2")
+
+	first, _ := node1.Addition("xx")
+	second, _ := node1.Addition("yy")
+
+	node2.Deliver(second)
+	assert.Equal(t, []string{}, node2.List())
+
+	node2.Deliver(first)
+	assert.Equal(t, []string{"xx", "yy"}, node2.List())
+}
+
+// TestOpTwoPSet_ExactlyOnce checks the functionality of the OpTwoPSet
+// when an operation is delivered multiple times, it should be applied once
+func TestOpTwoPSet_ExactlyOnce(t *testing.T) {
+	node1, _ := NewOpTwoPSet("peer-1")
+	node2, _ := NewOpTwoPSet("peer-2")
+
+	add, _ := node1.Addition("xx")
+
+	node2.Deliver(add, add)
+	node2.Deliver(add)
+
+	assert.Equal(t, []Operation{}, node2.Pending())
+	assert.Equal(t, map[string]uint64{"peer-1": 1}, node2.Version())
+}
+
+// TestOpTwoPSet_Invalid checks the functionality of the OpTwoPSet
+// when malformed operations or values are passed, it should return an error
+func TestOpTwoPSet_Invalid(t *testing.T) {
+	_, actualError := NewOpTwoPSet("")
+	assert.Equal(t, errors.New("empty node provided"), actualError)
+
+	node, _ := NewOpTwoPSet("peer-1")
+
+	_, actualError = node.Addition("")
+	assert.Equal(t, errors.New("empty value provided"), actualError)
+
+	actualError = node.Deliver(Operation{Type: "update", Value: "xx", Node: "peer-2", Sequence: 1})
+	assert.Equal(t, errors.New("invalid operation type provided"), actualError)
+
+	actualError = node.Deliver(Operation{Type: OperationAdd, Value: "xx", Node: "peer-2"})
+	assert.Equal(t, errors.New("invalid sequence number provided"), actualError)
+}
+
+// TestOperation_JSON checks that an Operation survives a
+// JSON round trip so it can be sent between nodes
+func TestOperation_JSON(t *testing.T) {
+	node, _ := NewOpTwoPSet("peer-1")
+	add, _ := node.Addition("xx")
+
+	encoded, actualError := json.Marshal(add)
+	assert.Nil(t, actualError)
+
+	var decoded Operation
+	actualError = json.Unmarshal(encoded, &decoded)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, add, decoded)
+}
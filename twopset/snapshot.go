@@ -0,0 +1,222 @@
+package twopset
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The following implements the snapshots of the TwoPSet written
+// alongside its write-ahead log. A snapshot holds the full TwoPSet
+// & its garbage collection Metadata along with the log offset it
+// covers, using the binary encoding laid out as
+//
+//	magic (3 bytes) | format version (1 byte) | offset | TwoPSet | Metadata | CRC-32 IEEE (4 bytes)
+//
+// Snapshots are written to a temporary file which is fsync'd & then
+// renamed, so a crash never leaves a partially written snapshot
+// under a snapshot's name. They are named after the offset they
+// cover so the newest snapshot sorts last
+
+const (
+	// snapshotPrefix & snapshotSuffix
+	// surround the name of a snapshot
+	snapshotPrefix = "snapshot-"
+	snapshotSuffix = ".2ps"
+)
+
+var (
+	// snapshotMagic prefixes a binary encoded Snapshot
+	snapshotMagic = []byte("2PN")
+)
+
+// Snapshot is the state of a TwoPSet
+// at an offset in its write-ahead log
+type Snapshot struct {
+	// Offset is the write-ahead log offset
+	// of the operations the Snapshot covers
+	Offset int64
+	// TwoPSet is the TwoPSet at the Offset
+	TwoPSet TwoPSet
+	// GC is the garbage collection
+	// Metadata at the Offset, if any
+	GC *Metadata
+}
+
+// MarshalBinary encodes the Snapshot using the binary encoding
+func (snapshot Snapshot) MarshalBinary() ([]byte, error) {
+	set, err := snapshot.TwoPSet.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata []byte
+	if snapshot.GC != nil {
+		if metadata, err = snapshot.GC.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+
+	encoder := newEncoder(snapshotMagic)
+	encoder.uvarint(uint64(snapshot.Offset))
+	encoder.string(string(set))
+	encoder.string(string(metadata))
+	return encoder.finish(), nil
+}
+
+// UnmarshalBinary decodes a Snapshot from the binary encoding
+func (snapshot *Snapshot) UnmarshalBinary(data []byte) error {
+	decoder, err := newDecoder(data, snapshotMagic)
+	if err != nil {
+		return err
+	}
+
+	offset, err := decoder.uvarint()
+	if err != nil {
+		return err
+	}
+	set, err := decoder.string()
+	if err != nil {
+		return err
+	}
+	metadata, err := decoder.string()
+	if err != nil {
+		return err
+	}
+
+	if err := decoder.finish(); err != nil {
+		return err
+	}
+
+	decoded := Snapshot{Offset: int64(offset)}
+
+	if err := decoded.TwoPSet.UnmarshalBinary([]byte(set)); err != nil {
+		return err
+	}
+	if metadata != "" {
+		decoded.GC = &Metadata{}
+		if err := decoded.GC.UnmarshalBinary([]byte(metadata)); err != nil {
+			return err
+		}
+	}
+
+	*snapshot = decoded
+	return nil
+}
+
+// WriteSnapshot atomically writes the Snapshot to
+// the given directory and returns the path to it
+func WriteSnapshot(dir string, snapshot Snapshot) (string, error) {
+	// Return an error if the offset passed is invalid
+	if snapshot.Offset < 0 {
+		return "", errors.New("invalid snapshot offset provided")
+	}
+
+	encoded, err := snapshot.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+
+	temp, err := ioutil.TempFile(dir, snapshotPrefix+"*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(encoded); err != nil {
+		temp.Close()
+		return "", err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return "", err
+	}
+	if err := temp.Close(); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s%020d%s", snapshotPrefix, snapshot.Offset, snapshotSuffix))
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return "", err
+	}
+
+	return path, syncDir(dir)
+}
+
+// ReadSnapshot reads the Snapshot at the given path
+// It returns an error if the Snapshot is corrupt
+func ReadSnapshot(path string) (Snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var snapshot Snapshot
+	err = snapshot.UnmarshalBinary(data)
+	return snapshot, err
+}
+
+// ListSnapshots returns the paths to the
+// Snapshots in the given directory newest first
+func ListSnapshots(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotSuffix) {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	return paths, nil
+}
+
+// SnapshotOffset returns the write-ahead log offset of the
+// Snapshot at the given path from its name, so it can be
+// obtained without reading the Snapshot
+func SnapshotOffset(path string) (int64, error) {
+	name := filepath.Base(path)
+	if !strings.HasPrefix(name, snapshotPrefix) || !strings.HasSuffix(name, snapshotSuffix) {
+		return 0, errors.New("invalid snapshot name: " + name)
+	}
+
+	offset, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid snapshot name: " + name)
+	}
+	return offset, nil
+}
+
+// RemoveSnapshots removes all but the given number
+// of newest Snapshots in the given directory
+func RemoveSnapshots(dir string, keep int) error {
+	paths, err := ListSnapshots(dir)
+	if err != nil {
+		return err
+	}
+
+	if keep < 0 {
+		keep = 0
+	}
+	if len(paths) <= keep {
+		return nil
+	}
+
+	for _, path := range paths[keep:] {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package twopset
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tempDir returns a new temporary directory
+// along with a function removing it
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "twopset-snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// TestSnapshot checks the basic functionality of WriteSnapshot() & ReadSnapshot()
+// a Snapshot should survive being written & read back
+func TestSnapshot(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	snapshot := Snapshot{
+		Offset:  42,
+		TwoPSet: TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet("xx")},
+		GC: &Metadata{
+			Node:    "peer-0",
+			Dots:    map[string][]Dot{"xx": {{Node: "peer-0", Sequence: 1}}},
+			Version: VersionVector{"peer-0": 1},
+			Purged:  []string{"yy", "zz"},
+		},
+	}
+
+	path, actualError := WriteSnapshot(dir, snapshot)
+	assert.Nil(t, actualError)
+	assert.Equal(t, filepath.Join(dir, "snapshot-00000000000000000042.2ps"), path)
+
+	actualValue, actualError := ReadSnapshot(path)
+	assert.Nil(t, actualError)
+	assert.Equal(t, snapshot, actualValue)
+
+	// No temporary files should be left behind
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 1, len(files))
+}
+
+// TestSnapshot_NoMetadata checks the functionality of ReadSnapshot()
+// a Snapshot without Metadata should be read back without it
+func TestSnapshot_NoMetadata(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	snapshot := Snapshot{TwoPSet: TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}}
+
+	path, _ := WriteSnapshot(dir, snapshot)
+	actualValue, actualError := ReadSnapshot(path)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, snapshot, actualValue)
+}
+
+// TestSnapshot_Corrupt checks the functionality of ReadSnapshot()
+// when the Snapshot is corrupt or truncated, it should return an error
+func TestSnapshot_Corrupt(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path, _ := WriteSnapshot(dir, Snapshot{Offset: 1, TwoPSet: TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}})
+	data, _ := ioutil.ReadFile(path)
+
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)-6] ^= 0xff
+	ioutil.WriteFile(path, corrupt, 0644)
+
+	_, actualError := ReadSnapshot(path)
+	assert.Equal(t, ErrChecksum, actualError)
+
+	ioutil.WriteFile(path, data[:len(data)/2], 0644)
+
+	_, actualError = ReadSnapshot(path)
+	assert.NotNil(t, actualError)
+}
+
+// TestListSnapshots checks the basic functionality of ListSnapshots() & RemoveSnapshots()
+// Snapshots should be listed newest first ignoring other files
+// and only the newest Snapshots should be kept
+func TestListSnapshots(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	for _, offset := range []int64{10, 2000, 300} {
+		WriteSnapshot(dir, Snapshot{Offset: offset, TwoPSet: Initialize()})
+	}
+	ioutil.WriteFile(filepath.Join(dir, "twopset.wal"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "snapshot-xx.tmp"), nil, 0644)
+
+	expectedValue := []string{
+		filepath.Join(dir, "snapshot-00000000000000002000.2ps"),
+		filepath.Join(dir, "snapshot-00000000000000000300.2ps"),
+		filepath.Join(dir, "snapshot-00000000000000000010.2ps"),
+	}
+
+	actualValue, actualError := ListSnapshots(dir)
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, actualValue)
+
+	assert.Nil(t, RemoveSnapshots(dir, 2))
+	actualValue, _ = ListSnapshots(dir)
+	assert.Equal(t, expectedValue[:2], actualValue)
+}
+
+// TestSnapshotOffset checks the basic functionality of SnapshotOffset()
+// the offset should be parsed from the Snapshot name
+// and invalid names should return an error
+func TestSnapshotOffset(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	path, _ := WriteSnapshot(dir, Snapshot{Offset: 300, TwoPSet: Initialize()})
+
+	actualValue, actualError := SnapshotOffset(path)
+	assert.Nil(t, actualError)
+	assert.Equal(t, int64(300), actualValue)
+
+	_, actualError = SnapshotOffset(filepath.Join(dir, "snapshot-xx.2ps"))
+	assert.NotNil(t, actualError)
+
+	_, actualError = SnapshotOffset(filepath.Join(dir, "twopset.wal"))
+	assert.NotNil(t, actualError)
+}
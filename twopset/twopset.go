@@ -2,33 +2,59 @@ package twopset
 
 import (
 	"errors"
-
-	"github.com/el10savio/gset-crdt/gset"
 )
 
 // package twopset implements the TwoPSet (2PSet) CRDT data type along with the functionality to
 // append, list & lookup values in a TwoPSet. It also provides the functionality to
 // merge multiple TwoPSets together and a utility function to clear a TwoPSet used in tests
 
+var (
+	// ErrNotAdded is returned by StrictRemoval when
+	// the value has never been added to the TwoPSet
+	ErrNotAdded = errors.New("value not added")
+	// ErrAlreadyRemoved is returned by StrictRemoval when
+	// the value has already been removed from the TwoPSet
+	ErrAlreadyRemoved = errors.New("value already removed")
+	// ErrPurged is returned when adding a value whose
+	// tombstone has been purged by the Collector
+	ErrPurged = errors.New("value removed & purged")
+)
+
 // TwoPSet is the TwoPSet CRDT data type
 // It is implemented by combining two GSets,
 // One to store the values added & another
 // to store the values removed
+// The GSets are hash indexed so Addition, Removal
+// & Lookup are O(1) and Merge is linear in the
+// size of the TwoPSets being merged
+// TwoPSets share their underlying GSets when
+// copied, use Copy() to obtain an independent one
 type TwoPSet struct {
 	// Add is a GSet to store the values added
-	Add gset.GSet `json:"add"`
+	Add GSet `json:"add"`
 	// Remove is a GSet to store the values removed
-	Remove gset.GSet `json:"remove"`
+	Remove GSet `json:"remove"`
 }
 
 // Initialize returns a new empty TwoPSet
 func Initialize() TwoPSet {
 	return TwoPSet{
-		Add:    gset.Initialize(),
-		Remove: gset.Initialize(),
+		Add:    NewGSet(),
+		Remove: NewGSet(),
 	}
 }
 
+// init allocates the GSets of a zero value TwoPSet
+func (twopset TwoPSet) init() TwoPSet {
+	if twopset.Add == nil {
+		twopset.Add = NewGSet()
+	}
+	if twopset.Remove == nil {
+		twopset.Remove = NewGSet()
+	}
+	return twopset
+}
+
 // Addition adds a new unique value to the TwoPSet using the
 // union operation for each value on the existing TwoPSet
 func (twopset TwoPSet) Addition(value string) (TwoPSet, error) {
@@ -37,50 +63,88 @@ func (twopset TwoPSet) Addition(value string) (TwoPSet, error) {
 		return twopset, errors.New("empty value provided")
 	}
 
+	twopset = twopset.init()
+
 	// Set = Set U value
-	twopset.Add.Set, _ = twopset.Add.Append(value)
+	twopset.Add.Insert(value)
 
 	// Return the new TwoPSet followed by nil error
 	return twopset, nil
 }
 
-// Removal ...
+// Removal adds a value to the Remove GSet of the
+// TwoPSet, marking it as removed permanently
 func (twopset TwoPSet) Removal(value string) (TwoPSet, error) {
 	// Return an error if the value passed is nil
 	if value == "" {
 		return twopset, errors.New("empty value provided")
 	}
 
+	twopset = twopset.init()
+
 	// Set = Set U value
-	twopset.Remove.Set, _ = twopset.Remove.Append(value)
+	twopset.Remove.Insert(value)
 
 	// Return the new TwoPSet followed by nil error
 	return twopset, nil
 }
 
-// List returns all the elements present in the TwoPSet
-func (twopset TwoPSet) List() []string {
-	if len(twopset.Remove.Set) == 0 || len(twopset.Add.Set) == 0 {
-		return twopset.Add.Set
+// StrictRemoval removes a value from the TwoPSet like Removal
+// but only if the value is present, enforcing the 2PSet
+// precondition remove(value) only if lookup(value)
+// It returns ErrNotAdded or ErrAlreadyRemoved otherwise,
+// leaving the TwoPSet unmodified
+func (twopset TwoPSet) StrictRemoval(value string) (TwoPSet, error) {
+	// Return an error if the value passed is nil
+	if value == "" {
+		return twopset, errors.New("empty value provided")
+	}
+
+	if err := twopset.CanRemove(value); err != nil {
+		return twopset, err
 	}
 
-	resultGSet := twopset.Add
+	return twopset.Removal(value)
+}
 
-	for _, element := range twopset.Remove.Set {
-		resultGSet = Delete(resultGSet, element)
+// CanRemove checks the StrictRemoval precondition without
+// modifying the TwoPSet, returning ErrNotAdded or
+// ErrAlreadyRemoved if the value is not present
+func (twopset TwoPSet) CanRemove(value string) error {
+	if twopset.Remove.Contains(value) {
+		return ErrAlreadyRemoved
 	}
 
-	return resultGSet.Set
+	if !twopset.Add.Contains(value) {
+		return ErrNotAdded
+	}
+
+	return nil
 }
 
-// Delete removes an entry from the GSET
-func Delete(gset gset.GSet, value string) gset.GSet {
-	for index, element := range gset.Set {
-		if element == value {
-			gset.Set = append(gset.Set[:index], gset.Set[index+1:]...)
-			return gset
+// List returns all the elements present in the TwoPSet
+// i.e. the values added that have not been removed,
+// in sorted order
+func (twopset TwoPSet) List() []string {
+	list := make([]string, 0, len(twopset.Add))
+
+	for _, element := range twopset.Add.Values() {
+		if twopset.Remove.Contains(element) {
+			continue
 		}
+		list = append(list, element)
 	}
+
+	return list
+}
+
+// Delete removes an entry from the GSet
+//
+// Deprecated: GSets only grow, values are removed
+// from a TwoPSet using Removal. Delete is kept for
+// the callers of the slice backed TwoPSet
+func Delete(gset GSet, value string) GSet {
+	delete(gset, value)
 	return gset
 }
 
@@ -92,51 +156,47 @@ func (twopset TwoPSet) Lookup(value string) (bool, error) {
 		return false, errors.New("empty value provided")
 	}
 
-	list := twopset.List()
-
-	// Iterative over the TwoPSet and check if the
-	// value is the one we're searching
-	// return true if the value exists
-	for _, element := range list {
-		if element == value {
-			return true, nil
-		}
-	}
-
-	// If the value isn't found after iterating
-	// over the entire TwoPSet we return false
-	return false, nil
+	// A value is present if it has been
+	// added and has not been removed
+	return twopset.Add.Contains(value) && !twopset.Remove.Contains(value), nil
 }
 
 // Merge conbines multiple TwoPSets together using Union
 // and returns a single merged TwoPSet
+// The TwoPSets passed are left unmodified
 func Merge(TwoPSets ...TwoPSet) TwoPSet {
-	var twoPSetMerged TwoPSet
+	twoPSetMerged := Initialize()
 
 	// GSetMerged = GSetMerged U GSetToMergeWith
 	for _, twopset := range TwoPSets {
-		for _, value := range twopset.Add.Set {
+		for value := range twopset.Add {
 			if value == "" {
 				continue
 			}
-			twoPSetMerged, _ = twoPSetMerged.Addition(value)
+			twoPSetMerged.Add[value] = struct{}{}
 		}
-		for _, value := range twopset.Remove.Set {
+		for value := range twopset.Remove {
 			if value == "" {
 				continue
 			}
-			twoPSetMerged, _ = twoPSetMerged.Removal(value)
+			twoPSetMerged.Remove[value] = struct{}{}
 		}
 	}
 
-	// Return the merged TwoPSet followed by nil error
+	// Return the merged TwoPSet
 	return twoPSetMerged
 }
 
+// Copy returns an independent copy of the TwoPSet
+func (twopset TwoPSet) Copy() TwoPSet {
+	return TwoPSet{
+		Add:    twopset.Add.Copy(),
+		Remove: twopset.Remove.Copy(),
+	}
+}
+
 // Clear is utility function used only for tests
 // to empty the contents of a given TwoPSet
 func (twopset TwoPSet) Clear() TwoPSet {
-	twopset.Add.Set = []string{}
-	twopset.Remove.Set = []string{}
-	return twopset
+	return Initialize()
 }
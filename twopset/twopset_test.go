@@ -1,10 +1,10 @@
 package twopset
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
 
-	"github.com/el10savio/gset-crdt/gset"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -90,7 +90,7 @@ func TestList_NoValue(t *testing.T) {
 // TestAddition checks the basic functionality of TwoPSet Addition()
 // it should return the TwoPSet back when the addition is successful
 func TestAddition(t *testing.T) {
-	expectedValue := TwoPSet{Add: gset.GSet{[]string{"xx"}}, Remove: gset.GSet{[]string{}}}
+	expectedValue := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
 	actualValue, actualError := twopset.Addition("xx")
 
 	assert.Nil(t, actualError)
@@ -103,7 +103,7 @@ func TestAddition(t *testing.T) {
 // when a nil value is passed to it, it should return
 // the an empty string slice back along with an error
 func TestAddition_NoValue(t *testing.T) {
-	expectedValue := TwoPSet{Add: gset.GSet{[]string{}}, Remove: gset.GSet{[]string{}}}
+	expectedValue := TwoPSet{Add: NewGSet(), Remove: NewGSet()}
 	expectedError := errors.New("empty value provided")
 	actualValue, actualError := twopset.Addition("")
 
@@ -244,11 +244,11 @@ func TestLookup_EmptyLookup(t *testing.T) {
 // TestMerge checks the basic functionality of the Merge() function on multiple GSets
 // it returns all the GSets merged together with unique elements as one single TwoPSet
 func TestMerge(t *testing.T) {
-	twopset1 := TwoPSet{Add: gset.GSet{[]string{"xx"}}, Remove: gset.GSet{[]string{}}}
-	twopset2 := TwoPSet{Add: gset.GSet{[]string{"yy"}}, Remove: gset.GSet{[]string{}}}
-	twopset3 := TwoPSet{Add: gset.GSet{[]string{"zz"}}, Remove: gset.GSet{[]string{"xx"}}}
+	twopset1 := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+	twopset2 := TwoPSet{Add: NewGSet("yy"), Remove: NewGSet()}
+	twopset3 := TwoPSet{Add: NewGSet("zz"), Remove: NewGSet("xx")}
 
-	expectedValue := TwoPSet{Add: gset.GSet{[]string{"xx", "yy", "zz"}}, Remove: gset.GSet{[]string{"xx"}}}
+	expectedValue := TwoPSet{Add: NewGSet("xx", "yy", "zz"), Remove: NewGSet("xx")}
 	actualValue := Merge(twopset1, twopset2, twopset3)
 
 	assert.Equal(t, expectedValue, actualValue)
@@ -264,11 +264,11 @@ func TestMerge(t *testing.T) {
 // TestMerge_Empty checks the functionality of the Merge() function on multiple GSets
 // when one TwoPSet is empty, it returns an empty TwoPSet followed by an error
 func TestMerge_Empty(t *testing.T) {
-	twopset1 := TwoPSet{Add: gset.GSet{[]string{"xx"}}, Remove: gset.GSet{[]string{}}}
-	twopset2 := TwoPSet{Add: gset.GSet{[]string{}}, Remove: gset.GSet{[]string{}}}
-	twopset3 := TwoPSet{Add: gset.GSet{[]string{"zz"}}, Remove: gset.GSet{[]string{"xx"}}}
+	twopset1 := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+	twopset2 := TwoPSet{Add: NewGSet(), Remove: NewGSet()}
+	twopset3 := TwoPSet{Add: NewGSet("zz"), Remove: NewGSet("xx")}
 
-	expectedValue := TwoPSet{Add: gset.GSet{[]string{"xx", "zz"}}, Remove: gset.GSet{[]string{"xx"}}}
+	expectedValue := TwoPSet{Add: NewGSet("xx", "zz"), Remove: NewGSet("xx")}
 	actualValue := Merge(twopset1, twopset2, twopset3)
 
 	assert.Equal(t, expectedValue, actualValue)
@@ -285,11 +285,11 @@ func TestMerge_Empty(t *testing.T) {
 // when duplicate values are passed with the TwoPSet it returns all the GSets
 // merged together with unique elements as one single TwoPSet
 func TestMerge_Duplicate(t *testing.T) {
-	twopset1 := TwoPSet{Add: gset.GSet{[]string{"xx"}}, Remove: gset.GSet{[]string{"zz"}}}
-	twopset2 := TwoPSet{Add: gset.GSet{[]string{"xx"}}, Remove: gset.GSet{[]string{}}}
-	twopset3 := TwoPSet{Add: gset.GSet{[]string{"zz"}}, Remove: gset.GSet{[]string{"zz"}}}
+	twopset1 := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet("zz")}
+	twopset2 := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+	twopset3 := TwoPSet{Add: NewGSet("zz"), Remove: NewGSet("zz")}
 
-	expectedValue := TwoPSet{Add: gset.GSet{[]string{"xx", "zz"}}, Remove: gset.GSet{[]string{"zz"}}}
+	expectedValue := TwoPSet{Add: NewGSet("xx", "zz"), Remove: NewGSet("zz")}
 	actualValue := Merge(twopset1, twopset2, twopset3)
 
 	assert.Equal(t, expectedValue, actualValue)
@@ -301,3 +301,135 @@ func TestMerge_Duplicate(t *testing.T) {
 
 	twopset = twopset.Clear()
 }
+
+// TestMerge_Immutable checks the functionality of the Merge() function
+// it should leave the TwoPSets passed to it unmodified
+func TestMerge_Immutable(t *testing.T) {
+	twopset1 := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+	twopset2 := TwoPSet{Add: NewGSet("yy"), Remove: NewGSet("xx")}
+
+	merged := Merge(twopset1, twopset2)
+	merged, _ = merged.Addition("zz")
+
+	assert.Equal(t, TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}, twopset1)
+	assert.Equal(t, TwoPSet{Add: NewGSet("yy"), Remove: NewGSet("xx")}, twopset2)
+	assert.Equal(t, []string{"yy", "zz"}, merged.List())
+}
+
+// TestAddition_InPlace checks the functionality of TwoPSet Addition()
+// & Removal(), they should update the GSets of the TwoPSet called on
+// in place, leaving the copies obtained using Copy() unmodified
+func TestAddition_InPlace(t *testing.T) {
+	original := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}
+	copied := original.Copy()
+
+	added, _ := original.Addition("yy")
+	removed, _ := original.Removal("xx")
+
+	assert.Equal(t, TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet("xx")}, original)
+	assert.Equal(t, original, added)
+	assert.Equal(t, original, removed)
+	assert.Equal(t, TwoPSet{Add: NewGSet("xx"), Remove: NewGSet()}, copied)
+}
+
+// TestDelete checks the basic functionality of the Delete() function
+// the value should be removed from the GSet
+func TestDelete(t *testing.T) {
+	assert.Equal(t, NewGSet("yy"), Delete(NewGSet("xx", "yy"), "xx"))
+	assert.Equal(t, NewGSet("yy"), Delete(NewGSet("yy"), "xx"))
+}
+
+// TestAddition_ZeroValue checks the functionality of TwoPSet Addition()
+// & Removal() on an uninitialized TwoPSet, it should allocate its GSets
+func TestAddition_ZeroValue(t *testing.T) {
+	var zero TwoPSet
+
+	zero, _ = zero.Addition("xx")
+	zero, _ = zero.Removal("yy")
+
+	expectedValue := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet("yy")}
+
+	assert.Equal(t, expectedValue, zero)
+}
+
+// TestJSON checks the JSON wire shape of the TwoPSet
+// it should match the GSet backed {"add": {"set": [...]}, ...} format
+func TestJSON(t *testing.T) {
+	set := TwoPSet{Add: NewGSet("yy", "xx"), Remove: NewGSet("xx")}
+
+	expectedValue := `{"add":{"set":["xx","yy"]},"remove":{"set":["xx"]}}`
+	actualValue, actualError := json.Marshal(set)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, string(actualValue))
+
+	var decoded TwoPSet
+	actualError = json.Unmarshal(actualValue, &decoded)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, set, decoded)
+}
+
+// TestJSON_Empty checks the JSON wire shape of an empty TwoPSet
+// it should encode empty GSets as empty arrays & decode nil ones
+func TestJSON_Empty(t *testing.T) {
+	expectedValue := `{"add":{"set":[]},"remove":{"set":[]}}`
+	actualValue, actualError := json.Marshal(Initialize())
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, string(actualValue))
+
+	var decoded TwoPSet
+	actualError = json.Unmarshal([]byte(`{"add":{"set":null},"remove":{"set":["xx",""]}}`), &decoded)
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, TwoPSet{Add: NewGSet(), Remove: NewGSet("xx")}, decoded)
+}
+
+// TestStrictRemoval checks the basic functionality of TwoPSet StrictRemoval()
+// a value present should be removed like Removal()
+func TestStrictRemoval(t *testing.T) {
+	twopset, _ = twopset.Addition("xx")
+
+	expectedValue := TwoPSet{Add: NewGSet("xx"), Remove: NewGSet("xx")}
+	actualValue, actualError := twopset.StrictRemoval("xx")
+
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, actualValue)
+
+	twopset = twopset.Clear()
+}
+
+// TestStrictRemoval_NotAdded checks the functionality of TwoPSet StrictRemoval()
+// when a value never added is removed, it should return ErrNotAdded
+// and the value should still be able to be added later
+func TestStrictRemoval_NotAdded(t *testing.T) {
+	actualValue, actualError := twopset.StrictRemoval("xx")
+
+	assert.Equal(t, ErrNotAdded, actualError)
+	assert.Equal(t, Initialize(), actualValue)
+
+	twopset, _ = twopset.Addition("xx")
+	present, _ := twopset.Lookup("xx")
+
+	assert.True(t, present)
+
+	twopset = twopset.Clear()
+}
+
+// TestStrictRemoval_AlreadyRemoved checks the functionality of TwoPSet StrictRemoval()
+// when a value already removed is removed again, it should return ErrAlreadyRemoved
+func TestStrictRemoval_AlreadyRemoved(t *testing.T) {
+	twopset, _ = twopset.Addition("xx")
+	twopset, _ = twopset.Removal("xx")
+
+	_, actualError := twopset.StrictRemoval("xx")
+
+	assert.Equal(t, ErrAlreadyRemoved, actualError)
+
+	_, actualError = twopset.StrictRemoval("")
+
+	assert.Equal(t, errors.New("empty value provided"), actualError)
+
+	twopset = twopset.Clear()
+}
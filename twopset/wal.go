@@ -0,0 +1,511 @@
+package twopset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// The following implements the append-only write-ahead log of the
+// TwoPSet Additions & Removals. The log starts with a header
+//
+//	magic (3 bytes) | format version (1 byte) | base offset (8 bytes)
+//
+// followed by the records, each laid out as
+//
+//	length (4 bytes) | CRC-32 IEEE (4 bytes) | type (1 byte) | value
+//
+// where the length & checksum cover the type & value. The type tells
+// the operations issued locally from the ones merged in from peers
+// Every record is fsync'd before Append returns, so an operation
+// acknowledged is never lost. A crash while appending leaves a torn record at the
+// end of the log, which is discarded when the log is opened again
+// An invalid record followed by a valid one can not have been torn
+// by a crash, so the log is reported corrupt instead of dropping
+// the records acknowledged after it. As a corrupt length hides where
+// the next record starts, the log is searched for a valid record at
+// every position after an invalid record whose length does not fit
+//
+// Offsets in the log are counted in bytes of records appended since
+// the log was created. Truncating the log drops the records before
+// an offset on a record boundary & records it as the base offset
+// of the records left
+
+const (
+	// walHeader is the size of the log header
+	walHeader = 12
+	// walRecordHeader is the size of the
+	// length & checksum preceding a record
+	walRecordHeader = 8
+
+	// walAdd & walRemove are the types of the
+	// records in the log issued locally, while
+	// walMergedAdd & walMergedRemove are the
+	// types of those merged in from peers
+	walAdd          byte = 'a'
+	walRemove       byte = 'r'
+	walMergedAdd    byte = 'A'
+	walMergedRemove byte = 'R'
+)
+
+var (
+	// walMagic prefixes the write-ahead log
+	walMagic = []byte("2PL")
+
+	// ErrWALClosed is returned when appending
+	// to a write-ahead log already closed
+	ErrWALClosed = errors.New("write-ahead log closed")
+
+	// ErrWALCorrupt is returned when opening a write-ahead
+	// log holding an invalid record before valid ones
+	ErrWALCorrupt = errors.New("write-ahead log corrupt")
+)
+
+// WAL is an append-only write-ahead log of
+// TwoPSet operations. It is safe for concurrent use
+type WAL struct {
+	mutex sync.Mutex
+	// path is the path to the log file
+	path string
+	// file is the log file opened for appending
+	file *os.File
+	// base is the offset of the
+	// first record in the log file
+	base int64
+	// offset is the offset right
+	// after the last valid record
+	offset int64
+	// discarded is the number of bytes of a torn
+	// record discarded when the log was opened
+	discarded int64
+	// failed is set when a failed append could not be
+	// dropped from the log file, no more operations
+	// are appended after it until the log is truncated
+	failed error
+}
+
+// OpenWAL opens the write-ahead log at the given path creating it
+// if not present, and returns the operations recorded in it from
+// the given offset onwards in the order they were appended
+// A torn record at the end of the log is truncated while
+// an invalid record before valid ones returns ErrWALCorrupt
+func OpenWAL(path string, from int64) (*WAL, []Operation, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wal := &WAL{path: path, file: file}
+
+	operations, err := wal.read(from)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	// Discard what follows the last valid record
+	// so new records are appended right after it
+	end := walHeader + wal.offset - wal.base
+	if err := file.Truncate(end); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	if _, err := file.Seek(end, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	// Persist the log's directory entry
+	// in case the log was just created
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return wal, operations, nil
+}
+
+// Append writes the operations to the end of the log
+// and waits until they are flushed to disk. The
+// operations are written & flushed at once, so
+// either all of them are appended or none are
+func (wal *WAL) Append(operations ...Operation) error {
+	records := []byte{}
+	for _, operation := range operations {
+		record, err := encodeWALRecord(operation)
+		if err != nil {
+			return err
+		}
+		records = append(records, record...)
+	}
+
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	if wal.file == nil {
+		return ErrWALClosed
+	}
+	if wal.failed != nil {
+		return wal.failed
+	}
+
+	if _, err := wal.file.Write(records); err != nil {
+		wal.rollback()
+		return err
+	}
+
+	// The records are not acknowledged so they are dropped,
+	// otherwise the offset would fall behind the file
+	if err := wal.file.Sync(); err != nil {
+		wal.rollback()
+		return err
+	}
+
+	wal.offset += int64(len(records))
+	return nil
+}
+
+// rollback drops what follows the last valid record after a
+// failed append so the log stays appendable. The log is marked
+// failed if it can not be, as later records would follow it
+// It must be called with the mutex held
+func (wal *WAL) rollback() {
+	end := walHeader + wal.offset - wal.base
+	if err := wal.file.Truncate(end); err != nil {
+		wal.failed = fmt.Errorf("write-ahead log failed: %v", err)
+		return
+	}
+	if _, err := wal.file.Seek(end, io.SeekStart); err != nil {
+		wal.failed = fmt.Errorf("write-ahead log failed: %v", err)
+	}
+}
+
+// Truncate drops the records of the log before the given offset
+// which must be on a record boundary
+// The records left are written to a new log file replacing
+// the current one, so a crash leaves either of them intact
+// A log failed is appendable again once truncated
+func (wal *WAL) Truncate(offset int64) error {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	if wal.file == nil {
+		return ErrWALClosed
+	}
+
+	if offset <= wal.base {
+		return nil
+	}
+	if offset > wal.offset {
+		return errors.New("truncate offset past the end of the log")
+	}
+
+	data := make([]byte, wal.offset-wal.base)
+	if _, err := wal.file.ReadAt(data, walHeader); err != nil {
+		return err
+	}
+
+	// Return an error if the offset falls inside a record
+	// as the log left would start with a partial one
+	if !walBoundary(data, offset-wal.base) {
+		return fmt.Errorf("truncate offset %d not on a record boundary", offset)
+	}
+
+	// The records left in the current log file
+	records := data[offset-wal.base:]
+
+	temp, err := ioutil.TempFile(filepath.Dir(wal.path), filepath.Base(wal.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(append(encodeWALHeader(offset), records...)); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(temp.Name(), wal.path); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(wal.path)); err != nil {
+		return err
+	}
+
+	// Reopen the new log file for appending
+	file, err := os.OpenFile(wal.path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		file.Close()
+		return err
+	}
+
+	wal.file.Close()
+	wal.file = file
+	wal.base = offset
+	wal.failed = nil
+	return nil
+}
+
+// Base returns the offset of
+// the first record in the log
+func (wal *WAL) Base() int64 {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+	return wal.base
+}
+
+// Discarded returns the number of bytes of a torn
+// record discarded when the log was opened
+func (wal *WAL) Discarded() int64 {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+	return wal.discarded
+}
+
+// Offset returns the offset right
+// after the last record in the log
+func (wal *WAL) Offset() int64 {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+	return wal.offset
+}
+
+// Close closes the log, after which
+// no more operations can be appended
+func (wal *WAL) Close() error {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	if wal.file == nil {
+		return nil
+	}
+
+	err := wal.file.Close()
+	wal.file = nil
+	return err
+}
+
+// Replay applies the operations in order
+// to a new TwoPSet and returns it
+func Replay(operations []Operation) TwoPSet {
+	twopset := Initialize()
+	for _, operation := range operations {
+		switch operation.Type {
+		case OperationAdd:
+			twopset.Add[operation.Value] = struct{}{}
+		case OperationRemove:
+			twopset.Remove[operation.Value] = struct{}{}
+		}
+	}
+	return twopset
+}
+
+// encodeWALHeader encodes the log header
+// with the given base offset
+func encodeWALHeader(base int64) []byte {
+	header := make([]byte, walHeader)
+	copy(header, walMagic)
+	header[len(walMagic)] = FormatVersion
+	binary.BigEndian.PutUint64(header[4:], uint64(base))
+	return header
+}
+
+// encodeWALRecord encodes the operation as a log record
+func encodeWALRecord(operation Operation) ([]byte, error) {
+	// Return an error if the value passed is nil
+	if operation.Value == "" {
+		return nil, errors.New("empty value provided")
+	}
+
+	var kind byte
+	switch {
+	case operation.Type == OperationAdd && operation.Merged:
+		kind = walMergedAdd
+	case operation.Type == OperationRemove && operation.Merged:
+		kind = walMergedRemove
+	case operation.Type == OperationAdd:
+		kind = walAdd
+	case operation.Type == OperationRemove:
+		kind = walRemove
+	default:
+		return nil, errors.New("invalid operation type provided: " + string(operation.Type))
+	}
+
+	payload := append([]byte{kind}, operation.Value...)
+
+	record := make([]byte, walRecordHeader, walRecordHeader+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	return append(record, payload...), nil
+}
+
+// read reads the header & the valid records of the log file
+// setting its base & end offsets, and returns the operations
+// from the given offset onwards. A new log file gets a header
+// Reading stops at a torn record at the end of the log
+func (wal *WAL) read(from int64) ([]Operation, error) {
+	info, err := wal.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		if _, err := wal.file.Write(encodeWALHeader(0)); err != nil {
+			return nil, err
+		}
+		return nil, wal.file.Sync()
+	}
+
+	if _, err := wal.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(wal.file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < walHeader {
+		return nil, errors.New("invalid write-ahead log: bad header")
+	}
+	if !bytes.Equal(data[:len(walMagic)], walMagic) {
+		return nil, errors.New("invalid write-ahead log: bad magic")
+	}
+	if data[len(walMagic)] != FormatVersion {
+		return nil, ErrFormatVersion
+	}
+
+	wal.base = int64(binary.BigEndian.Uint64(data[4:walHeader]))
+	wal.offset = wal.base
+
+	var operations []Operation
+	records := data[walHeader:]
+
+	for len(records) > 0 {
+		operation, size, valid := decodeWALRecord(records)
+		if !valid {
+			if !tornWALRecord(records) {
+				return nil, fmt.Errorf("%w: invalid record at offset %d", ErrWALCorrupt, wal.offset)
+			}
+			wal.discarded = int64(len(records))
+			return operations, nil
+		}
+
+		if wal.offset >= from {
+			operations = append(operations, operation)
+		}
+		wal.offset += int64(size)
+		records = records[size:]
+	}
+
+	return operations, nil
+}
+
+// decodeWALRecord decodes the log record the data starts with
+// and returns its operation along with the size of the record
+// It returns false if the data does not start with a valid record
+func decodeWALRecord(data []byte) (Operation, int, bool) {
+	if len(data) < walRecordHeader {
+		return Operation{}, 0, false
+	}
+
+	length := binary.BigEndian.Uint32(data[0:4])
+	checksum := binary.BigEndian.Uint32(data[4:8])
+
+	// A record holds at least its type & a non empty value
+	// and can not run past the end of the log file
+	if length < 2 || uint64(length) > uint64(len(data)-walRecordHeader) {
+		return Operation{}, 0, false
+	}
+
+	// The type is checked before the checksum
+	// as the log is searched for valid records
+	payload := data[walRecordHeader : walRecordHeader+int(length)]
+
+	var operation Operation
+	switch payload[0] {
+	case walAdd:
+		operation.Type = OperationAdd
+	case walRemove:
+		operation.Type = OperationRemove
+	case walMergedAdd:
+		operation.Type, operation.Merged = OperationAdd, true
+	case walMergedRemove:
+		operation.Type, operation.Merged = OperationRemove, true
+	default:
+		return Operation{}, 0, false
+	}
+
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return Operation{}, 0, false
+	}
+
+	operation.Value = string(payload[1:])
+	return operation, walRecordHeader + int(length), true
+}
+
+// tornWALRecord returns true if the invalid record the data starts
+// with can have been torn by a crash while appending it. Only the
+// last record can be torn, so no valid record can follow it: after
+// its end if its length fits in the log, and at any position after
+// its start otherwise, as its length may be corrupt. This covers a
+// record zeroed out to the end of the log by a file system extending
+// the file, which holds no valid record
+func tornWALRecord(data []byte) bool {
+	if len(data) < walRecordHeader {
+		return true
+	}
+
+	start := 1
+	length := binary.BigEndian.Uint32(data[0:4])
+	if length >= 2 && uint64(length) <= uint64(len(data)-walRecordHeader) {
+		start = walRecordHeader + int(length)
+	}
+
+	for ; start+walRecordHeader < len(data); start++ {
+		if _, _, valid := decodeWALRecord(data[start:]); valid {
+			return false
+		}
+	}
+	return true
+}
+
+// walBoundary returns true if the offset in the
+// valid records is the start of one or their end
+func walBoundary(records []byte, offset int64) bool {
+	position := int64(0)
+	for position < offset {
+		length := binary.BigEndian.Uint32(records[position : position+4])
+		position += walRecordHeader + int64(length)
+	}
+	return position == offset
+}
+
+// syncDir flushes the directory entries
+// of the given directory to disk
+func syncDir(dir string) error {
+	directory, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer directory.Close()
+	return directory.Sync()
+}
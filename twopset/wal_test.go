@@ -0,0 +1,311 @@
+package twopset
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tempWAL returns the path to a write-ahead log in a new temporary
+// directory along with a function removing the directory
+func tempWAL(t *testing.T) (string, func()) {
+	dir, cleanup := tempDir(t)
+	return filepath.Join(dir, "twopset.wal"), cleanup
+}
+
+// TestWAL checks the basic functionality of the WAL
+// operations appended should be returned in order when reopened
+func TestWAL(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, operations, actualError := OpenWAL(path, 0)
+	assert.Nil(t, actualError)
+	assert.Empty(t, operations)
+
+	assert.Nil(t, wal.Append(Operation{Type: OperationAdd, Value: "xx"}))
+	assert.Nil(t, wal.Append(Operation{Type: OperationAdd, Value: "yy"}))
+	assert.Nil(t, wal.Append(Operation{Type: OperationRemove, Value: "xx"}))
+	assert.Nil(t, wal.Close())
+
+	expectedValue := []Operation{
+		{Type: OperationAdd, Value: "xx"},
+		{Type: OperationAdd, Value: "yy"},
+		{Type: OperationRemove, Value: "xx"},
+	}
+
+	wal, operations, actualError = OpenWAL(path, 0)
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, operations)
+	assert.Nil(t, wal.Close())
+}
+
+// TestWAL_Merged checks the functionality of WAL Append()
+// the operations appended together should all be returned
+// when reopened, telling those merged in from peers
+func TestWAL_Merged(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	expectedValue := []Operation{
+		{Type: OperationAdd, Value: "xx"},
+		{Type: OperationAdd, Value: "yy", Merged: true},
+		{Type: OperationRemove, Value: "yy", Merged: true},
+	}
+
+	wal, _, _ := OpenWAL(path, 0)
+	assert.Nil(t, wal.Append(expectedValue...))
+	assert.Nil(t, wal.Close())
+
+	wal, operations, actualError := OpenWAL(path, 0)
+	assert.Nil(t, actualError)
+	assert.Equal(t, expectedValue, operations)
+
+	// An invalid operation appends none of them
+	offset := wal.Offset()
+	assert.NotNil(t, wal.Append(Operation{Type: OperationAdd, Value: "zz"}, Operation{Type: OperationAdd, Value: ""}))
+	assert.Equal(t, offset, wal.Offset())
+	assert.Nil(t, wal.Close())
+}
+
+// TestWAL_Append checks the functionality of WAL Append()
+// invalid operations and appending to a closed log should return an error
+func TestWAL_Append(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, _, _ := OpenWAL(path, 0)
+
+	assert.NotNil(t, wal.Append(Operation{Type: OperationAdd, Value: ""}))
+	assert.NotNil(t, wal.Append(Operation{Type: "xx", Value: "xx"}))
+	assert.Equal(t, int64(0), wal.Offset())
+
+	assert.Nil(t, wal.Close())
+	assert.Equal(t, ErrWALClosed, wal.Append(Operation{Type: OperationAdd, Value: "xx"}))
+}
+
+// TestWAL_TornRecord checks the functionality of OpenWAL()
+// a torn or corrupt record at the end of the log should be
+// discarded and new records should be appended after the valid ones
+func TestWAL_TornRecord(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, _, _ := OpenWAL(path, 0)
+	wal.Append(Operation{Type: OperationAdd, Value: "xx"})
+	wal.Append(Operation{Type: OperationAdd, Value: "yy"})
+	valid := wal.Offset()
+	wal.Close()
+
+	// Tear the last record & append garbage
+	info, _ := os.Stat(path)
+	os.Truncate(path, info.Size()-1)
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	file.Write([]byte{0xff, 0xff})
+	file.Close()
+
+	wal, operations, actualError := OpenWAL(path, 0)
+	assert.Nil(t, actualError)
+	assert.Equal(t, []Operation{{Type: OperationAdd, Value: "xx"}}, operations)
+	assert.True(t, wal.Offset() < valid)
+	assert.NotZero(t, wal.Discarded())
+
+	assert.Nil(t, wal.Append(Operation{Type: OperationAdd, Value: "zz"}))
+	wal.Close()
+
+	_, operations, _ = OpenWAL(path, 0)
+	assert.Equal(t, []Operation{
+		{Type: OperationAdd, Value: "xx"},
+		{Type: OperationAdd, Value: "zz"},
+	}, operations)
+}
+
+// TestWAL_Corrupt checks the functionality of OpenWAL()
+// a record whose checksum does not match followed by valid
+// records should return an error instead of dropping them
+func TestWAL_Corrupt(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, _, _ := OpenWAL(path, 0)
+	wal.Append(Operation{Type: OperationAdd, Value: "xx"})
+	wal.Append(Operation{Type: OperationAdd, Value: "yy"})
+	wal.Append(Operation{Type: OperationAdd, Value: "zz"})
+	wal.Close()
+
+	// Flip the value of the second record
+	data, _ := ioutil.ReadFile(path)
+	data[walHeader+walRecordHeader+3+walRecordHeader+1] = 'x'
+	ioutil.WriteFile(path, data, 0644)
+
+	_, operations, actualError := OpenWAL(path, 0)
+	assert.True(t, errors.Is(actualError, ErrWALCorrupt))
+	assert.Nil(t, operations)
+
+	// The log file should be left intact
+	actualValue, _ := ioutil.ReadFile(path)
+	assert.Equal(t, data, actualValue)
+}
+
+// TestWAL_CorruptLength checks the functionality of OpenWAL()
+// a record whose length is corrupt to run past the end of the log
+// followed by valid records should return an error instead of
+// being discarded as torn along with the records after it
+func TestWAL_CorruptLength(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, _, _ := OpenWAL(path, 0)
+	wal.Append(Operation{Type: OperationAdd, Value: "xx"})
+	wal.Append(Operation{Type: OperationAdd, Value: "yy"})
+	wal.Append(Operation{Type: OperationAdd, Value: "zz"})
+	wal.Close()
+
+	// Set the length of the second record past the end
+	data, _ := ioutil.ReadFile(path)
+	data[walHeader+walRecordHeader+3] = 0xff
+	ioutil.WriteFile(path, data, 0644)
+
+	_, operations, actualError := OpenWAL(path, 0)
+	assert.True(t, errors.Is(actualError, ErrWALCorrupt))
+	assert.Nil(t, operations)
+
+	// The log file should be left intact
+	actualValue, _ := ioutil.ReadFile(path)
+	assert.Equal(t, data, actualValue)
+}
+
+// TestWAL_ZeroedTail checks the functionality of OpenWAL()
+// a record zeroed out to the end of the log should be
+// discarded as torn by a crash while appending it
+func TestWAL_ZeroedTail(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, _, _ := OpenWAL(path, 0)
+	wal.Append(Operation{Type: OperationAdd, Value: "xx"})
+	valid := wal.Offset()
+	wal.Close()
+
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	file.Write(make([]byte, 16))
+	file.Close()
+
+	wal, operations, actualError := OpenWAL(path, 0)
+	assert.Nil(t, actualError)
+	assert.Equal(t, []Operation{{Type: OperationAdd, Value: "xx"}}, operations)
+	assert.Equal(t, valid, wal.Offset())
+	assert.Equal(t, int64(16), wal.Discarded())
+	wal.Close()
+}
+
+// TestWAL_Failed checks the functionality of WAL Append()
+// when a failed append can not be dropped from the log file
+// later appends should fail until the log is truncated
+func TestWAL_Failed(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, _, _ := OpenWAL(path, 0)
+	wal.Append(Operation{Type: OperationAdd, Value: "xx"})
+	valid := wal.Offset()
+
+	// Close the log file behind the WAL's back
+	// so writing to & truncating it fail
+	wal.file.Close()
+
+	assert.NotNil(t, wal.Append(Operation{Type: OperationAdd, Value: "yy"}))
+	assert.NotNil(t, wal.failed)
+	assert.Equal(t, wal.failed, wal.Append(Operation{Type: OperationAdd, Value: "zz"}))
+	assert.Equal(t, valid, wal.Offset())
+
+	wal.file = nil
+	wal, operations, actualError := OpenWAL(path, 0)
+	assert.Nil(t, actualError)
+	assert.Equal(t, []Operation{{Type: OperationAdd, Value: "xx"}}, operations)
+	wal.Close()
+}
+
+// TestReplay checks the basic functionality of Replay()
+// it should return the TwoPSet the operations were applied to
+func TestReplay(t *testing.T) {
+	operations := []Operation{
+		{Type: OperationAdd, Value: "xx"},
+		{Type: OperationAdd, Value: "yy"},
+		{Type: OperationRemove, Value: "xx"},
+		{Type: OperationRemove, Value: "zz"},
+	}
+
+	expectedValue := TwoPSet{Add: NewGSet("xx", "yy"), Remove: NewGSet("xx", "zz")}
+
+	assert.Equal(t, expectedValue, Replay(operations))
+	assert.Equal(t, Initialize(), Replay(nil))
+}
+
+// TestWAL_From checks the functionality of OpenWAL()
+// only the operations from the given offset onwards should be returned
+func TestWAL_From(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, _, _ := OpenWAL(path, 0)
+	wal.Append(Operation{Type: OperationAdd, Value: "xx"})
+	offset := wal.Offset()
+	wal.Append(Operation{Type: OperationAdd, Value: "yy"})
+	wal.Close()
+
+	_, operations, actualError := OpenWAL(path, offset)
+	assert.Nil(t, actualError)
+	assert.Equal(t, []Operation{{Type: OperationAdd, Value: "yy"}}, operations)
+}
+
+// TestWAL_Truncate checks the basic functionality of WAL Truncate()
+// the records before the offset should be dropped while the offsets
+// of the records left stay the same, and offsets inside a record
+// should return an error
+func TestWAL_Truncate(t *testing.T) {
+	path, cleanup := tempWAL(t)
+	defer cleanup()
+
+	wal, _, _ := OpenWAL(path, 0)
+	wal.Append(Operation{Type: OperationAdd, Value: "xx"})
+	offset := wal.Offset()
+	wal.Append(Operation{Type: OperationRemove, Value: "xx"})
+	end := wal.Offset()
+
+	assert.Nil(t, wal.Truncate(offset))
+	assert.Equal(t, offset, wal.Base())
+	assert.Equal(t, end, wal.Offset())
+
+	assert.Nil(t, wal.Append(Operation{Type: OperationAdd, Value: "yy"}))
+	assert.NotNil(t, wal.Truncate(wal.Offset()+1))
+
+	// Offsets inside a record should not be truncated to
+	assert.NotNil(t, wal.Truncate(end+1))
+	assert.Equal(t, offset, wal.Base())
+	wal.Close()
+
+	wal, operations, actualError := OpenWAL(path, 0)
+	assert.Nil(t, actualError)
+	assert.Equal(t, offset, wal.Base())
+	assert.Equal(t, []Operation{
+		{Type: OperationRemove, Value: "xx"},
+		{Type: OperationAdd, Value: "yy"},
+	}, operations)
+
+	// Truncating up to the end should leave an empty log
+	assert.Nil(t, wal.Truncate(wal.Offset()))
+	wal.Close()
+
+	wal, operations, _ = OpenWAL(path, 0)
+	assert.Empty(t, operations)
+	assert.Equal(t, wal.Base(), wal.Offset())
+
+	// No temporary files should be left behind
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	assert.Equal(t, 1, len(files))
+}